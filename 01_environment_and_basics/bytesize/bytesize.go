@@ -0,0 +1,203 @@
+// Package bytesize 在第01节 KB/MB/GB/TB 常量的基础上提供可读的字节大小类型，
+// 可以直接用于命令行参数（flag.Value）和配置文件（encoding.TextUnmarshaler）。
+//
+// 单位区分十进制（SI）和二进制（IEC）：
+//
+//	KB、MB、GB…  = 1000、1000²、1000³…（简写 K、M、G…）
+//	KiB、MiB、GiB… = 1024、1024²、1024³…（简写 Ki、Mi、Gi…）
+//
+// 注意第01节示例里的 KB = 1 << 10 沿用的是传统的二进制含义，对应这里的 KiB。
+package bytesize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ByteSize 是字节数，最大约 8 EiB（int64）
+type ByteSize int64
+
+// 二进制单位（IEC）
+const (
+	B ByteSize = 1 << (10 * iota)
+	KiB
+	MiB
+	GiB
+	TiB
+	PiB
+	EiB
+)
+
+// 十进制单位（SI）
+const (
+	KB ByteSize = 1000
+	MB          = KB * 1000
+	GB          = MB * 1000
+	TB          = GB * 1000
+	PB          = TB * 1000
+	EB          = PB * 1000
+)
+
+var (
+	ErrSyntax   = errors.New("无效的字节大小")
+	ErrUnit     = errors.New("未知的单位")
+	ErrOverflow = errors.New("字节大小超出范围")
+	ErrNegative = errors.New("字节大小不能为负")
+)
+
+// units 按小写单位名查找倍数
+var units = map[string]ByteSize{
+	"": B, "b": B, "byte": B, "bytes": B,
+	"k": KB, "kb": KB, "m": MB, "mb": MB, "g": GB, "gb": GB,
+	"t": TB, "tb": TB, "p": PB, "pb": PB, "e": EB, "eb": EB,
+	"ki": KiB, "kib": KiB, "mi": MiB, "mib": MiB, "gi": GiB, "gib": GiB,
+	"ti": TiB, "tib": TiB, "pi": PiB, "pib": PiB, "ei": EiB, "eib": EiB,
+}
+
+// ========== 解析 ==========
+
+// Parse 解析 "10MB"、"512KiB"、"2.5G"、"1 GiB"、"4096" 这样的字符串（单位不区分大小写）。
+// 小数部分按字节四舍五入；负数、未知单位和超过 int64 的值都会返回错误。
+func Parse(s string) (ByteSize, error) {
+	text := strings.TrimSpace(s)
+	i := 0
+	for i < len(text) && (text[i] >= '0' && text[i] <= '9' || text[i] == '.') {
+		i++
+	}
+	number, unit := text[:i], strings.ToLower(strings.TrimSpace(text[i:]))
+	if number == "" || strings.Count(number, ".") > 1 || number == "." {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	multiplier, ok := units[unit]
+	if !ok {
+		return 0, fmt.Errorf("%w %q: %q", ErrUnit, text[i:], s)
+	}
+
+	// 用有理数精确计算，避免 "0.1GB" 之类的浮点误差
+	r, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(int64(multiplier)))
+	// 四舍五入：floor(r + 1/2)
+	r.Add(r, big.NewRat(1, 2))
+	n := new(big.Int).Quo(r.Num(), r.Denom())
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+	return ByteSize(n.Int64()), nil
+}
+
+// MustParse 与 Parse 相同，出错时 panic，适合用于常量初始化
+func MustParse(s string) ByteSize {
+	size, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return size
+}
+
+// ========== 格式化 ==========
+
+var (
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siUnits  = []string{"B", "KB", "MB", "GB", "TB", "PB", "EB"}
+)
+
+// String 使用二进制单位输出，例如 "1.5 GiB"、"512 B"
+func (b ByteSize) String() string {
+	return format(b, 1024, iecUnits)
+}
+
+// SI 使用十进制单位输出，例如 "1.61 GB"
+func (b ByteSize) SI() string {
+	return format(b, 1000, siUnits)
+}
+
+// format 选择使值不小于 1 的最大单位，最多保留两位小数并去掉末尾的 0。
+// 单位在四舍五入之后确定，所以 1048575 输出 "1 MiB" 而不是 "1024 KiB"。
+func format(b ByteSize, base float64, names []string) string {
+	sign := ""
+	if b < 0 {
+		sign = "-"
+	}
+	// 先转成 uint64 再取绝对值，math.MinInt64 也不会溢出
+	abs := uint64(b)
+	if b < 0 {
+		abs = -abs
+	}
+	if float64(abs) < base {
+		return sign + strconv.FormatUint(abs, 10) + " B"
+	}
+	v := float64(abs)
+	i := 0
+	for i < len(names)-1 && math.Round(v*100)/100 >= base {
+		v /= base
+		i++
+	}
+	text := strconv.FormatFloat(v, 'f', 2, 64)
+	text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	return sign + text + " " + names[i]
+}
+
+// ========== 接口实现 ==========
+
+// Set 实现 flag.Value，配合 flag.Var 使用
+func (b *ByteSize) Set(s string) error {
+	size, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// MarshalText 输出能被精确读回的文本：选择能整除的最大单位，例如 "1536 KiB"、"10 MB"、"1025"。
+// Parse 不接受负数，所以负数返回 ErrNegative，而不是写出一个读不回来的值。
+func (b ByteSize) MarshalText() ([]byte, error) {
+	if b < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrNegative, int64(b))
+	}
+	for i := len(iecUnits) - 1; i > 0; i-- {
+		if unit := ByteSize(1) << (10 * i); b%unit == 0 && b != 0 {
+			return []byte(strconv.FormatInt(int64(b/unit), 10) + " " + iecUnits[i]), nil
+		}
+	}
+	for i, unit := len(siUnits)-1, EB; i > 0; i, unit = i-1, unit/1000 {
+		if b%unit == 0 && b != 0 {
+			return []byte(strconv.FormatInt(int64(b/unit), 10) + " " + siUnits[i]), nil
+		}
+	}
+	return []byte(strconv.FormatInt(int64(b), 10)), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，JSON、TOML 等配置可以直接写 "10MB"
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	return b.Set(string(text))
+}
+
+// UnmarshalJSON 同时接受字符串（"10MB"）和数字（字节数）
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		// 按 JSON 规则解码，支持 \/、\u 代理对等 strconv.Unquote 不认识的转义
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return fmt.Errorf("%w: %s", ErrSyntax, data)
+		}
+		return b.Set(text)
+	}
+	if string(data) == "null" {
+		return nil
+	}
+	return b.Set(string(data))
+}
+
+// Bytes 返回字节数
+func (b ByteSize) Bytes() int64 {
+	return int64(b)
+}
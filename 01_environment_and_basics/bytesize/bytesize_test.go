@@ -0,0 +1,159 @@
+package bytesize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestString(t *testing.T) {
+	cases := []struct {
+		size ByteSize
+		iec  string
+		si   string
+	}{
+		{0, "0 B", "0 B"},
+		{1023, "1023 B", "1.02 KB"},
+		{1536, "1.5 KiB", "1.54 KB"},
+		{999_999, "976.56 KiB", "1 MB"},
+		{MiB - 1, "1 MiB", "1.05 MB"},
+		{MiB - 6, "1023.99 KiB", "1.05 MB"},
+		{GiB - 1, "1 GiB", "1.07 GB"},
+		{-1536, "-1.5 KiB", "-1.54 KB"},
+		{math.MaxInt64, "8 EiB", "9.22 EB"},
+		{math.MinInt64, "-8 EiB", "-9.22 EB"},
+	}
+	for _, c := range cases {
+		if got := c.size.String(); got != c.iec {
+			t.Errorf("ByteSize(%d).String() = %q, want %q", int64(c.size), got, c.iec)
+		}
+		if got := c.size.SI(); got != c.si {
+			t.Errorf("ByteSize(%d).SI() = %q, want %q", int64(c.size), got, c.si)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := map[string]ByteSize{
+		"4096":     4096,
+		"10MB":     10 * MB,
+		"512 KiB":  512 * KiB,
+		"2.5G":     2500 * MB,
+		"0.1gb":    100 * MB,
+		"1.5 b":    2,
+		" 1 GiB  ": GiB,
+	}
+	for text, want := range cases {
+		if got, err := Parse(text); err != nil || got != want {
+			t.Errorf("Parse(%q) = %d, %v, want %d", text, got, err, want)
+		}
+	}
+	for text, want := range map[string]error{
+		"":       ErrSyntax,
+		"-1KB":   ErrSyntax,
+		"1.2.3":  ErrSyntax,
+		"10 XB":  ErrUnit,
+		"9 EiB":  ErrOverflow,
+		"1e3 KB": ErrUnit,
+	} {
+		if _, err := Parse(text); !errors.Is(err, want) {
+			t.Errorf("Parse(%q) err = %v, want %v", text, err, want)
+		}
+	}
+}
+
+// MarshalText 的输出必须能被 Parse 精确读回
+func TestMarshalTextRoundTrip(t *testing.T) {
+	cfg := &quick.Config{
+		MaxCount: 2000,
+		Rand:     rand.New(rand.NewSource(1)),
+		Values: func(args []reflect.Value, r *rand.Rand) {
+			// 一半取随机值，一半取某个单位的整数倍，覆盖两种输出形式
+			n := ByteSize(r.Int63())
+			if r.Intn(2) == 0 {
+				units := []ByteSize{KiB, MiB, GiB, KB, MB, GB, TB}
+				unit := units[r.Intn(len(units))]
+				n = ByteSize(r.Int63n(int64(math.MaxInt64/unit))) * unit
+			}
+			args[0] = reflect.ValueOf(n)
+		},
+	}
+	roundTrip := func(b ByteSize) bool {
+		text, err := b.MarshalText()
+		if err != nil {
+			return false
+		}
+		parsed, err := Parse(string(text))
+		return err == nil && parsed == b
+	}
+	if err := quick.Check(roundTrip, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []ByteSize{0, 1, 1025, 1536 * KiB, 10 * MB, math.MaxInt64} {
+		if !roundTrip(b) {
+			t.Errorf("ByteSize(%d) 无法往返", int64(b))
+		}
+	}
+}
+
+func TestNegativeCannotBeMarshaled(t *testing.T) {
+	if _, err := ByteSize(-KiB).MarshalText(); !errors.Is(err, ErrNegative) {
+		t.Fatalf("MarshalText(-1 KiB) err = %v, want ErrNegative", err)
+	}
+	if _, err := json.Marshal(struct{ Limit ByteSize }{-1}); !errors.Is(err, ErrNegative) {
+		t.Fatalf("json.Marshal err = %v, want ErrNegative", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var cfg struct {
+		Cache ByteSize `json:"cache"`
+		Log   ByteSize `json:"log"`
+	}
+	cfg.Log = MiB
+	if err := json.Unmarshal([]byte(`{"cache": "1.5GiB", "log": null}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Cache != 1536*MiB || cfg.Log != MiB {
+		t.Fatalf("cache = %v, log = %v", cfg.Cache, cfg.Log)
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil || string(data) != `{"cache":"1536 MiB","log":"1 MiB"}` {
+		t.Fatalf("Marshal = %s, %v", data, err)
+	}
+}
+
+// 字符串按 JSON 规则解码转义，而不是 Go 的字符串字面量规则：
+// \/ 和 \u 代理对在 Go 里不合法，但在 JSON 里合法，解码后交给 Set 判断单位
+func TestUnmarshalJSONEscapes(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    ByteSize
+		wantErr error
+		unit    string // 错误信息中应当出现的、解码后的单位
+	}{
+		{`"10\u0020MB"`, 10 * MB, nil, ""},
+		{`"2 \u004b\u0069B"`, 2 * KiB, nil, ""},
+		{`"5 \/B"`, 0, ErrUnit, "/B"},
+		{`"5 \ud83d\ude00"`, 0, ErrUnit, "😀"},
+	}
+	for _, c := range cases {
+		var b ByteSize
+		err := json.Unmarshal([]byte(c.in), &b)
+		if c.wantErr == nil {
+			if err != nil || b != c.want {
+				t.Errorf("Unmarshal(%s) = %v, %v, want %v", c.in, b, err, c.want)
+			}
+			continue
+		}
+		if !errors.Is(err, c.wantErr) || !strings.Contains(fmt.Sprint(err), c.unit) {
+			t.Errorf("Unmarshal(%s) err = %v, want %v（包含 %q）", c.in, err, c.wantErr, c.unit)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Package calendar 提供工作日历：基于 time.Weekday 的可配置工作周、
+// 从文件加载的节假日（包括调休上班日），以及工作日加减和区间计数，供发货时效、计薪周期等场景使用。
+// 星期直接使用标准库的 time.Weekday，取值与第01节的 Weekday 枚举相同，可以互相转换。
+package calendar
+
+import "time"
+
+// ========== 工作周 ==========
+
+// WorkWeek 用位集合表示一周中哪几天上班，第 d 位对应 time.Weekday(d)
+type WorkWeek uint8
+
+// StandardWeek 是周一到周五的标准工作周
+const StandardWeek = WorkWeek(1<<time.Monday | 1<<time.Tuesday | 1<<time.Wednesday | 1<<time.Thursday | 1<<time.Friday)
+
+// NewWorkWeek 由上班的星期组成工作周，例如单休：NewWorkWeek(time.Monday, …, time.Saturday)
+func NewWorkWeek(days ...time.Weekday) WorkWeek {
+	var w WorkWeek
+	for _, d := range days {
+		if validWeekday(d) {
+			w |= 1 << d
+		}
+	}
+	return w
+}
+
+// Contains 报告这一天是否上班
+func (w WorkWeek) Contains(d time.Weekday) bool {
+	return validWeekday(d) && w&(1<<d) != 0
+}
+
+// Days 按周日到周六的顺序返回上班的星期
+func (w WorkWeek) Days() []time.Weekday {
+	var days []time.Weekday
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if w.Contains(d) {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func validWeekday(d time.Weekday) bool {
+	return d >= time.Sunday && d <= time.Saturday
+}
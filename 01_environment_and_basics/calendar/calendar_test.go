@@ -0,0 +1,136 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const holidays2025 = `
+# 2025 年春节和国庆
+2025-01-01              休  元旦
+2025-01-28~2025-02-04   休  春节
+2025-01-26              班  春节调休
+2025-02-08              班  春节调休
+2025-10-01~2025-10-08   休  国庆节、中秋节
+2025-09-28              班  国庆节调休
+2025-10-11              班  国庆节调休
+`
+
+func newCalendar(t *testing.T) *Calendar {
+	t.Helper()
+	c, err := Parse(strings.NewReader(holidays2025), StandardWeek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func day(t testing.TB, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestWorkWeek(t *testing.T) {
+	week := NewWorkWeek(time.Monday, time.Saturday, time.Weekday(7), time.Weekday(-1))
+	if got := week.Days(); len(got) != 2 || got[0] != time.Monday || got[1] != time.Saturday {
+		t.Fatalf("Days() = %v, want [Monday Saturday]", got)
+	}
+	if StandardWeek.Contains(time.Sunday) || !StandardWeek.Contains(time.Friday) || StandardWeek.Contains(time.Weekday(9)) {
+		t.Fatal("StandardWeek.Contains 结果错误")
+	}
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	c := newCalendar(t)
+	for s, want := range map[string]bool{
+		"2025-01-24": true,  // 周五
+		"2025-01-25": false, // 周六
+		"2025-01-26": true,  // 周日，调休上班
+		"2025-01-29": false, // 周三，春节
+		"2025-10-11": true,  // 周六，调休上班
+	} {
+		if got := c.IsBusinessDay(day(t, s)); got != want {
+			t.Errorf("IsBusinessDay(%s) = %v, want %v", s, got, want)
+		}
+	}
+	if name, ok := c.Holiday(day(t, "2025-01-29")); !ok || name != "春节" {
+		t.Errorf("Holiday = %q, %v", name, ok)
+	}
+}
+
+func TestAddBusinessDaysAndBetween(t *testing.T) {
+	c := newCalendar(t)
+	from := day(t, "2025-01-24")
+	due := c.AddBusinessDays(from, 3)
+	if want := day(t, "2025-02-05"); !due.Equal(want) {
+		t.Fatalf("AddBusinessDays = %s, want %s", due.Format(time.DateOnly), want.Format(time.DateOnly))
+	}
+	if n := c.BusinessDaysBetween(from, due); n != 3 {
+		t.Fatalf("BusinessDaysBetween = %d, want 3", n)
+	}
+	if n := c.BusinessDaysBetween(due, from); n != -3 {
+		t.Fatalf("反向 BusinessDaysBetween = %d, want -3", n)
+	}
+	for m, want := range map[time.Month]int{time.January: 19, time.February: 19, time.October: 18} {
+		if got := c.BusinessDaysInMonth(2025, m); got != want {
+			t.Errorf("2025 年 %d 月工作日 %d 天，want %d", m, got, want)
+		}
+	}
+}
+
+// 两个时间时区不同时，to 换算到 from 的时区后再取日期
+func TestBusinessDaysBetweenAcrossLocations(t *testing.T) {
+	c := New(StandardWeek)
+	shanghai := time.FixedZone("CST", 8*3600)
+	newYork := time.FixedZone("EST", -5*3600)
+
+	from := time.Date(2025, 3, 3, 9, 0, 0, 0, shanghai) // 周一
+	// 纽约周四 20:00 就是上海周五 09:00
+	to := time.Date(2025, 3, 6, 20, 0, 0, 0, newYork)
+	if n := c.BusinessDaysBetween(from, to); n != 4 {
+		t.Fatalf("BusinessDaysBetween = %d, want 4", n)
+	}
+	if n := c.BusinessDaysBetween(from, to.In(shanghai)); n != 4 {
+		t.Fatalf("同一时区 BusinessDaysBetween = %d, want 4", n)
+	}
+	// 同一时刻在两个时区是不同的日期，区间仍然为空
+	if n := c.BusinessDaysBetween(from, from.In(newYork)); n != 0 {
+		t.Fatalf("同一时刻 BusinessDaysBetween = %d, want 0", n)
+	}
+}
+
+// 工作周为空时也不会死循环，长区间能在合理时间内算完
+func TestBusinessDaysBetweenTerminates(t *testing.T) {
+	empty := New(0)
+	from := day(t, "2000-01-01")
+	to := day(t, "2100-01-01")
+	if n := empty.BusinessDaysBetween(from, to); n != 0 {
+		t.Fatalf("空工作周 BusinessDaysBetween = %d, want 0", n)
+	}
+	if got := empty.AddBusinessDays(from, 1); got.Sub(from) > (maxScan+1)*24*time.Hour {
+		t.Fatalf("空工作周 AddBusinessDays 扫描超过 maxScan: %s", got.Format(time.DateOnly))
+	}
+	if n := New(StandardWeek).BusinessDaysBetween(from, to); n != 26089 {
+		t.Fatalf("100 年的工作日 %d, want 26089", n)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for input, want := range map[string]error{
+		"2025-01-01":                    ErrSyntax,
+		"2025-13-01 休":                  ErrSyntax,
+		"2025-01-05~2025-01-01 休":       ErrSyntax,
+		"2025-01-01 放":                  ErrSyntax,
+		"2025-01-01 休\n2025-01-01 班 调休": ErrConflict,
+	} {
+		if _, err := Parse(strings.NewReader(input), StandardWeek); !errors.Is(err, want) {
+			t.Errorf("Parse(%q) err = %v, want %v", input, err, want)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSyntax   = errors.New("节假日文件格式错误")
+	ErrConflict = errors.New("同一天既是假日又是调休上班日")
+)
+
+// date 是不带时区和时刻的日期，用作节假日表的键
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dateOf(t time.Time) date {
+	y, m, d := t.Date()
+	return date{y, m, d}
+}
+
+// ========== 日历 ==========
+
+// Calendar 由工作周和节假日表组成。
+// 判断某天是否上班的顺序：调休上班日 > 法定假日 > 工作周。
+// Calendar 构建完成后只读，可以被多个 goroutine 同时使用。
+type Calendar struct {
+	Week     WorkWeek
+	holidays map[date]string // 假日名称
+	workdays map[date]string // 调休上班日（落在周末但要上班）
+}
+
+// New 创建只使用工作周、没有节假日的日历
+func New(week WorkWeek) *Calendar {
+	return &Calendar{
+		Week:     week,
+		holidays: make(map[date]string),
+		workdays: make(map[date]string),
+	}
+}
+
+// AddHoliday 把 [from, to] 内的每一天标记为假日
+func (c *Calendar) AddHoliday(from, to time.Time, name string) error {
+	return c.mark(from, to, name, c.holidays, c.workdays)
+}
+
+// AddWorkday 把 [from, to] 内的每一天标记为调休上班日
+func (c *Calendar) AddWorkday(from, to time.Time, name string) error {
+	return c.mark(from, to, name, c.workdays, c.holidays)
+}
+
+func (c *Calendar) mark(from, to time.Time, name string, set, other map[date]string) error {
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		key := dateOf(d)
+		if _, dup := other[key]; dup {
+			return fmt.Errorf("%w: %s", ErrConflict, d.Format(time.DateOnly))
+		}
+		set[key] = name
+	}
+	return nil
+}
+
+// ========== 加载节假日文件 ==========
+
+// Load 从文件读取节假日，见 Parse
+func Load(path string, week WorkWeek) (*Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := Parse(f, week)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse 读取节假日表，每行一条，# 开头为注释：
+//
+//	2025-01-28~2025-02-04 休 春节
+//	2025-01-26            班 春节调休
+//	2025-01-01            休 元旦
+//
+// 第二列 "休"（或 holiday）表示放假，"班"（或 workday）表示调休上班；名称可以省略。
+func Parse(r io.Reader, week WorkWeek) (*Calendar, error) {
+	c := New(week)
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%w: 第 %d 行缺少类型: %q", ErrSyntax, line, text)
+		}
+		from, to, err := parseRange(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: 第 %d 行: %v", ErrSyntax, line, err)
+		}
+		name := strings.Join(fields[2:], " ")
+		switch strings.ToLower(fields[1]) {
+		case "休", "holiday":
+			err = c.AddHoliday(from, to, name)
+		case "班", "workday":
+			err = c.AddWorkday(from, to, name)
+		default:
+			return nil, fmt.Errorf("%w: 第 %d 行类型应为 休 或 班: %q", ErrSyntax, line, fields[1])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseRange 解析 "2025-05-01" 或 "2025-05-01~2025-05-05"
+func parseRange(s string) (from, to time.Time, err error) {
+	first, last, isRange := strings.Cut(s, "~")
+	if from, err = time.Parse(time.DateOnly, first); err != nil {
+		return
+	}
+	to = from
+	if isRange {
+		if to, err = time.Parse(time.DateOnly, last); err != nil {
+			return
+		}
+		if to.Before(from) {
+			err = fmt.Errorf("结束日期早于开始日期: %q", s)
+		}
+	}
+	return
+}
+
+// ========== 查询 ==========
+
+// IsBusinessDay 报告 t 所在的日期是否上班
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	key := dateOf(t)
+	if _, ok := c.workdays[key]; ok {
+		return true
+	}
+	if _, ok := c.holidays[key]; ok {
+		return false
+	}
+	return c.Week.Contains(t.Weekday())
+}
+
+// Holiday 返回 t 所在日期的假日名称
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	name, ok := c.holidays[dateOf(t)]
+	return name, ok
+}
+
+// AdjustedWorkday 返回 t 所在日期的调休说明
+func (c *Calendar) AdjustedWorkday(t time.Time) (string, bool) {
+	name, ok := c.workdays[dateOf(t)]
+	return name, ok
+}
+
+// ========== 工作日计算 ==========
+
+// maxScan 限制向前向后查找的天数，防止工作周为空时死循环
+const maxScan = 366 * 10
+
+// NextBusinessDay 返回 t 当天或之后的第一个工作日（保留时刻）
+func (c *Calendar) NextBusinessDay(t time.Time) time.Time {
+	for i := 0; i < maxScan && !c.IsBusinessDay(t); i++ {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// AddBusinessDays 返回 t 之后第 n 个工作日（n 为负数时向前），起始日本身不计入；
+// 保留 t 的时刻，n 为 0 时原样返回。
+// 例如周五下单、3 个工作日内发货：AddBusinessDays(周五, 3) 是下周三。
+func (c *Calendar) AddBusinessDays(t time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for scanned := 0; n > 0 && scanned < maxScan; scanned++ {
+		t = t.AddDate(0, 0, step)
+		if c.IsBusinessDay(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// BusinessDaysBetween 统计 [from, to) 内的工作日数，只看日期不看时刻；
+// to 早于 from 时返回负数，使得 AddBusinessDays 与它互为逆运算（在工作日上）。
+// to 先换算到 from 的时区再取日期，所以两个时间的时区不同也能得到确定的结果。
+func (c *Calendar) BusinessDaysBetween(from, to time.Time) int {
+	start, end := midnight(from), midnight(to.In(from.Location()))
+	sign := 1
+	if end.Before(start) {
+		start, end, sign = end, start, -1
+	}
+	// 按 UTC 零点相减得到准确的天数，循环次数由它确定，不会因为日期比较出错而死循环
+	days := int((end.Unix() - start.Unix()) / secondsPerDay)
+	n := 0
+	for i := 0; i < days; i++ {
+		if c.IsBusinessDay(start.AddDate(0, 0, i)) {
+			n++
+		}
+	}
+	return sign * n
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// midnight 返回 t 所在日期的 UTC 零点，用于按日期计数（UTC 没有夏令时，每天都是 24 小时）
+func midnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// BusinessDaysInMonth 返回某月的工作日数，用于按出勤日计薪
+func (c *Calendar) BusinessDaysInMonth(year int, month time.Month) int {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return c.BusinessDaysBetween(start, start.AddDate(0, 1, 0))
+}
@@ -1,22 +1,32 @@
 package main
 
-import "fmt"
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"time"
+
+	"golang_study/01_environment_and_basics/bytesize"
+	"golang_study/01_environment_and_basics/calendar"
+)
 
 // ========== 包级别常量（可被其他包访问，因为首字母大写）==========
 const Pi = 3.14159
 const AppName = "GoLearn"
 
 // ========== 枚举定义 ==========
+//
+//go:generate go run golang_study/tools/enumgen -type=Weekday
 type Weekday int
 
 const (
-	Sunday    Weekday = iota // 0
-	Monday                   // 1
-	Tuesday                  // 2
-	Wednesday                // 3
-	Thursday                 // 4
-	Friday                   // 5
-	Saturday                 // 6
+	Sunday    Weekday = iota // 0 enum:"星期日"
+	Monday                   // 1 enum:"星期一"
+	Tuesday                  // 2 enum:"星期二"
+	Wednesday                // 3 enum:"星期三"
+	Thursday                 // 4 enum:"星期四"
+	Friday                   // 5 enum:"星期五"
+	Saturday                 // 6 enum:"星期六"
 )
 
 // 存储单位
@@ -119,6 +129,29 @@ func main() {
 	if today == Wednesday {
 		fmt.Println("✓ 今天是星期三\n")
 	}
+	// String()/ParseWeekday() 由 enumgen 生成（go generate）
+	fmt.Printf("今天是%v，一周: %v\n", today, WeekdayValues())
+	if day, err := ParseWeekday("星期五"); err == nil {
+		fmt.Printf("解析 \"星期五\": %s (%d)\n", day.Name(), day)
+	}
+
+	// calendar 包直接使用 time.Weekday，取值与上面的 Weekday 相同，可以直接转换
+	fmt.Printf("time.Weekday: %v，Weekday: %v\n", time.Weekday(today), Weekday(time.Saturday))
+	// 节假日文件里包含调休上班日（在本目录下运行）
+	if cal, err := calendar.Load("holidays_2025.txt", calendar.StandardWeek); err != nil {
+		fmt.Println("加载节假日失败:", err)
+	} else {
+		for _, s := range []string{"2025-01-24", "2025-01-26", "2025-01-29", "2025-09-30"} {
+			ordered, _ := time.Parse(time.DateOnly, s)
+			due := cal.AddBusinessDays(ordered, 3)
+			fmt.Printf("%s（%v）下单，3 个工作日内发货 → %s（%v）\n",
+				s, Weekday(ordered.Weekday()), due.Format(time.DateOnly), Weekday(due.Weekday()))
+		}
+		for _, m := range []time.Month{time.January, time.February, time.October} {
+			fmt.Printf("2025 年 %d 月计薪工作日: %d 天\n", m, cal.BusinessDaysInMonth(2025, m))
+		}
+	}
+	fmt.Println()
 
 	// ========== 存储单位演示 ==========
 	fmt.Println("【存储单位（iota + 位运算）】")
@@ -126,7 +159,30 @@ func main() {
 	fmt.Printf("文件大小: %d 字节\n", fileSize)
 	fmt.Printf("文件大小: %.2f GB\n", float64(fileSize)/float64(GB))
 	fmt.Printf("文件大小: %.2f MB\n", float64(fileSize)/float64(MB))
-	fmt.Printf("文件大小: %.2f KB\n\n", float64(fileSize)/float64(KB))
+	fmt.Printf("文件大小: %.2f KB\n", float64(fileSize)/float64(KB))
+
+	// bytesize.ByteSize：自动选择单位，并区分十进制（GB）和二进制（GiB）
+	size := bytesize.ByteSize(fileSize) + 512*bytesize.MiB
+	fmt.Printf("可读格式: %v（十进制: %s）\n", size, size.SI())
+	for _, text := range []string{"10MB", "512KiB", "2.5G", "1.5 gib", "10XB", "9EiB"} {
+		if parsed, err := bytesize.Parse(text); err != nil {
+			fmt.Printf("解析 %-8q 失败: %v\n", text, err)
+		} else {
+			fmt.Printf("解析 %-8q = %d 字节（%v）\n", text, parsed.Bytes(), parsed)
+		}
+	}
+
+	// 作为命令行参数（flag.Value）和配置项（TextUnmarshaler）
+	flags := flag.NewFlagSet("demo", flag.ContinueOnError)
+	maxUpload := 8 * bytesize.MiB
+	flags.Var(&maxUpload, "max-upload", "上传大小上限")
+	flags.Parse([]string{"-max-upload", "64MiB"})
+	var config struct {
+		CacheSize bytesize.ByteSize `json:"cache_size"`
+		LogLimit  bytesize.ByteSize `json:"log_limit"`
+	}
+	json.Unmarshal([]byte(`{"cache_size": "256MB", "log_limit": 1048576}`), &config)
+	fmt.Printf("参数 -max-upload: %v，配置 cache_size: %v，log_limit: %v\n\n", maxUpload, config.CacheSize, config.LogLimit)
 
 	// ========== 变量批量声明 ==========
 	fmt.Println("【批量声明】")
@@ -141,3 +197,132 @@ func main() {
 
 	fmt.Println("\n========== 示例程序结束 ==========")
 }
+
+// enumgen:begin Weekday
+// 以下代码由 enumgen 根据 Weekday 的常量生成，请勿手动修改；修改常量后重新运行 go generate。
+
+// String 返回显示名称（常量注释中的 enum:"..."，没有标注时为常量名）
+func (x Weekday) String() string {
+	switch x {
+	case Sunday:
+		return "星期日"
+	case Monday:
+		return "星期一"
+	case Tuesday:
+		return "星期二"
+	case Wednesday:
+		return "星期三"
+	case Thursday:
+		return "星期四"
+	case Friday:
+		return "星期五"
+	case Saturday:
+		return "星期六"
+	}
+	return fmt.Sprintf("Weekday(%d)", int64(x))
+}
+
+// Name 返回常量名，用于文本和 JSON 序列化
+func (x Weekday) Name() string {
+	switch x {
+	case Sunday:
+		return "Sunday"
+	case Monday:
+		return "Monday"
+	case Tuesday:
+		return "Tuesday"
+	case Wednesday:
+		return "Wednesday"
+	case Thursday:
+		return "Thursday"
+	case Friday:
+		return "Friday"
+	case Saturday:
+		return "Saturday"
+	}
+	return fmt.Sprintf("Weekday(%d)", int64(x))
+}
+
+// IsValid 报告 x 是否是已定义的常量
+func (x Weekday) IsValid() bool {
+	switch x {
+	case Sunday, Monday, Tuesday, Wednesday, Thursday, Friday, Saturday:
+		return true
+	}
+	return false
+}
+
+// WeekdayValues 按声明顺序返回所有常量
+func WeekdayValues() []Weekday {
+	return []Weekday{Sunday, Monday, Tuesday, Wednesday, Thursday, Friday, Saturday}
+}
+
+// ParseWeekday 按常量名或显示名称解析
+func ParseWeekday(s string) (Weekday, error) {
+	switch s {
+	case "Sunday", "星期日":
+		return Sunday, nil
+	case "Monday", "星期一":
+		return Monday, nil
+	case "Tuesday", "星期二":
+		return Tuesday, nil
+	case "Wednesday", "星期三":
+		return Wednesday, nil
+	case "Thursday", "星期四":
+		return Thursday, nil
+	case "Friday", "星期五":
+		return Friday, nil
+	case "Saturday", "星期六":
+		return Saturday, nil
+	}
+	return 0, fmt.Errorf("无效的 Weekday: %q", s)
+}
+
+// MarshalText 实现 encoding.TextMarshaler，输出常量名
+func (x Weekday) MarshalText() ([]byte, error) {
+	if !x.IsValid() {
+		return nil, fmt.Errorf("无效的 Weekday: %d", int64(x))
+	}
+	return []byte(x.Name()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，接受常量名或显示名称
+func (x *Weekday) UnmarshalText(text []byte) error {
+	v, err := ParseWeekday(string(text))
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+// MarshalJSON 输出常量名字符串
+func (x Weekday) MarshalJSON() ([]byte, error) {
+	text, err := x.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%q", text)), nil
+}
+
+// UnmarshalJSON 接受字符串（常量名或显示名称）或数字；null 保持原值不变，与标准库的约定一致
+func (x *Weekday) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("无效的 Weekday: %s", data)
+		}
+		return x.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil || !Weekday(n).IsValid() {
+		return fmt.Errorf("无效的 Weekday: %s", data)
+	}
+	*x = Weekday(n)
+	return nil
+}
+
+// enumgen:end Weekday
@@ -4,7 +4,10 @@
  */
 package main
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 // ========== 常量定义 ==========
 const (
@@ -14,28 +17,18 @@ const (
 
 // ========== 员工状态枚举 ==========
 // 定义类型别名，提供类型安全性
+// String()、ParseEmployeeStatus() 等方法由 enumgen 生成（类似 Java 的 toString/valueOf），
+// 显示名称来自行尾注释里的 enum:"..." 标注
+//
+//go:generate go run golang_study/tools/enumgen -type=EmployeeStatus
 type EmployeeStatus int
 
 const (
-	Probation EmployeeStatus = iota + 1 // 1: 试用期
-	Regular                             // 2: 正式员工
-	Resigned                            // 3: 已离职
+	Probation EmployeeStatus = iota + 1 // 1 enum:"试用期"
+	Regular                             // 2 enum:"正式员工"
+	Resigned                            // 3 enum:"已离职"
 )
 
-// 为枚举类型添加 String() 方法（类似 Java 的 toString）
-func (s EmployeeStatus) String() string {
-	switch s {
-	case Probation:
-		return "试用期"
-	case Regular:
-		return "正式员工"
-	case Resigned:
-		return "已离职"
-	default:
-		return "未知状态"
-	}
-}
-
 func main() {
 	// ========== 个人信息变量 ==========
 	name := "李四"
@@ -78,6 +71,123 @@ func main() {
 		fmt.Println("状态说明: 未知状态")
 	}
 
-	// 方法2：使用 String() 方法（更优雅）
-	// fmt.Printf("状态说明: %s\n", status)  // 自动调用 String() 方法
+	// 方法2：使用生成的 String() 方法（更优雅）
+	fmt.Printf("状态说明: %s\n", status) // 自动调用 String() 方法
+
+	// 方法3：生成的解析和 JSON 支持
+	parsed, err := ParseEmployeeStatus("试用期")
+	fmt.Printf("解析 \"试用期\": %v, 错误: %v\n", parsed.Name(), err)
+	if _, err := ParseEmployeeStatus("实习"); err != nil {
+		fmt.Println("解析失败:", err)
+	}
+	data, _ := json.Marshal(map[string]EmployeeStatus{"李四": status})
+	fmt.Printf("JSON: %s\n", data)
+	var decoded struct{ Status EmployeeStatus }
+	json.Unmarshal([]byte(`{"Status":"已离职"}`), &decoded)
+	fmt.Printf("从 JSON 读取: %v, 是否有效: %t\n", decoded.Status, decoded.Status.IsValid())
+}
+
+// enumgen:begin EmployeeStatus
+// 以下代码由 enumgen 根据 EmployeeStatus 的常量生成，请勿手动修改；修改常量后重新运行 go generate。
+
+// String 返回显示名称（常量注释中的 enum:"..."，没有标注时为常量名）
+func (x EmployeeStatus) String() string {
+	switch x {
+	case Probation:
+		return "试用期"
+	case Regular:
+		return "正式员工"
+	case Resigned:
+		return "已离职"
+	}
+	return fmt.Sprintf("EmployeeStatus(%d)", int64(x))
+}
+
+// Name 返回常量名，用于文本和 JSON 序列化
+func (x EmployeeStatus) Name() string {
+	switch x {
+	case Probation:
+		return "Probation"
+	case Regular:
+		return "Regular"
+	case Resigned:
+		return "Resigned"
+	}
+	return fmt.Sprintf("EmployeeStatus(%d)", int64(x))
+}
+
+// IsValid 报告 x 是否是已定义的常量
+func (x EmployeeStatus) IsValid() bool {
+	switch x {
+	case Probation, Regular, Resigned:
+		return true
+	}
+	return false
+}
+
+// EmployeeStatusValues 按声明顺序返回所有常量
+func EmployeeStatusValues() []EmployeeStatus {
+	return []EmployeeStatus{Probation, Regular, Resigned}
 }
+
+// ParseEmployeeStatus 按常量名或显示名称解析
+func ParseEmployeeStatus(s string) (EmployeeStatus, error) {
+	switch s {
+	case "Probation", "试用期":
+		return Probation, nil
+	case "Regular", "正式员工":
+		return Regular, nil
+	case "Resigned", "已离职":
+		return Resigned, nil
+	}
+	return 0, fmt.Errorf("无效的 EmployeeStatus: %q", s)
+}
+
+// MarshalText 实现 encoding.TextMarshaler，输出常量名
+func (x EmployeeStatus) MarshalText() ([]byte, error) {
+	if !x.IsValid() {
+		return nil, fmt.Errorf("无效的 EmployeeStatus: %d", int64(x))
+	}
+	return []byte(x.Name()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，接受常量名或显示名称
+func (x *EmployeeStatus) UnmarshalText(text []byte) error {
+	v, err := ParseEmployeeStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+// MarshalJSON 输出常量名字符串
+func (x EmployeeStatus) MarshalJSON() ([]byte, error) {
+	text, err := x.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%q", text)), nil
+}
+
+// UnmarshalJSON 接受字符串（常量名或显示名称）或数字；null 保持原值不变，与标准库的约定一致
+func (x *EmployeeStatus) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("无效的 EmployeeStatus: %s", data)
+		}
+		return x.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil || !EmployeeStatus(n).IsValid() {
+		return fmt.Errorf("无效的 EmployeeStatus: %s", data)
+	}
+	*x = EmployeeStatus(n)
+	return nil
+}
+
+// enumgen:end EmployeeStatus
@@ -0,0 +1,141 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ========== 支付方式 ==========
+
+// PaymentMethod 与第04节的 PaymentMethod 方法集相同
+type PaymentMethod interface {
+	Pay(amount float64) error
+	GetName() string
+}
+
+// RefundableMethod 是支持退款的支付方式，取消已支付订单时需要
+type RefundableMethod interface {
+	PaymentMethod
+	Refund(amount float64) error
+}
+
+var (
+	ErrPaymentFailed     = errors.New("支付失败")
+	ErrRefundFailed      = errors.New("退款失败")
+	ErrRefundUnsupported = errors.New("该支付方式不支持退款")
+)
+
+// ========== 结账服务 ==========
+
+// Receipt 记录订单的支付信息
+type Receipt struct {
+	OrderID int
+	Method  string
+	Amount  float64
+}
+
+// Checkout 把订单、库存和支付串起来：
+// 预留库存 -> 扣款 -> 标记已支付；扣款失败时回滚库存，订单保持待支付。
+// Pay、Cancel 与 Order 自己的 ChangeStatus、Cancel 共用订单锁，并发调用不会重复扣款或重复退款。
+type Checkout struct {
+	inventory *Inventory
+
+	mu       sync.Mutex
+	payments map[int]payment // 订单ID -> 支付记录
+}
+
+type payment struct {
+	method PaymentMethod
+	amount float64
+}
+
+func NewCheckout(inventory *Inventory) *Checkout {
+	return &Checkout{
+		inventory: inventory,
+		payments:  make(map[int]payment),
+	}
+}
+
+// Pay 为订单扣款 CalculateTotal()，成功后订单进入 Paid。
+// 状态检查、扣款和状态变更在订单锁内完成，同一订单的第二次 Pay 会看到 Paid 并返回错误。
+// 扣款成功但订单无法进入 Paid 时（例如状态被绕过订单锁直接改掉）会原路退款。
+func (c *Checkout) Pay(o *Order, pm PaymentMethod) (receipt Receipt, err error) {
+	defer o.lock()()
+
+	if o.Status != Pending {
+		return Receipt{}, fmt.Errorf("%w: 订单状态为 %v，无法支付", ErrInvalidTransition, o.Status)
+	}
+	if len(o.Items) == 0 {
+		return Receipt{}, fmt.Errorf("订单 %d 为空，无法支付", o.ID)
+	}
+	amount := o.CalculateTotal()
+
+	reservation, err := c.inventory.Reserve(o.Items)
+	if err != nil {
+		return Receipt{}, err
+	}
+	// 任何失败（包括支付方式 panic）都要归还库存
+	paid := false
+	defer func() {
+		if !paid {
+			reservation.Release()
+		}
+	}()
+
+	if err := pm.Pay(amount); err != nil {
+		return Receipt{}, fmt.Errorf("%w: %s: %w", ErrPaymentFailed, pm.GetName(), err)
+	}
+	if err := o.setStatus(Paid); err != nil {
+		return Receipt{}, errors.Join(err, refund(pm, amount))
+	}
+	paid = true
+
+	c.mu.Lock()
+	c.payments[o.ID] = payment{method: pm, amount: amount}
+	c.mu.Unlock()
+	return Receipt{OrderID: o.ID, Method: pm.GetName(), Amount: amount}, nil
+}
+
+// Cancel 取消订单：待支付订单直接取消；已支付订单先原路退款，成功后归还库存再取消
+func (c *Checkout) Cancel(o *Order) error {
+	defer o.lock()()
+
+	switch o.Status {
+	case Pending:
+		return o.setStatus(Canceled)
+	case Paid:
+	default:
+		return fmt.Errorf("%w: 订单状态为 %v，无法取消", ErrInvalidTransition, o.Status)
+	}
+
+	c.mu.Lock()
+	record, ok := c.payments[o.ID]
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("订单 %d 没有支付记录", o.ID)
+	}
+
+	// 退款失败时订单保持已支付，可以稍后重试取消
+	if err := refund(record.method, record.amount); err != nil {
+		return err
+	}
+
+	c.inventory.Restock(o.Items)
+	c.mu.Lock()
+	delete(c.payments, o.ID)
+	c.mu.Unlock()
+	return o.setStatus(Canceled)
+}
+
+// refund 原路退回 amount；支付方式不支持退款时返回 ErrRefundUnsupported
+func refund(pm PaymentMethod, amount float64) error {
+	refunder, ok := pm.(RefundableMethod)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRefundUnsupported, pm.GetName())
+	}
+	if err := refunder.Refund(amount); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrRefundFailed, pm.GetName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// ========== 模拟支付方式 ==========
+
+// wallet 总是成功、支持退款，记录扣款次数
+type wallet struct {
+	mu      sync.Mutex
+	balance float64
+	charges int
+}
+
+func (w *wallet) Pay(amount float64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.balance < amount {
+		return fmt.Errorf("余额不足：需要 ¥%.2f，只有 ¥%.2f", amount, w.balance)
+	}
+	w.balance -= amount
+	w.charges++
+	return nil
+}
+
+func (w *wallet) Refund(amount float64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.balance += amount
+	return nil
+}
+
+func (w *wallet) GetName() string { return "钱包" }
+
+// brokenCard 故意扣款失败
+type brokenCard struct{}
+
+func (brokenCard) Pay(amount float64) error { return errors.New("银行卡已冻结") }
+func (brokenCard) GetName() string          { return "银行卡" }
+
+// panicCard 扣款时 panic
+type panicCard struct{}
+
+func (panicCard) Pay(amount float64) error { panic("渠道 SDK 崩溃") }
+func (panicCard) GetName() string          { return "问题渠道" }
+
+// noRefundCoupon 可以支付但退款失败
+type noRefundCoupon struct{}
+
+func (noRefundCoupon) Pay(amount float64) error { return nil }
+func (noRefundCoupon) GetName() string          { return "优惠券" }
+
+func (noRefundCoupon) Refund(amount float64) error {
+	return errors.New("优惠券已核销，不能退回")
+}
+
+// sneakyWallet 扣款成功，但扣款过程中绕过订单锁把订单直接改成已取消
+type sneakyWallet struct {
+	wallet
+	order *Order
+}
+
+func (s *sneakyWallet) Pay(amount float64) error {
+	if err := s.wallet.Pay(amount); err != nil {
+		return err
+	}
+	s.order.Status = Canceled
+	return nil
+}
+
+// cashOnly 不支持退款
+type cashOnly struct{}
+
+func (cashOnly) Pay(amount float64) error { return nil }
+func (cashOnly) GetName() string          { return "现金" }
+
+// ========== 测试 ==========
+
+var (
+	laptop = Product{ID: 1, Name: "笔记本电脑", Price: 5999.99, Stock: 2}
+	phone  = Product{ID: 2, Name: "智能手机", Price: 3999.50, Stock: 5}
+)
+
+func newOrder(t *testing.T, id int, items ...OrderItem) *Order {
+	t.Helper()
+	o := NewOrder(id)
+	for _, item := range items {
+		if err := o.AddItem(item.Product, item.Quantity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return o
+}
+
+func TestChangeStatusToPaidRequiresPayment(t *testing.T) {
+	o := newOrder(t, 1, OrderItem{laptop, 1})
+	if err := o.ChangeStatus(Paid); !errors.Is(err, ErrPaymentRequired) {
+		t.Fatalf("ChangeStatus(Paid) err = %v, want ErrPaymentRequired", err)
+	}
+	if o.Status != Pending {
+		t.Fatalf("状态 = %v, want 待支付", o.Status)
+	}
+}
+
+func TestPayFailureRollsBackStock(t *testing.T) {
+	inv := NewInventory(laptop, phone)
+	c := NewCheckout(inv)
+	o := newOrder(t, 1, OrderItem{laptop, 2}, OrderItem{phone, 1})
+
+	if _, err := c.Pay(o, brokenCard{}); !errors.Is(err, ErrPaymentFailed) {
+		t.Fatalf("err = %v, want ErrPaymentFailed", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("支付方式 panic 应当继续向上传递")
+			}
+		}()
+		c.Pay(o, panicCard{})
+	}()
+
+	if o.Status != Pending {
+		t.Errorf("状态 = %v, want 待支付", o.Status)
+	}
+	if inv.Available(laptop.ID) != 2 || inv.Available(phone.ID) != 5 {
+		t.Errorf("库存没有回滚: 笔记本 %d, 手机 %d", inv.Available(laptop.ID), inv.Available(phone.ID))
+	}
+}
+
+func TestPayThenCancelRefunds(t *testing.T) {
+	inv := NewInventory(laptop, phone)
+	c := NewCheckout(inv)
+	w := &wallet{balance: 10000}
+	o := newOrder(t, 1, OrderItem{laptop, 1})
+
+	receipt, err := c.Pay(o, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Amount != 5999.99 || o.Status != Paid || inv.Available(laptop.ID) != 1 {
+		t.Fatalf("支付后 receipt=%+v 状态=%v 库存=%d", receipt, o.Status, inv.Available(laptop.ID))
+	}
+	if err := o.Cancel(); !errors.Is(err, ErrPaymentRequired) {
+		t.Fatalf("已支付订单直接 Cancel 的 err = %v, want ErrPaymentRequired", err)
+	}
+
+	if err := c.Cancel(o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Status != Canceled || w.balance != 10000 || inv.Available(laptop.ID) != 2 {
+		t.Fatalf("取消后 状态=%v 余额=%.2f 库存=%d", o.Status, w.balance, inv.Available(laptop.ID))
+	}
+	if err := c.Cancel(o); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("重复取消 err = %v, want ErrInvalidTransition", err)
+	}
+}
+
+func TestOutOfStock(t *testing.T) {
+	inv := NewInventory(laptop)
+	c := NewCheckout(inv)
+	if _, err := c.Pay(newOrder(t, 1, OrderItem{laptop, 2}), &wallet{balance: 1e6}); err != nil {
+		t.Fatal(err)
+	}
+	w := &wallet{balance: 1e6}
+	if _, err := c.Pay(newOrder(t, 2, OrderItem{laptop, 1}), w); !errors.Is(err, ErrOutOfStock) {
+		t.Fatalf("err = %v, want ErrOutOfStock", err)
+	}
+	if w.charges != 0 {
+		t.Fatal("库存不足时不应扣款")
+	}
+}
+
+func TestRefundFailureKeepsOrderPaid(t *testing.T) {
+	inv := NewInventory(phone)
+	c := NewCheckout(inv)
+
+	o := newOrder(t, 1, OrderItem{phone, 1})
+	if _, err := c.Pay(o, noRefundCoupon{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Cancel(o); !errors.Is(err, ErrRefundFailed) {
+		t.Fatalf("err = %v, want ErrRefundFailed", err)
+	}
+
+	o2 := newOrder(t, 2, OrderItem{phone, 1})
+	if _, err := c.Pay(o2, cashOnly{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Cancel(o2); !errors.Is(err, ErrRefundUnsupported) {
+		t.Fatalf("err = %v, want ErrRefundUnsupported", err)
+	}
+
+	if o.Status != Paid || o2.Status != Paid || inv.Available(phone.ID) != 3 {
+		t.Fatalf("退款失败后 状态=%v/%v 库存=%d", o.Status, o2.Status, inv.Available(phone.ID))
+	}
+}
+
+// 同一个订单并发支付只扣一次款，并发取消只退一次款
+func TestConcurrentPayChargesOnce(t *testing.T) {
+	inv := NewInventory(Product{ID: 9, Name: "贴纸", Price: 1, Stock: 100})
+	c := NewCheckout(inv)
+	w := &wallet{balance: 1000}
+	o := newOrder(t, 1, OrderItem{Product{ID: 9, Name: "贴纸", Price: 1, Stock: 100}, 1})
+
+	var ok atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Pay(o, w); err == nil {
+				ok.Add(1)
+			} else if !errors.Is(err, ErrInvalidTransition) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if ok.Load() != 1 || w.charges != 1 || inv.Available(9) != 99 {
+		t.Fatalf("成功 %d 次，扣款 %d 次，库存 %d", ok.Load(), w.charges, inv.Available(9))
+	}
+
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Cancel(o)
+		}()
+	}
+	wg.Wait()
+	if w.balance != 1000 || inv.Available(9) != 100 || o.Status != Canceled {
+		t.Fatalf("并发取消后 余额=%.2f 库存=%d 状态=%v", w.balance, inv.Available(9), o.Status)
+	}
+}
+
+// 扣款成功但订单无法进入已支付时要原路退款并归还库存
+func TestPayRefundsWhenStatusChangeFails(t *testing.T) {
+	inv := NewInventory(laptop)
+	c := NewCheckout(inv)
+	o := newOrder(t, 1, OrderItem{laptop, 1})
+	w := &sneakyWallet{wallet: wallet{balance: 10000}, order: o}
+
+	_, err := c.Pay(o, w)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("err = %v, want ErrInvalidTransition", err)
+	}
+	if errors.Is(err, ErrRefundFailed) || w.charges != 1 || w.balance != 10000 || inv.Available(laptop.ID) != 2 {
+		t.Fatalf("err=%v 扣款 %d 次 余额=%.2f 库存=%d, want 已退款且库存归还", err, w.charges, w.balance, inv.Available(laptop.ID))
+	}
+	if err := c.Cancel(o); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("没有支付记录的订单 Cancel err = %v, want ErrInvalidTransition", err)
+	}
+}
+
+// Order 自己的 ChangeStatus、Cancel 与 Checkout.Pay 共用订单锁：
+// 要么先取消、不扣款，要么先支付、直接取消被拒绝，不会出现已取消却扣了款
+func TestCancelRacesWithPay(t *testing.T) {
+	for range 50 {
+		inv := NewInventory(phone)
+		c := NewCheckout(inv)
+		w := &wallet{balance: 10000}
+		o := newOrder(t, 1, OrderItem{phone, 1})
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			o.Cancel()
+		}()
+		go func() {
+			defer wg.Done()
+			c.Pay(o, w)
+		}()
+		wg.Wait()
+
+		switch o.Status {
+		case Canceled:
+			if w.charges != 0 || inv.Available(phone.ID) != 5 {
+				t.Fatalf("订单已取消却扣款 %d 次，库存 %d", w.charges, inv.Available(phone.ID))
+			}
+		case Paid:
+			if w.charges != 1 || inv.Available(phone.ID) != 4 {
+				t.Fatalf("订单已支付，扣款 %d 次，库存 %d", w.charges, inv.Available(phone.ID))
+			}
+			if err := o.ChangeStatus(Shipping); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			t.Fatalf("状态 = %v, want 已取消或已支付", o.Status)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ========== 库存 ==========
+
+var ErrOutOfStock = errors.New("库存不足")
+
+// Inventory 管理商品库存。结账时先预留库存，支付成功后确认，失败则释放。
+type Inventory struct {
+	mu    sync.Mutex
+	stock map[int]int // 商品ID -> 可用库存
+}
+
+func NewInventory(products ...Product) *Inventory {
+	inv := &Inventory{stock: make(map[int]int)}
+	for _, p := range products {
+		inv.stock[p.ID] += p.Stock
+	}
+	return inv
+}
+
+// Available 返回商品当前可用库存
+func (inv *Inventory) Available(productID int) int {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	return inv.stock[productID]
+}
+
+// Reservation 是一次库存预留，只能被 Release 一次
+type Reservation struct {
+	inv      *Inventory
+	items    map[int]int
+	released bool
+}
+
+// Reserve 为订单项预留库存：要么全部成功，要么一个都不预留
+func (inv *Inventory) Reserve(items []OrderItem) (*Reservation, error) {
+	need := make(map[int]int)
+	for _, item := range items {
+		need[item.Product.ID] += item.Quantity
+	}
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	for _, item := range items {
+		if inv.stock[item.Product.ID] < need[item.Product.ID] {
+			return nil, fmt.Errorf("%w: 商品 %s 需要 %d 件，仅剩 %d 件",
+				ErrOutOfStock, item.Product.Name, need[item.Product.ID], inv.stock[item.Product.ID])
+		}
+	}
+	for id, quantity := range need {
+		inv.stock[id] -= quantity
+	}
+	return &Reservation{inv: inv, items: need}, nil
+}
+
+// Release 归还预留的库存（支付失败时回滚）
+func (r *Reservation) Release() {
+	r.inv.mu.Lock()
+	defer r.inv.mu.Unlock()
+	if r.released {
+		return
+	}
+	r.released = true
+	for id, quantity := range r.items {
+		r.inv.stock[id] += quantity
+	}
+}
+
+// Restock 把已售出的商品放回库存（已支付订单取消并退款后）
+func (inv *Inventory) Restock(items []OrderItem) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	for _, item := range items {
+		inv.stock[item.Product.ID] += item.Quantity
+	}
+}
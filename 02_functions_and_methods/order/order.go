@@ -0,0 +1,289 @@
+// Package order 是第02节订单管理挑战（challenge_template.go）的参考实现，
+// 并加上了结账服务：订单只能通过 Checkout 在扣款成功后进入"已支付"状态。
+package order
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ========== 订单状态 ==========
+
+// OrderStatus 的 String 等方法由 enumgen 生成，显示名称来自 enum:"..." 标注
+//
+//go:generate go run golang_study/tools/enumgen -type=OrderStatus
+type OrderStatus int
+
+const (
+	Pending   OrderStatus = iota + 1 // 1 enum:"待支付"
+	Paid                             // 2 enum:"已支付"
+	Shipping                         // 3 enum:"发货中"
+	Completed                        // 4 enum:"已完成"
+	Canceled                         // 5 enum:"已取消"
+)
+
+var (
+	ErrInvalidTransition = errors.New("订单状态变更不合法")
+	ErrPaymentRequired   = errors.New("订单必须通过结账服务支付")
+)
+
+// ========== 商品 ==========
+
+type Product struct {
+	ID    int     // 商品ID
+	Name  string  // 商品名称
+	Price float64 // 单价
+	Stock int     // 库存数量
+}
+
+func (p Product) ShowInfo() {
+	fmt.Printf("[%d] %s - ¥%.2f (库存: %d件)\n", p.ID, p.Name, p.Price, p.Stock)
+}
+
+func (p Product) IsAvailable(quantity int) bool {
+	return p.Stock >= quantity
+}
+
+func (p *Product) UpdateStock(quantity int) error {
+	newStock := p.Stock + quantity
+	if newStock < 0 {
+		return fmt.Errorf("库存不足，无法减少 %d 件", -quantity)
+	}
+	p.Stock = newStock
+	return nil
+}
+
+// ========== 订单 ==========
+
+type OrderItem struct {
+	Product  Product // 商品信息
+	Quantity int     // 购买数量
+}
+
+type Order struct {
+	ID     int         // 订单ID
+	Items  []OrderItem // 订单项列表
+	Status OrderStatus // 订单状态（只读，修改请使用 ChangeStatus、Cancel 或 Checkout）
+
+	// mu 串行化同一订单的状态变更，ChangeStatus、Cancel 和 Checkout 共用；
+	// 用指针是为了让 CalculateTotal 等值接收者方法可以复制 Order
+	mu *sync.Mutex
+}
+
+// NewOrder 创建一个待支付的空订单
+func NewOrder(id int) *Order {
+	return &Order{ID: id, Status: Pending, mu: &sync.Mutex{}}
+}
+
+// lazyLock 保护字面量创建的订单第一次加锁时的初始化
+var lazyLock sync.Mutex
+
+// lock 锁住订单的状态，返回解锁函数
+func (o *Order) lock() func() {
+	lazyLock.Lock()
+	if o.mu == nil {
+		o.mu = &sync.Mutex{}
+	}
+	l := o.mu
+	lazyLock.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+func (o Order) CalculateTotal() float64 {
+	total := 0.0
+	for _, item := range o.Items {
+		total += item.Product.Price * float64(item.Quantity)
+	}
+	return total
+}
+
+func (o Order) GetItemCount() int {
+	count := 0
+	for _, item := range o.Items {
+		count += item.Quantity
+	}
+	return count
+}
+
+// AddItem 只允许在待支付状态下添加商品。这里只按商品快照粗略检查库存，真正的库存在结账时预留
+func (o *Order) AddItem(product Product, quantity int) error {
+	if quantity <= 0 {
+		return fmt.Errorf("购买数量必须大于0")
+	}
+	if !product.IsAvailable(quantity) {
+		return fmt.Errorf("商品 %s 库存不足", product.Name)
+	}
+	if o.Status != Pending {
+		return fmt.Errorf("订单状态为 %v，无法修改", o.Status)
+	}
+	o.Items = append(o.Items, OrderItem{Product: product, Quantity: quantity})
+	return nil
+}
+
+// ChangeStatus 处理与支付无关的状态流转。
+// 进入 Paid 必须走 Checkout.Pay，从 Paid 取消必须走 Checkout.Cancel（需要退款）。
+func (o *Order) ChangeStatus(newStatus OrderStatus) error {
+	defer o.lock()()
+	if newStatus == Paid {
+		return ErrPaymentRequired
+	}
+	if o.Status == Paid && newStatus == Canceled {
+		return fmt.Errorf("%w: 已支付订单需要通过结账服务退款取消", ErrPaymentRequired)
+	}
+	return o.setStatus(newStatus)
+}
+
+// Cancel 取消待支付的订单；已支付的订单要退款，需要通过 Checkout.Cancel
+func (o *Order) Cancel() error {
+	defer o.lock()()
+	switch o.Status {
+	case Shipping, Completed:
+		return fmt.Errorf("%w: 订单已发货，无法取消", ErrInvalidTransition)
+	case Paid:
+		return fmt.Errorf("%w: 已支付订单需要通过结账服务退款取消", ErrPaymentRequired)
+	}
+	return o.setStatus(Canceled)
+}
+
+// 状态流转表：Pending -> Paid/Canceled，Paid -> Shipping/Canceled，Shipping -> Completed
+func canTransition(from, to OrderStatus) bool {
+	switch from {
+	case Pending:
+		return to == Paid || to == Canceled
+	case Paid:
+		return to == Shipping || to == Canceled
+	case Shipping:
+		return to == Completed
+	default:
+		return false
+	}
+}
+
+// setStatus 按流转表修改状态；Checkout 在支付/退款成功后直接调用它，跳过支付检查。调用方需持有订单锁
+func (o *Order) setStatus(newStatus OrderStatus) error {
+	if !canTransition(o.Status, newStatus) {
+		return fmt.Errorf("%w: 无法从 %v 变更到 %v", ErrInvalidTransition, o.Status, newStatus)
+	}
+	o.Status = newStatus
+	return nil
+}
+
+// enumgen:begin OrderStatus
+// 以下代码由 enumgen 根据 OrderStatus 的常量生成，请勿手动修改；修改常量后重新运行 go generate。
+
+// String 返回显示名称（常量注释中的 enum:"..."，没有标注时为常量名）
+func (x OrderStatus) String() string {
+	switch x {
+	case Pending:
+		return "待支付"
+	case Paid:
+		return "已支付"
+	case Shipping:
+		return "发货中"
+	case Completed:
+		return "已完成"
+	case Canceled:
+		return "已取消"
+	}
+	return fmt.Sprintf("OrderStatus(%d)", int64(x))
+}
+
+// Name 返回常量名，用于文本和 JSON 序列化
+func (x OrderStatus) Name() string {
+	switch x {
+	case Pending:
+		return "Pending"
+	case Paid:
+		return "Paid"
+	case Shipping:
+		return "Shipping"
+	case Completed:
+		return "Completed"
+	case Canceled:
+		return "Canceled"
+	}
+	return fmt.Sprintf("OrderStatus(%d)", int64(x))
+}
+
+// IsValid 报告 x 是否是已定义的常量
+func (x OrderStatus) IsValid() bool {
+	switch x {
+	case Pending, Paid, Shipping, Completed, Canceled:
+		return true
+	}
+	return false
+}
+
+// OrderStatusValues 按声明顺序返回所有常量
+func OrderStatusValues() []OrderStatus {
+	return []OrderStatus{Pending, Paid, Shipping, Completed, Canceled}
+}
+
+// ParseOrderStatus 按常量名或显示名称解析
+func ParseOrderStatus(s string) (OrderStatus, error) {
+	switch s {
+	case "Pending", "待支付":
+		return Pending, nil
+	case "Paid", "已支付":
+		return Paid, nil
+	case "Shipping", "发货中":
+		return Shipping, nil
+	case "Completed", "已完成":
+		return Completed, nil
+	case "Canceled", "已取消":
+		return Canceled, nil
+	}
+	return 0, fmt.Errorf("无效的 OrderStatus: %q", s)
+}
+
+// MarshalText 实现 encoding.TextMarshaler，输出常量名
+func (x OrderStatus) MarshalText() ([]byte, error) {
+	if !x.IsValid() {
+		return nil, fmt.Errorf("无效的 OrderStatus: %d", int64(x))
+	}
+	return []byte(x.Name()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，接受常量名或显示名称
+func (x *OrderStatus) UnmarshalText(text []byte) error {
+	v, err := ParseOrderStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+// MarshalJSON 输出常量名字符串
+func (x OrderStatus) MarshalJSON() ([]byte, error) {
+	text, err := x.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%q", text)), nil
+}
+
+// UnmarshalJSON 接受字符串（常量名或显示名称）或数字；null 保持原值不变，与标准库的约定一致
+func (x *OrderStatus) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("无效的 OrderStatus: %s", data)
+		}
+		return x.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil || !OrderStatus(n).IsValid() {
+		return fmt.Errorf("无效的 OrderStatus: %s", data)
+	}
+	*x = OrderStatus(n)
+	return nil
+}
+
+// enumgen:end OrderStatus
@@ -0,0 +1,38 @@
+package order
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOrderStatusJSON(t *testing.T) {
+	data, err := json.Marshal(Paid)
+	if err != nil || string(data) != `"Paid"` {
+		t.Fatalf("Marshal(Paid) = %s, %v", data, err)
+	}
+
+	for _, input := range []string{`"Paid"`, `"已支付"`, `2`, `"\u5df2\u652f\u4ed8"`} {
+		var s OrderStatus
+		if err := json.Unmarshal([]byte(input), &s); err != nil || s != Paid {
+			t.Errorf("Unmarshal(%s) = %v, %v, want 已支付", input, s, err)
+		}
+	}
+
+	// null 保持原值，和标准库对其他类型的处理一致
+	var o struct{ Status OrderStatus }
+	o.Status = Shipping
+	if err := json.Unmarshal([]byte(`{"Status": null}`), &o); err != nil || o.Status != Shipping {
+		t.Fatalf("null 之后状态 = %v, %v, want 发货中", o.Status, err)
+	}
+
+	// 直接调用 UnmarshalJSON 时也不能接受 Go 语法的字符串或多余的字符
+	for _, input := range []string{"`Paid`", `"Paid" junk`, `"Paid`, `2.0`, `0`, `99`, `"paid"`, ``} {
+		s := Pending
+		if err := s.UnmarshalJSON([]byte(input)); err == nil {
+			t.Errorf("UnmarshalJSON(%s) 应当失败，得到 %v", input, s)
+		}
+		if s != Pending {
+			t.Errorf("UnmarshalJSON(%s) 失败后修改了原值: %v", input, s)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Package ecs 是第04节"组合"示例的运行时版本：一个简单的实体-组件系统。
+//
+// Car 嵌入 Engine、Duck 嵌入 Flyer 和 Swimmer 的组合在编译期就固定了，
+// 而且两个嵌入类型都有 Name 字段时，duck.Name 是有歧义的。
+// 这里实体在运行时挂载组件，按"能力"（接口）查询实体；
+// 当多个组件都提供同一能力时，查询返回 ErrAmbiguous，必须用 Resolve 显式指定。
+package ecs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrDuplicateComponent = errors.New("组件已存在")
+	ErrMissingComponent   = errors.New("没有提供该能力的组件")
+	ErrAmbiguous          = errors.New("多个组件提供同一能力")
+	ErrNotAttached        = errors.New("组件未挂载到实体上")
+	ErrDespawned          = errors.New("实体已销毁")
+)
+
+// Component 可以是任意值，同一实体上每种具体类型最多挂载一个
+type Component any
+
+type ID uint64
+
+// ========== 实体 ==========
+
+// Entity 是组件的容器。实体自己的 Name 与组件里的同名字段互不干扰。
+type Entity struct {
+	ID   ID
+	Name string
+
+	world      *World
+	components []Component                   // 按挂载顺序
+	resolved   map[reflect.Type]reflect.Type // 能力 -> 显式指定的组件类型
+	alive      bool
+}
+
+func (e *Entity) String() string {
+	return fmt.Sprintf("#%d %s", e.ID, e.Name)
+}
+
+// Add 挂载组件；同一具体类型重复挂载返回 ErrDuplicateComponent
+func (e *Entity) Add(c Component) error {
+	e.world.mu.Lock()
+	defer e.world.mu.Unlock()
+	return e.add(c)
+}
+
+func (e *Entity) add(c Component) error {
+	if err := e.checkAlive(); err != nil {
+		return err
+	}
+	if c == nil {
+		return fmt.Errorf("%v: 组件不能为 nil", e)
+	}
+	t := reflect.TypeOf(c)
+	if e.indexOf(t) >= 0 {
+		return fmt.Errorf("%v: %w: %v", e, ErrDuplicateComponent, t)
+	}
+	e.components = append(e.components, c)
+	return nil
+}
+
+// Remove 卸载与 c 具体类型相同的组件，指向它的显式指定也一并清除；实体已销毁时返回 ErrDespawned
+func (e *Entity) Remove(c Component) error {
+	e.world.mu.Lock()
+	defer e.world.mu.Unlock()
+	if err := e.checkAlive(); err != nil {
+		return err
+	}
+	t := reflect.TypeOf(c)
+	i := e.indexOf(t)
+	if i < 0 {
+		return fmt.Errorf("%v: %w: %v", e, ErrNotAttached, t)
+	}
+	e.components = append(e.components[:i], e.components[i+1:]...)
+	for capability, provider := range e.resolved {
+		if provider == t {
+			delete(e.resolved, capability)
+		}
+	}
+	return nil
+}
+
+// Replace 替换同类型的组件（组件通常是值类型，修改后需要写回）
+func (e *Entity) Replace(c Component) error {
+	e.world.mu.Lock()
+	defer e.world.mu.Unlock()
+	if err := e.checkAlive(); err != nil {
+		return err
+	}
+	i := e.indexOf(reflect.TypeOf(c))
+	if i < 0 {
+		return e.add(c)
+	}
+	e.components[i] = c
+	return nil
+}
+
+// Components 返回组件快照（按挂载顺序）
+func (e *Entity) Components() []Component {
+	e.world.mu.RLock()
+	defer e.world.mu.RUnlock()
+	return append([]Component(nil), e.components...)
+}
+
+// checkAlive 对已销毁的实体返回 ErrDespawned；调用方需持有 world.mu
+func (e *Entity) checkAlive() error {
+	if !e.alive {
+		return fmt.Errorf("%v: %w", e, ErrDespawned)
+	}
+	return nil
+}
+
+func (e *Entity) indexOf(t reflect.Type) int {
+	for i, c := range e.components {
+		if reflect.TypeOf(c) == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// ========== 能力查询 ==========
+
+// Get 返回实体上提供能力 T 的组件。T 可以是具体类型，也可以是接口（例如"会飞"）。
+// 实体已销毁时返回 ErrDespawned，没有组件提供时返回 ErrMissingComponent；
+// 多个组件都提供且没有用 Resolve 指定时返回 ErrAmbiguous，而不是随便挑一个。
+func Get[T any](e *Entity) (T, error) {
+	e.world.mu.RLock()
+	defer e.world.mu.RUnlock()
+	return get[T](e)
+}
+
+func get[T any](e *Entity) (T, error) {
+	var zero T
+	if err := e.checkAlive(); err != nil {
+		return zero, err
+	}
+	capability := reflect.TypeFor[T]()
+
+	if provider, ok := e.resolved[capability]; ok {
+		if i := e.indexOf(provider); i >= 0 {
+			return e.components[i].(T), nil
+		}
+	}
+
+	var found []T
+	var types []string
+	for _, c := range e.components {
+		if v, ok := c.(T); ok {
+			found = append(found, v)
+			types = append(types, reflect.TypeOf(c).String())
+		}
+	}
+	switch len(found) {
+	case 0:
+		return zero, fmt.Errorf("%v: %w: %v", e, ErrMissingComponent, capability)
+	case 1:
+		return found[0], nil
+	default:
+		return zero, fmt.Errorf("%v: %w %v: %s", e, ErrAmbiguous, capability, strings.Join(types, ", "))
+	}
+}
+
+// Has 报告实体上是否有组件提供能力 T（有歧义也算有）
+func Has[T any](e *Entity) bool {
+	e.world.mu.RLock()
+	defer e.world.mu.RUnlock()
+	for _, c := range e.components {
+		if _, ok := c.(T); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve 显式指定由哪个已挂载的组件提供能力 T，用于消除歧义
+func Resolve[T any](e *Entity, provider Component) error {
+	e.world.mu.Lock()
+	defer e.world.mu.Unlock()
+	if err := e.checkAlive(); err != nil {
+		return err
+	}
+	capability := reflect.TypeFor[T]()
+	t := reflect.TypeOf(provider)
+	if _, ok := provider.(T); !ok {
+		return fmt.Errorf("%v: %v 不提供能力 %v", e, t, capability)
+	}
+	if e.indexOf(t) < 0 {
+		return fmt.Errorf("%v: %w: %v", e, ErrNotAttached, t)
+	}
+	if e.resolved == nil {
+		e.resolved = make(map[reflect.Type]reflect.Type)
+	}
+	e.resolved[capability] = t
+	return nil
+}
+
+// ========== 世界 ==========
+
+// World 保存所有实体和系统，并发安全
+type World struct {
+	mu       sync.RWMutex
+	nextID   ID
+	entities map[ID]*Entity
+	systems  []system
+}
+
+type system struct {
+	name string
+	run  func(*World) error
+}
+
+func NewWorld() *World {
+	return &World{entities: make(map[ID]*Entity)}
+}
+
+// Spawn 创建实体并挂载组件，任何一个组件冲突都会导致创建失败
+func (w *World) Spawn(name string, components ...Component) (*Entity, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	e := &Entity{ID: w.nextID + 1, Name: name, world: w, alive: true}
+	for _, c := range components {
+		if err := e.add(c); err != nil {
+			return nil, err
+		}
+	}
+	w.nextID++
+	w.entities[e.ID] = e
+	return e, nil
+}
+
+// Despawn 销毁实体
+func (w *World) Despawn(id ID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if e, ok := w.entities[id]; ok {
+		e.alive = false
+		delete(w.entities, id)
+	}
+}
+
+// Entity 按 ID 查找实体
+func (w *World) Entity(id ID) (*Entity, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	e, ok := w.entities[id]
+	return e, ok
+}
+
+// Entities 返回所有实体，按 ID（即创建顺序）排序
+func (w *World) Entities() []*Entity {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.sorted()
+}
+
+func (w *World) sorted() []*Entity {
+	list := make([]*Entity, 0, len(w.entities))
+	for _, e := range w.entities {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Match 是一次查询命中的实体和它提供能力的组件
+type Match[T any] struct {
+	Entity *Entity
+	Value  T
+}
+
+// Query 找出所有能提供能力 T 的实体（例如"所有会飞的"），按 ID 排序。
+// 有歧义的实体不会出现在结果里，而是汇总到返回的错误中（errors.Is(err, ErrAmbiguous)）。
+func Query[T any](w *World) ([]Match[T], error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	var matches []Match[T]
+	var errs []error
+	for _, e := range w.sorted() {
+		v, err := get[T](e)
+		switch {
+		case err == nil:
+			matches = append(matches, Match[T]{Entity: e, Value: v})
+		case errors.Is(err, ErrAmbiguous):
+			errs = append(errs, err)
+		}
+	}
+	return matches, errors.Join(errs...)
+}
+
+// ========== 系统 ==========
+
+// AddSystem 注册系统，Tick 时按注册顺序执行
+func (w *World) AddSystem(name string, run func(*World) error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.systems = append(w.systems, system{name: name, run: run})
+}
+
+// Each 生成一个系统：对每个提供能力 T 的实体调用 fn
+func Each[T any](fn func(e *Entity, v T) error) func(*World) error {
+	return func(w *World) error {
+		matches, err := Query[T](w)
+		for _, m := range matches {
+			if ferr := fn(m.Entity, m.Value); ferr != nil {
+				err = errors.Join(err, ferr)
+			}
+		}
+		return err
+	}
+}
+
+// Tick 依次运行所有系统；某个系统出错不影响后面的系统，错误会带上系统名一起返回
+func (w *World) Tick() error {
+	w.mu.RLock()
+	systems := append([]system(nil), w.systems...)
+	w.mu.RUnlock()
+
+	var errs []error
+	for _, s := range systems {
+		if err := s.run(w); err != nil {
+			errs = append(errs, fmt.Errorf("系统 %s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+package ecs
+
+import (
+	"errors"
+	"testing"
+)
+
+type flyer interface{ Fly() string }
+
+type swimmer interface{ Swim() string }
+
+type wings struct{ Span int }
+
+func (w wings) Fly() string { return "扇动翅膀" }
+
+type jetpack struct{}
+
+func (jetpack) Fly() string { return "喷气" }
+
+type fins struct{}
+
+func (fins) Swim() string { return "划水" }
+
+type name struct{ Name string }
+
+func TestEntityComponents(t *testing.T) {
+	w := NewWorld()
+	duck, err := w.Spawn("鸭子", wings{Span: 2}, fins{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := w.Spawn("旧实体", wings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Despawn(stale.ID)
+
+	cases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"Add 新组件", duck.Add(name{"唐老鸭"}), nil},
+		{"Add 重复类型", duck.Add(wings{Span: 3}), ErrDuplicateComponent},
+		{"Remove 已挂载", duck.Remove(name{}), nil},
+		{"Remove 未挂载", duck.Remove(name{}), ErrNotAttached},
+		{"Add 已销毁", stale.Add(fins{}), ErrDespawned},
+		{"Remove 已销毁", stale.Remove(wings{}), ErrDespawned},
+		{"Replace 已销毁", stale.Replace(wings{Span: 9}), ErrDespawned},
+		{"Resolve 已销毁", Resolve[flyer](stale, wings{}), ErrDespawned},
+	}
+	for _, c := range cases {
+		if !errors.Is(c.err, c.want) || (c.want == nil) != (c.err == nil) {
+			t.Errorf("%s: err = %v, want %v", c.name, c.err, c.want)
+		}
+	}
+	if n := len(stale.Components()); n != 1 {
+		t.Errorf("已销毁实体的组件数 %d，操作失败后不应改变, want 1", n)
+	}
+
+	if f, err := Get[flyer](duck); err != nil || f.(wings).Span != 2 {
+		t.Errorf("Get[flyer] = %v, %v, want wings{2}", f, err)
+	}
+	if _, err := Get[name](duck); !errors.Is(err, ErrMissingComponent) {
+		t.Errorf("Get 已卸载的组件 err = %v, want ErrMissingComponent", err)
+	}
+	if _, err := Get[wings](stale); !errors.Is(err, ErrDespawned) {
+		t.Errorf("Get 已销毁实体 err = %v, want ErrDespawned", err)
+	}
+	if _, ok := w.Entity(stale.ID); ok {
+		t.Error("Despawn 之后仍能按 ID 找到实体")
+	}
+}
+
+func TestQueryAndResolve(t *testing.T) {
+	w := NewWorld()
+	duck, _ := w.Spawn("鸭子", wings{}, fins{})
+	robot, _ := w.Spawn("机器鸭", wings{Span: 5}, jetpack{})
+	fish, _ := w.Spawn("鱼", fins{})
+
+	// 机器鸭有两个会飞的组件：不出现在结果里，而是报告歧义
+	flyers, err := Query[flyer](w)
+	if !errors.Is(err, ErrAmbiguous) {
+		t.Fatalf("Query[flyer] err = %v, want ErrAmbiguous", err)
+	}
+	if len(flyers) != 1 || flyers[0].Entity != duck {
+		t.Fatalf("Query[flyer] = %v, want 只有鸭子", flyers)
+	}
+	if _, err := Get[flyer](robot); !errors.Is(err, ErrAmbiguous) {
+		t.Fatalf("Get[flyer](机器鸭) err = %v, want ErrAmbiguous", err)
+	}
+
+	// 不提供该能力或没有挂载的组件不能用来消除歧义
+	if err := Resolve[swimmer](robot, wings{}); err == nil {
+		t.Error("Resolve 不提供能力的组件应当失败")
+	}
+	if err := Resolve[flyer](fish, wings{}); !errors.Is(err, ErrNotAttached) {
+		t.Errorf("Resolve 未挂载的组件 err = %v, want ErrNotAttached", err)
+	}
+
+	if err := Resolve[flyer](robot, jetpack{}); err != nil {
+		t.Fatal(err)
+	}
+	flyers, err = Query[flyer](w)
+	if err != nil || len(flyers) != 2 || flyers[1].Entity != robot || flyers[1].Value.Fly() != "喷气" {
+		t.Fatalf("Resolve 后 Query[flyer] = %v, %v, want 鸭子和喷气的机器鸭", flyers, err)
+	}
+
+	// 卸载被指定的组件后，指定随之失效，剩下的 wings 成为唯一的提供者
+	if err := robot.Remove(jetpack{}); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := Get[flyer](robot); err != nil || f.Fly() != "扇动翅膀" {
+		t.Fatalf("卸载 jetpack 后 Get[flyer] = %v, %v, want wings", f, err)
+	}
+
+	// 已销毁的实体不再出现在查询结果中
+	w.Despawn(duck.ID)
+	swimmers, err := Query[swimmer](w)
+	if err != nil || len(swimmers) != 1 || swimmers[0].Entity != fish {
+		t.Fatalf("Query[swimmer] = %v, %v, want 只有鱼", swimmers, err)
+	}
+}
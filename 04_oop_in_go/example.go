@@ -1,6 +1,24 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang_study/04_oop_in_go/ecs"
+	"golang_study/04_oop_in_go/memfs"
+	"golang_study/04_oop_in_go/payment"
+	"golang_study/04_oop_in_go/pretty"
+	"golang_study/04_oop_in_go/zoo"
+)
 
 // ==================== 示例1：接口的基本使用 ====================
 
@@ -77,6 +95,24 @@ func (w WeChatPay) GetName() string {
 	return "微信支付"
 }
 
+// 实现3：银行卡（Offline 时返回可重试错误，用于演示故障切换）
+type BankCard struct {
+	CardNo  string
+	Offline bool
+}
+
+func (b BankCard) Pay(amount float64) error {
+	if b.Offline {
+		return &payment.Error{Code: "unavailable", Message: "银行系统维护中", Retryable: true, Err: payment.ErrUnavailable}
+	}
+	fmt.Printf("[银行卡] 卡号 %s 支付 %.2f 元\n", b.CardNo, amount)
+	return nil
+}
+
+func (b BankCard) GetName() string {
+	return "银行卡"
+}
+
 // 统一支付处理（多态）
 func ProcessPayment(pm PaymentMethod, amount float64) {
 	fmt.Printf("使用 %s 进行支付\n", pm.GetName())
@@ -116,27 +152,76 @@ func DescribeAnimalSwitch(s Speaker) {
 	}
 }
 
+// ==================== 示例3（续）：按种类注册的动物模拟 ====================
+
+func demoZoo() {
+	fmt.Println("\n--- 动物模拟 ---")
+	// 在全局注册表的副本上注册新种类，不影响其他使用 zoo.Default() 的代码
+	kinds := zoo.Default().Clone()
+	// 鹦鹉：用装饰器把叫声重复两遍
+	kinds.Register(zoo.Kind{
+		Name:  "parrot",
+		Label: "鹦鹉",
+		Speak: zoo.Repeat(zoo.Sound("你好！"), 2),
+		Eat:   zoo.Diet{"瓜子", "苹果"},
+		Move:  zoo.Walk{Verb: "飞", Step: 2},
+	})
+	// 柴犬：相当于 DogWithOverride，只替换叫声行为
+	kinds.Register(zoo.Kind{
+		Name:  "shiba",
+		Label: "柴犬",
+		Speak: zoo.SpeakFunc(func(a *zoo.Animal, rng *rand.Rand) string {
+			return a.Name + "（柴犬）: 汪汪汪！"
+		}),
+		Eat:  zoo.Diet{"狗粮"},
+		Move: zoo.Lazy(zoo.Walk{Verb: "跑", Step: 2}, 50),
+	})
+	fmt.Println("已注册种类:", kinds.Names())
+
+	sim := zoo.NewSimulation(42)
+	for _, spec := range [][2]string{{"dog", "旺财"}, {"cat", "小白"}, {"parrot", "波利"}, {"shiba", "小柴"}, {"tiger", "大虎"}} {
+		animal, err := kinds.New(spec[0], spec[1])
+		if err != nil {
+			fmt.Println("错误:", err)
+			continue
+		}
+		fmt.Println(animal.Describe())
+		sim.Add(animal)
+	}
+
+	// 同一种子的日志固定不变，由 zoo 包的 golden 测试把关
+	for _, line := range sim.Run(6) {
+		fmt.Println(" ", line)
+	}
+}
+
 // ==================== 示例4：空接口（any） ====================
 
-// 打印任意类型的值
+// 打印任意类型的值：反射打印器可以展开结构体、切片和 map
 func PrintAny(value any) {
-	fmt.Printf("值: %v, 类型: %T\n", value, value)
+	fmt.Printf("值: %s, 类型: %T\n", pretty.SingleLine().Sprint(value), value)
+}
+
+// 处理不同类型：每种类型的处理方式注册到打印器上，而不是写在类型开关里
+var valuePrinter = pretty.SingleLine()
+
+func init() {
+	pretty.Register(valuePrinter, func(v int) string {
+		return fmt.Sprintf("整数: %d, 双倍: %d", v, v*2)
+	})
+	pretty.Register(valuePrinter, func(v string) string {
+		return fmt.Sprintf("字符串: %s, 长度: %d", v, len(v))
+	})
+	pretty.Register(valuePrinter, func(v []int) string {
+		return fmt.Sprintf("整数切片: %v, 元素个数: %d", v, len(v))
+	})
+	pretty.Register(valuePrinter, func(v Speaker) string {
+		return fmt.Sprintf("会说话的动物: %s 说 %s", v.GetName(), v.Speak())
+	})
 }
 
-// 处理不同类型
 func ProcessValue(value any) {
-	switch v := value.(type) {
-	case int:
-		fmt.Printf("整数: %d, 双倍: %d\n", v, v*2)
-	case string:
-		fmt.Printf("字符串: %s, 长度: %d\n", v, len(v))
-	case []int:
-		fmt.Printf("整数切片: %v, 元素个数: %d\n", v, len(v))
-	case Speaker:
-		fmt.Printf("会说话的动物: %s 说 %s\n", v.GetName(), v.Speak())
-	default:
-		fmt.Printf("未知类型: %T\n", v)
-	}
+	fmt.Println(valuePrinter.Sprint(value))
 }
 
 // ==================== 示例5：组合（Embedding） ====================
@@ -236,6 +321,84 @@ type Duck struct {
 	Swimmer
 }
 
+// ==================== 示例8（续）：运行时组合 ====================
+
+// 能力：任何有 Fly()/Swim() 方法的组件都算
+type CanFly interface {
+	Fly()
+}
+
+type CanSwim interface {
+	Swim()
+}
+
+// 位置和速度组件，由移动系统更新
+type Position struct {
+	X, Y int
+}
+
+type Velocity struct {
+	DX, DY int
+}
+
+func demoECS() {
+	fmt.Println("\n--- 运行时组合：实体与组件 ---")
+	world := ecs.NewWorld()
+	donald, _ := world.Spawn("唐老鸭", Flyer{Name: "唐老鸭"}, Swimmer{Name: "唐老鸭"}, Position{}, Velocity{DX: 1})
+	world.Spawn("飞机", Engine{Power: 1000, Brand: "波音"}, Flyer{Name: "飞机"}, Position{Y: 10}, Velocity{DX: 5})
+	world.Spawn("金鱼", Swimmer{Name: "金鱼"}, Position{Y: -1})
+
+	// 按能力查询："所有会飞的"
+	flyers, _ := ecs.Query[CanFly](world)
+	for _, m := range flyers {
+		fmt.Printf("[会飞] %v: ", m.Entity)
+		m.Value.Fly()
+	}
+	swimmers, _ := ecs.Query[CanSwim](world)
+	fmt.Printf("会游泳的实体数: %d\n", len(swimmers))
+
+	// 组件按类型取出，不会有 duck.Name 那样的歧义
+	flyer, _ := ecs.Get[Flyer](donald)
+	fmt.Printf("%s 的 Flyer.Name = %s\n", donald.Name, flyer.Name)
+
+	// 同一类型不能挂两次
+	if err := donald.Add(Flyer{Name: "另一只"}); err != nil {
+		fmt.Println("错误:", err)
+	}
+
+	// 两个组件都提供 Speaker 能力：查询报错，而不是随便选一个
+	toy, _ := world.Spawn("猫狗玩偶", Dog{Name: "旺财"}, Cat{Name: "小白"})
+	if _, err := ecs.Get[Speaker](toy); err != nil {
+		fmt.Println("错误:", err)
+		fmt.Println("  是歧义错误吗:", errors.Is(err, ecs.ErrAmbiguous))
+	}
+	ecs.Resolve[Speaker](toy, Dog{})
+	if speaker, err := ecs.Get[Speaker](toy); err == nil {
+		fmt.Print("显式指定后: ")
+		MakeSound(speaker)
+	}
+
+	// 系统：每个 Tick 按速度移动所有有位置的实体
+	world.AddSystem("移动", ecs.Each(func(e *ecs.Entity, v Velocity) error {
+		pos, err := ecs.Get[Position](e)
+		if err != nil {
+			return err
+		}
+		pos.X += v.DX
+		pos.Y += v.DY
+		return e.Replace(pos)
+	}))
+	for i := 0; i < 3; i++ {
+		if err := world.Tick(); err != nil {
+			fmt.Println("错误:", err)
+		}
+	}
+	positions, _ := ecs.Query[Position](world)
+	for _, m := range positions {
+		fmt.Printf("%v 位置: (%d, %d)\n", m.Entity, m.Value.X, m.Value.Y)
+	}
+}
+
 // ==================== 示例9：接口组合 ====================
 
 type Reader interface {
@@ -312,6 +475,51 @@ func main() {
 	fmt.Println()
 	ProcessPayment(wechat, 200.00)
 
+	// 接入本地模拟渠道：异步授权 + 签名回调
+	fmt.Println("\n--- 接入支付渠道 ---")
+	notifier := payment.NewNotifier("demo-secret")
+	callback := httptest.NewServer(notifier)
+	defer callback.Close()
+
+	provider := payment.NewMockProvider("demo-secret")
+	provider.CallbackURL = callback.URL
+	provider.AsyncDelay = 100 * time.Millisecond
+	provider.Limit = 1000
+	defer provider.Close()
+
+	processor := payment.NewProcessor(payment.NewHTTPGateway(provider.Start()))
+	processor.Notifier = notifier
+
+	// GatewayMethod 也是 PaymentMethod：Pay 由编排器完成 预授权 -> 扣款，失败自动重试并以渠道状态对账
+	alipayGateway := payment.GatewayMethod{Name: alipay.GetName(), Account: alipay.Account, Processor: processor}
+	wechatGateway := payment.GatewayMethod{Name: wechat.GetName(), Account: wechat.Account, Processor: processor}
+	ProcessPayment(alipayGateway, 100.50)
+	fmt.Println()
+	provider.LoseResponseNext(1) // 模拟响应丢失：靠幂等键重试，不会重复扣款
+	ProcessPayment(wechatGateway, 200.00)
+	fmt.Println()
+	ProcessPayment(alipayGateway, 5000) // 超过单笔限额，被渠道拒绝
+
+	// 多种支付方式：按偏好、金额范围和健康状况路由，可重试错误自动切换
+	fmt.Println("\n--- 支付路由与故障切换 ---")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	router := payment.NewRouter(payment.DefaultBreakerConfig(),
+		payment.Route{Method: BankCard{CardNo: "6222****1234", Offline: true}},
+		payment.Route{Method: alipay, MaxAmount: 1000},
+		payment.Route{Method: wechat},
+	)
+	fmt.Println(router.Pay(ctx, 300, "银行卡"))
+	fmt.Println(router.Pay(ctx, 3000, "支付宝"))
+
+	// 银行卡连续失败后被熔断，之后的请求直接跳过它
+	for i := 0; i < 4; i++ {
+		router.Pay(ctx, 50, "银行卡")
+	}
+	breaker := router.Breaker("银行卡")
+	fmt.Printf("银行卡成功率: %.0f%%，熔断器状态: %v\n", breaker.SuccessRate()*100, breaker.State())
+	fmt.Println(router.Pay(ctx, 80, "银行卡"))
+
 	fmt.Println("\n==================== 示例3：类型断言 ====================")
 	DescribeAnimal(dog)
 	DescribeAnimal(cat)
@@ -319,6 +527,9 @@ func main() {
 	DescribeAnimalSwitch(dog)
 	DescribeAnimalSwitch(cat)
 
+	// 开放的种类集合：新增动物只需注册，不用修改类型开关
+	demoZoo()
+
 	fmt.Println("\n==================== 示例4：空接口（any） ====================")
 	PrintAny(123)
 	PrintAny("Hello Go")
@@ -330,6 +541,23 @@ func main() {
 	ProcessValue("Go语言")
 	ProcessValue([]int{1, 2, 3, 4, 5})
 	ProcessValue(dog)
+	ProcessValue(3.14) // 没有注册的类型交给反射打印
+
+	// 缩进、单行和彩色三种输出方式，支持循环引用
+	fmt.Println("\n--- 反射打印器 ---")
+	type Node struct {
+		Name     string
+		Tags     map[string]int
+		Children []*Node
+		Parent   *Node
+	}
+	root := &Node{Name: "根", Tags: map[string]int{"z": 26, "a": 1}}
+	root.Children = []*Node{{Name: "子节点", Parent: root}}
+	pretty.Print(root)
+	fmt.Println(pretty.SingleLine().Sprint(Employee{Person: Person{Name: "张三", Age: 30}, EmployeeID: "E001"}))
+	colored := pretty.Colored()
+	colored.MaxDepth = 3
+	colored.Print(root)
 
 	fmt.Println("\n==================== 示例5：组合（Embedding） ====================")
 	emp := Employee{
@@ -381,6 +609,9 @@ func main() {
 	fmt.Printf("Flyer Name: %s\n", duck.Flyer.Name)
 	fmt.Printf("Swimmer Name: %s\n", duck.Swimmer.Name)
 
+	// 组合在运行时进行：挂载组件、按能力查询
+	demoECS()
+
 	fmt.Println("\n==================== 示例9：接口组合 ====================")
 	file := &File{Name: "test.txt"}
 	file.Open()
@@ -409,4 +640,84 @@ func main() {
 	if err != nil {
 		fmt.Println("错误:", err)
 	}
+
+	// 上面的 File 是自制接口；memfs 提供实现标准库 io 接口的内存文件系统
+	demoMemFS()
+}
+
+// 使用标准库 io 接口的内存文件系统
+func demoMemFS() {
+	fmt.Println("\n==================== 示例9（续）：标准 io 接口的内存文件 ====================")
+	fsys := memfs.New()
+	fsys.MkdirAll("docs/notes", 0o755)
+
+	f, err := fsys.Create("docs/test.txt")
+	if err != nil {
+		fmt.Println("创建失败:", err)
+		return
+	}
+	// *memfs.File 可以赋值给标准库的组合接口
+	var rwc io.ReadWriteCloser = f
+	fmt.Fprintf(rwc, "Hello, Go!\n")
+	io.WriteString(rwc, "第二行\n")
+
+	// Seek 回到开头，用 io.ReadAll 读出全部内容
+	f.Seek(0, io.SeekStart)
+	content, _ := io.ReadAll(f)
+	fmt.Printf("读取内容: %q\n", content)
+
+	// ReaderAt 不影响读写位置
+	buf := make([]byte, 2)
+	f.ReadAt(buf, 7)
+	fmt.Printf("ReadAt(7): %q\n", buf)
+	rwc.Close()
+
+	// 追加模式：两个句柄并发写入，内容都保留
+	var wg sync.WaitGroup
+	for i := 1; i <= 2; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			log, err := fsys.OpenFile("docs/notes/log.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+			if err != nil {
+				fmt.Println("打开失败:", err)
+				return
+			}
+			defer log.Close()
+			for j := 0; j < 3; j++ {
+				fmt.Fprintf(log, "writer%d-%d\n", id, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	data, _ := fsys.ReadFile("docs/notes/log.txt")
+	fmt.Printf("追加写入 %d 行\n", strings.Count(string(data), "\n"))
+
+	// 截断模式
+	fsys.WriteFile("docs/test.txt", []byte("short"), 0o644)
+	data, _ = fsys.ReadFile("docs/test.txt")
+	fmt.Printf("截断后内容: %q\n", data)
+
+	// 权限：只读文件不能以写方式打开
+	fsys.Chmod("docs/test.txt", 0o444)
+	if _, err := fsys.OpenFile("docs/test.txt", os.O_WRONLY, 0); err != nil {
+		fmt.Println("错误:", err)
+		fmt.Println("  是权限错误吗:", errors.Is(err, fs.ErrPermission))
+	}
+
+	// 关闭后的句柄
+	if _, err := f.Read(buf); err != nil {
+		fmt.Println("错误:", err)
+	}
+
+	// 作为 io/fs.FS 使用
+	fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, _ := d.Info()
+		fmt.Printf("  %s %-22s %d 字节\n", info.Mode(), path, info.Size())
+		return nil
+	})
+	// 是否完整遵守 io/fs 的约定由 memfs_test.go 中的 fstest.TestFS 检查
 }
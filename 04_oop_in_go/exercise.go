@@ -1,6 +1,15 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"golang_study/04_oop_in_go/pretty"
+	"golang_study/04_oop_in_go/shapes"
+)
 
 type Shape interface {
 	Area() float64
@@ -12,6 +21,14 @@ type Circle struct {
 	Radius float64
 }
 
+// 尺寸校验交给 shapes 包的构造函数，负数、0、NaN 都会返回 ErrInvalidGeometry
+func NewCircle(radius float64) (Circle, error) {
+	if _, err := shapes.NewCircle(radius); err != nil {
+		return Circle{}, err
+	}
+	return Circle{Radius: radius}, nil
+}
+
 func (c Circle) Area() float64 {
 	return 3.14 * c.Radius * c.Radius
 }
@@ -29,6 +46,13 @@ type Rectangle struct {
 	Height float64
 }
 
+func NewRectangle(width, height float64) (Rectangle, error) {
+	if _, err := shapes.NewRectangle(width, height); err != nil {
+		return Rectangle{}, err
+	}
+	return Rectangle{Width: width, Height: height}, nil
+}
+
 func (r Rectangle) Area() float64 {
 	return r.Width * r.Height
 }
@@ -47,9 +71,17 @@ type Triangle struct {
 	SideC float64
 }
 
+// NewTriangle 额外检查三角形不等式
+func NewTriangle(a, b, c float64) (Triangle, error) {
+	if _, err := shapes.NewTriangle(a, b, c); err != nil {
+		return Triangle{}, err
+	}
+	return Triangle{SideA: a, SideB: b, SideC: c}, nil
+}
+
+// 面积计算交给 shapes 包中数值稳定的实现（针状三角形也不会失真）
 func (t Triangle) Area() float64 {
-	s := (t.SideA + t.SideB + t.SideC) / 2
-	return sqrt(s * (s - t.SideA) * (s - t.SideB) * (s - t.SideC))
+	return shapes.Triangle{SideA: t.SideA, SideB: t.SideB, SideC: t.SideC}.Area()
 }
 
 func (t Triangle) Perimeter() float64 {
@@ -60,18 +92,9 @@ func (t Triangle) GetName() string {
 	return "三角形"
 }
 
-func sqrt(value float64) float64 {
-	z := value
-	for i := 0; i < 10; i++ {
-		z -= (z*z - value) / (2 * z)
-	}
-	return z
-}
-
-type ColoredShape struct {
-	Shape
-	Color string
-}
+// 带颜色的图形直接复用 shapes 包的定义（结构同样是嵌入 Shape + Color），
+// 这样 SVG 渲染器可以识别颜色
+type ColoredShape = shapes.ColoredShape
 
 func PrintShapeInfo(s Shape) {
 	fmt.Printf("形状: %s\n", s.GetName())
@@ -101,18 +124,17 @@ func TotalArea(shapes ...Shape) float64 {
 	return total
 }
 
-// 空接口any实现描述任意类型的值
+// 空接口any实现描述任意类型的值：图形用自定义格式，其他值交给反射打印器
+var describer = pretty.SingleLine()
+
+func init() {
+	pretty.Register(describer, func(s Shape) string {
+		return fmt.Sprintf("图形: %s (面积: %.2f)", s.GetName(), s.Area())
+	})
+}
+
 func Describe(value any) {
-	switch v := value.(type) {
-	case int:
-		fmt.Printf("整数: %d\n", v)
-	case string:
-		fmt.Printf("字符串: %s\n", v)
-	case Shape:
-		fmt.Printf("图形: %s (面积: %.2f)\n", v.GetName(), v.Area())
-	default:
-		fmt.Printf("未知类型: %T, 值: %v\n", v, v)
-	}
+	fmt.Printf("%T: %s\n", value, describer.Sprint(value))
 }
 
 // ===== 图形信息 =====
@@ -138,15 +160,27 @@ func Describe(value any) {
 // 这是一个圆形，半径: 5.00
 
 // ===== 空接口演示 =====
-// 整数: 42
-// 字符串: Hello Go
-// 图形: 圆形 (面积: 78.54)
+// int: 42
+// string: "Hello Go"
+// main.Circle: 图形: 圆形 (面积: 78.54)
 
 func main() {
-	// 创建图形实例
-	circle := Circle{Radius: 5}
-	rectangle := Rectangle{Width: 4, Height: 5}
-	triangle := Triangle{SideA: 3, SideB: 4, SideC: 5}
+	// 创建图形实例（通过带校验的构造函数）
+	circle, err := NewCircle(5)
+	if err != nil {
+		fmt.Println("创建圆形失败:", err)
+		return
+	}
+	rectangle, err := NewRectangle(4, 5)
+	if err != nil {
+		fmt.Println("创建矩形失败:", err)
+		return
+	}
+	triangle, err := NewTriangle(3, 4, 5)
+	if err != nil {
+		fmt.Println("创建三角形失败:", err)
+		return
+	}
 
 	// 打印图形信息
 	fmt.Println("===== 图形信息 =====")
@@ -187,4 +221,93 @@ func main() {
 	Describe(42)
 	Describe("Hello Go")
 	Describe(circle)
+
+	// 注册表示例：注册自定义类型，再从文件解析图形
+	fmt.Println("\n===== 从文件解析图形 =====")
+	// 练习里的 Rectangle 方法集与 shapes.Shape 一致，可以直接作为插件注册
+	shapes.Register("square", func(p shapes.Params) (shapes.Shape, error) {
+		side, err := p.Float("s", "side")
+		if err != nil {
+			return nil, err
+		}
+		return NewRectangle(side, side)
+	})
+
+	parsed, err := shapes.ParseFile("shapes.txt")
+	if err != nil {
+		fmt.Println("解析失败:", err)
+		return
+	}
+	fileShapes := make([]Shape, 0, len(parsed))
+	for _, s := range parsed {
+		fileShapes = append(fileShapes, s)
+		fmt.Printf("%s: 面积 %.2f, 周长 %.2f\n", s.GetName(), s.Area(), s.Perimeter())
+	}
+	fmt.Printf("文件中图形总面积: %.2f\n", TotalArea(fileShapes...))
+
+	fromJSON, err := shapes.ParseJSON([]byte(`{"type":"rectangle","width":4,"height":5}`))
+	if err != nil {
+		fmt.Println("解析失败:", err)
+		return
+	}
+	fmt.Printf("JSON 解析: %s, 面积 %.2f\n", fromJSON[0].GetName(), fromJSON[0].Area())
+
+	// 渲染成 SVG（带颜色的图形会使用自己的填充色）
+	fmt.Println("\n===== SVG 渲染 =====")
+	drawList := []shapes.Shape{
+		ColoredShape{Shape: parsed[0], Color: "红色"},
+		parsed[1],
+		ColoredShape{Shape: parsed[2], Color: "蓝色"},
+	}
+	drawList = append(drawList, parsed[3:]...)
+	// 输出到临时目录，不改动仓库里的文件（渲染结果由 shapes 包的 golden 测试把关）
+	svgPath := filepath.Join(os.TempDir(), "shapes.svg")
+	svgFile, err := os.Create(svgPath)
+	if err != nil {
+		fmt.Println("创建文件失败:", err)
+		return
+	}
+	defer svgFile.Close()
+	if err := shapes.NewSVGRenderer().Render(svgFile, drawList); err != nil {
+		fmt.Println("渲染失败:", err)
+	} else {
+		fmt.Printf("已输出 %d 个图形到 %s\n", len(drawList), svgPath)
+	}
+
+	// 变换与碰撞：Placed 仍然是 Shape，可以继续使用 CompareAreas、TotalArea
+	fmt.Println("\n===== 变换与碰撞检测 =====")
+	placedCircle := shapes.Place(parsed[0])
+	placedRect := shapes.Place(parsed[1]).Translate(6, 0).Rotate(math.Pi / 4)
+	bigCircle := shapes.Place(parsed[0]).Translate(20, 0).Scale(2)
+	fmt.Println(CompareAreas(placedCircle, bigCircle))
+	fmt.Printf("变换后总面积: %.2f\n", TotalArea(placedCircle, placedRect, bigCircle))
+	fmt.Printf("圆与旋转后的矩形是否相交: %t\n", shapes.Intersects(placedCircle, placedRect))
+	fmt.Printf("点 (20, 9) 是否在放大的圆内: %t\n", bigCircle.Contains(shapes.Point{X: 20, Y: 9}))
+
+	index := shapes.NewGrid(5)
+	for _, p := range []shapes.Placed{placedCircle, placedRect, bigCircle} {
+		if _, err := index.Insert(p); err != nil {
+			fmt.Println("登记失败:", err)
+		}
+	}
+	area := shapes.AABB{Min: shapes.Point{X: 4, Y: -1}, Max: shapes.Point{X: 12, Y: 1}}
+	for _, id := range index.Query(area) {
+		p := index.Get(id)
+		fmt.Printf("与区域重叠: %s, 包围盒 %v\n", p.GetName(), p.Bounds())
+	}
+
+	// 带校验的构造函数：非法尺寸直接返回错误
+	fmt.Println("\n===== 几何校验 =====")
+	if _, err := shapes.NewTriangle(1, 2, 5); err != nil {
+		fmt.Println("错误:", err)
+	}
+	if _, err := NewCircle(-3); err != nil {
+		fmt.Println("错误:", err)
+	}
+	if _, err := NewRectangle(4, -5); err != nil {
+		fmt.Println("错误:", err)
+	}
+	if _, err := shapes.Parse("rectangle width=-4 height=5"); errors.Is(err, shapes.ErrInvalidGeometry) {
+		fmt.Println("错误:", err)
+	}
 }
@@ -0,0 +1,309 @@
+package hr
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========== 员工目录 ==========
+
+// Directory 保存所有员工（包括已离职的），并发安全。
+// 对外返回的都是 Employee 的副本，修改必须通过 Directory 的方法。
+type Directory struct {
+	mu        sync.RWMutex
+	employees map[string]*Employee
+}
+
+func NewDirectory() *Directory {
+	return &Directory{employees: make(map[string]*Employee)}
+}
+
+// Hire 办理入职：校验编号唯一、年龄不低于 MinAge、上级在职，入职当天进入试用期
+func (d *Directory) Hire(e Employee, date time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, exists := d.employees[e.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicate, e.ID)
+	}
+	if age := e.AgeOn(date); age < MinAge {
+		return fmt.Errorf("%w: %s 入职时 %d 岁（最低 %d 岁）", ErrUnderage, e.Name, age, MinAge)
+	}
+	if e.ManagerID != "" {
+		if err := d.checkManager(e.ID, e.ManagerID, date); err != nil {
+			return err
+		}
+	}
+
+	e.HireDate = date
+	e.History = []StatusChange{{To: Probation, Effective: date, Reason: "入职"}}
+	d.employees[e.ID] = &e
+	return nil
+}
+
+// checkManager 校验上级存在、在职，且不会形成汇报环；调用方需持有锁
+func (d *Directory) checkManager(id, managerID string, date time.Time) error {
+	manager, ok := d.employees[managerID]
+	if !ok {
+		return fmt.Errorf("上级 %w: %s", ErrNotFound, managerID)
+	}
+	if !manager.ActiveOn(date) {
+		return fmt.Errorf("上级 %v %w", manager, ErrInactive)
+	}
+	for cur := managerID; cur != ""; cur = d.employees[cur].ManagerID {
+		if cur == id {
+			return fmt.Errorf("%w: %s -> %s", ErrManagerCycle, id, managerID)
+		}
+	}
+	return nil
+}
+
+// Confirm 转正
+func (d *Directory) Confirm(id string, date time.Time) error {
+	return d.transition(id, Regular, date, "转正")
+}
+
+// Resign 离职；仍有在职直属下属时必须先调整汇报关系
+func (d *Directory) Resign(id string, date time.Time, reason string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, other := range d.employees {
+		if other.ManagerID == id && other.ActiveOn(date) {
+			return fmt.Errorf("%s %w，请先调整汇报关系", id, ErrHasReports)
+		}
+	}
+	return d.transitionLocked(id, Resigned, date, reason)
+}
+
+func (d *Directory) transition(id string, to Status, date time.Time, reason string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.transitionLocked(id, to, date, reason)
+}
+
+// transitionLocked 记录一次状态变更；调用方需持有锁
+func (d *Directory) transitionLocked(id string, to Status, date time.Time, reason string) error {
+	e, ok := d.employees[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	last := e.History[len(e.History)-1]
+	if !canTransition(last.To, to) {
+		return fmt.Errorf("%w: %v 从 %v 到 %v", ErrInvalidTransition, e, last.To, to)
+	}
+	if date.Before(last.Effective) {
+		return fmt.Errorf("%w: %v 上次变更于 %s", ErrBackdated, e, last.Effective.Format(time.DateOnly))
+	}
+	e.History = append(e.History, StatusChange{From: last.To, To: to, Effective: date, Reason: reason})
+	return nil
+}
+
+// Transfer 调岗：修改部门和上级（managerID 为空表示没有上级）
+func (d *Directory) Transfer(id, department, managerID string, date time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.employees[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if !e.ActiveOn(date) {
+		return fmt.Errorf("%v %w", e, ErrInactive)
+	}
+	if managerID != "" {
+		if err := d.checkManager(id, managerID, date); err != nil {
+			return err
+		}
+	}
+	e.Department = department
+	e.ManagerID = managerID
+	return nil
+}
+
+// SetSalary 调整月薪
+func (d *Directory) SetSalary(id string, salary float64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.employees[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	e.Salary = salary
+	return nil
+}
+
+// ========== 查询 ==========
+
+// Get 返回员工副本
+func (d *Directory) Get(id string) (Employee, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	e, ok := d.employees[id]
+	if !ok {
+		return Employee{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return e.clone(), nil
+}
+
+// All 返回所有员工（按编号排序）
+func (d *Directory) All() []Employee {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.filter(func(*Employee) bool { return true })
+}
+
+// ActiveOn 返回在指定日期在职的员工（按编号排序）
+func (d *Directory) ActiveOn(date time.Time) []Employee {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.filter(func(e *Employee) bool { return e.ActiveOn(date) })
+}
+
+// Reports 返回在指定日期在职的直属下属
+func (d *Directory) Reports(managerID string, date time.Time) []Employee {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.filter(func(e *Employee) bool { return e.ManagerID == managerID && e.ActiveOn(date) })
+}
+
+// Chain 返回从直属上级到最高层的汇报链
+func (d *Directory) Chain(id string) ([]Employee, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	e, ok := d.employees[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	var chain []Employee
+	for cur := e.ManagerID; cur != ""; cur = d.employees[cur].ManagerID {
+		chain = append(chain, d.employees[cur].clone())
+	}
+	return chain, nil
+}
+
+// filter 返回满足条件的员工副本；调用方需持有读锁
+func (d *Directory) filter(keep func(*Employee) bool) []Employee {
+	var list []Employee
+	for _, e := range d.employees {
+		if keep(e) {
+			list = append(list, e.clone())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// DepartmentStats 是一个部门在某一天的统计
+type DepartmentStats struct {
+	Department string
+	Headcount  int
+	Probation  int     // 其中试用期人数
+	Salary     float64 // 在职员工月薪合计
+}
+
+// Stats 按部门统计在指定日期在职的人数和薪资合计，按部门名排序
+func (d *Directory) Stats(date time.Time) []DepartmentStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	byDept := make(map[string]*DepartmentStats)
+	for _, e := range d.employees {
+		status, ok := e.StatusOn(date)
+		if !ok || !status.Active() {
+			continue
+		}
+		s, exists := byDept[e.Department]
+		if !exists {
+			s = &DepartmentStats{Department: e.Department}
+			byDept[e.Department] = s
+		}
+		s.Headcount++
+		s.Salary += e.Salary
+		if status == Probation {
+			s.Probation++
+		}
+	}
+
+	stats := make([]DepartmentStats, 0, len(byDept))
+	for _, s := range byDept {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Department < stats[j].Department })
+	return stats
+}
+
+// Headcount 返回各部门在指定日期的在职人数
+func (d *Directory) Headcount(date time.Time) map[string]int {
+	counts := make(map[string]int)
+	for _, s := range d.Stats(date) {
+		counts[s.Department] = s.Headcount
+	}
+	return counts
+}
+
+// SalaryTotals 返回各部门在指定日期的在职员工月薪合计
+func (d *Directory) SalaryTotals(date time.Time) map[string]float64 {
+	totals := make(map[string]float64)
+	for _, s := range d.Stats(date) {
+		totals[s.Department] = s.Salary
+	}
+	return totals
+}
+
+// ========== 组织架构图 ==========
+
+// WriteOrgChart 以树形输出指定日期在职员工的组织架构。
+// 汇报关系使用当前值（调岗不保留历史）；上级不在职的员工作为根节点。
+func (d *Directory) WriteOrgChart(w io.Writer, date time.Time) error {
+	active := d.ActiveOn(date)
+	activeIDs := make(map[string]bool, len(active))
+	for _, e := range active {
+		activeIDs[e.ID] = true
+	}
+	children := make(map[string][]Employee)
+	var roots []Employee
+	for _, e := range active {
+		if e.ManagerID == "" || !activeIDs[e.ManagerID] {
+			roots = append(roots, e)
+		} else {
+			children[e.ManagerID] = append(children[e.ManagerID], e)
+		}
+	}
+
+	var write func(e Employee, prefix string, last, root bool) error
+	write = func(e Employee, prefix string, last, root bool) error {
+		branch, next := "├── ", prefix+"│   "
+		if last {
+			branch, next = "└── ", prefix+"    "
+		}
+		if root {
+			branch, next = "", ""
+		}
+		status, _ := e.StatusOn(date)
+		if _, err := fmt.Fprintf(w, "%s%s%s [%s·%v]\n", prefix, branch, e, e.Department, status); err != nil {
+			return err
+		}
+		kids := children[e.ID]
+		for i, kid := range kids {
+			if err := write(kid, next, i == len(kids)-1, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := write(root, "", true, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OrgChart 返回组织架构图文本
+func (d *Directory) OrgChart(date time.Time) string {
+	var b strings.Builder
+	d.WriteOrgChart(&b, date)
+	return b.String()
+}
@@ -0,0 +1,172 @@
+package hr
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(t testing.TB, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func newEmployee(t testing.TB, id, name, birth, dept, manager string, salary float64) Employee {
+	return Employee{
+		Person:     Person{Name: name, BirthDate: date(t, birth)},
+		ID:         id,
+		Department: dept,
+		Salary:     salary,
+		ManagerID:  manager,
+	}
+}
+
+// newCompany 建立 张三 <- 李四 <- 王五 的汇报线，赵六直属张三，四人都已入职
+func newCompany(t *testing.T) *Directory {
+	t.Helper()
+	dir := NewDirectory()
+	hires := []struct {
+		emp  Employee
+		date string
+	}{
+		{newEmployee(t, "E001", "张三", "1980-03-15", "管理层", "", 50000), "2020-01-01"},
+		{newEmployee(t, "E002", "李四", "1990-07-01", "技术部", "E001", 30000), "2021-03-01"},
+		{newEmployee(t, "E003", "王五", "1995-11-20", "技术部", "E002", 20000), "2023-06-01"},
+		{newEmployee(t, "E004", "赵六", "1998-02-28", "市场部", "E001", 15000), "2024-01-15"},
+	}
+	for _, h := range hires {
+		if err := dir.Hire(h.emp, date(t, h.date)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestHireRules(t *testing.T) {
+	dir := newCompany(t)
+
+	xiaoming := newEmployee(t, "E005", "小明", "2008-09-01", "技术部", "E002", 8000)
+	if err := dir.Hire(xiaoming, date(t, "2024-07-01")); !errors.Is(err, ErrUnderage) {
+		t.Fatalf("15 岁入职 err = %v, want ErrUnderage", err)
+	}
+	// 18 岁生日当天可以入职
+	if err := dir.Hire(xiaoming, date(t, "2026-09-01")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.Hire(xiaoming, date(t, "2026-09-01")); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("重复入职 err = %v, want ErrDuplicate", err)
+	}
+	orphan := newEmployee(t, "E006", "孙七", "1999-05-05", "技术部", "E009", 18000)
+	if err := dir.Hire(orphan, date(t, "2024-07-01")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("上级不存在 err = %v, want ErrNotFound", err)
+	}
+	if _, err := dir.Get("E006"); !errors.Is(err, ErrNotFound) {
+		t.Fatal("入职失败的员工不应出现在目录中")
+	}
+}
+
+func TestStatusTransitions(t *testing.T) {
+	dir := newCompany(t)
+	for _, id := range []string{"E001", "E002", "E003"} {
+		e, _ := dir.Get(id)
+		if err := dir.Confirm(id, e.HireDate.AddDate(0, 3, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := dir.Confirm("E003", date(t, "2023-10-01")); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("重复转正 err = %v, want ErrInvalidTransition", err)
+	}
+	if err := dir.Confirm("E004", date(t, "2023-12-01")); !errors.Is(err, ErrBackdated) {
+		t.Fatalf("倒签转正 err = %v, want ErrBackdated", err)
+	}
+	if err := dir.Resign("E002", date(t, "2025-12-31"), "个人原因"); !errors.Is(err, ErrHasReports) {
+		t.Fatalf("有下属时离职 err = %v, want ErrHasReports", err)
+	}
+	if e, _ := dir.Get("E004"); e.Status() != Probation || len(e.History) != 1 {
+		t.Fatalf("被拒绝的变更修改了记录: %v %v", e.Status(), e.History)
+	}
+
+	if err := dir.Transfer("E003", "技术部", "E001", date(t, "2025-12-01")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.Resign("E002", date(t, "2025-12-31"), "个人原因"); err != nil {
+		t.Fatal(err)
+	}
+
+	li, _ := dir.Get("E002")
+	for d, want := range map[string]Status{
+		"2021-02-28": 0,
+		"2021-05-01": Probation,
+		"2024-01-01": Regular,
+		"2026-01-01": Resigned,
+	} {
+		if got, _ := li.StatusOn(date(t, d)); got != want {
+			t.Errorf("%s 时的状态 %v, want %v", d, got, want)
+		}
+	}
+	if err := dir.Transfer("E002", "技术部", "E001", date(t, "2026-01-01")); !errors.Is(err, ErrInactive) {
+		t.Fatalf("离职员工调岗 err = %v, want ErrInactive", err)
+	}
+}
+
+func TestTransferRejectsCycle(t *testing.T) {
+	dir := newCompany(t)
+	if err := dir.Transfer("E001", "管理层", "E003", date(t, "2025-12-01")); !errors.Is(err, ErrManagerCycle) {
+		t.Fatalf("err = %v, want ErrManagerCycle", err)
+	}
+	chain, err := dir.Chain("E003")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 2 || chain[0].ID != "E002" || chain[1].ID != "E001" {
+		t.Fatalf("汇报链 %v, want [李四(E002) 张三(E001)]", chain)
+	}
+}
+
+func TestReturnedEmployeesAreCopies(t *testing.T) {
+	dir := newCompany(t)
+	e, _ := dir.Get("E002")
+	e.Salary = 0
+	e.History[0].To = Resigned
+	if again, _ := dir.Get("E002"); again.Salary != 30000 || again.Status() != Probation {
+		t.Fatal("修改 Get 返回值影响了目录")
+	}
+}
+
+func TestStatsAndOrgChart(t *testing.T) {
+	dir := newCompany(t)
+	dir.Confirm("E001", date(t, "2020-04-01"))
+	dir.Transfer("E003", "技术部", "E001", date(t, "2025-12-01"))
+	dir.Resign("E002", date(t, "2025-12-31"), "个人原因")
+
+	day := date(t, "2026-10-01")
+	want := []DepartmentStats{
+		{Department: "市场部", Headcount: 1, Probation: 1, Salary: 15000},
+		{Department: "技术部", Headcount: 1, Probation: 1, Salary: 20000},
+		{Department: "管理层", Headcount: 1, Probation: 0, Salary: 50000},
+	}
+	got := dir.Stats(day)
+	if len(got) != len(want) {
+		t.Fatalf("Stats = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Stats[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if n := dir.Headcount(date(t, "2024-12-31"))["技术部"]; n != 2 {
+		t.Errorf("李四离职前技术部人数 %d, want 2", n)
+	}
+
+	const chart = "张三(E001) [管理层·正式员工]\n" +
+		"├── 王五(E003) [技术部·试用期]\n" +
+		"└── 赵六(E004) [市场部·试用期]\n"
+	if got := dir.OrgChart(day); got != chart {
+		t.Errorf("OrgChart:\n%s\nwant:\n%s", got, chart)
+	}
+}
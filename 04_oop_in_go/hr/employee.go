@@ -0,0 +1,141 @@
+// Package hr 把第01节的 EmployeeStatus/MinAge 和第04节的 Person/Employee 统一成一个人事模块：
+// 组织架构（部门和上下级）、带生效日期的状态变更、入职年龄规则，以及按部门统计人数和薪资。
+package hr
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ========== 常量与状态 ==========
+
+// MinAge 是入职的最低年龄（按入职日期计算）
+const MinAge = 18
+
+// Status 与第01节的 EmployeeStatus 取值一致
+type Status int
+
+const (
+	Probation Status = iota + 1 // 1: 试用期
+	Regular                     // 2: 正式员工
+	Resigned                    // 3: 已离职
+)
+
+func (s Status) String() string {
+	switch s {
+	case Probation:
+		return "试用期"
+	case Regular:
+		return "正式员工"
+	case Resigned:
+		return "已离职"
+	default:
+		return "未知状态"
+	}
+}
+
+// Active 报告该状态是否算在职
+func (s Status) Active() bool {
+	return s == Probation || s == Regular
+}
+
+// 允许的状态变更：试用期可以转正或离职，正式员工只能离职
+var transitions = map[Status][]Status{
+	Probation: {Regular, Resigned},
+	Regular:   {Resigned},
+}
+
+func canTransition(from, to Status) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrUnderage          = errors.New("未达到入职年龄")
+	ErrInvalidTransition = errors.New("无效的状态变更")
+	ErrBackdated         = errors.New("生效日期早于上一次变更")
+	ErrNotFound          = errors.New("员工不存在")
+	ErrDuplicate         = errors.New("员工编号已存在")
+	ErrManagerCycle      = errors.New("汇报关系形成环")
+	ErrInactive          = errors.New("员工不在职")
+	ErrHasReports        = errors.New("仍有直属下属")
+)
+
+// ========== 人员 ==========
+
+// Person 与第04节的 Person 对应，年龄由出生日期计算
+type Person struct {
+	Name      string
+	BirthDate time.Time
+}
+
+// AgeOn 返回在指定日期的周岁
+func (p Person) AgeOn(date time.Time) int {
+	age := date.Year() - p.BirthDate.Year()
+	if date.Month() < p.BirthDate.Month() ||
+		date.Month() == p.BirthDate.Month() && date.Day() < p.BirthDate.Day() {
+		age--
+	}
+	return age
+}
+
+// StatusChange 是一次带生效日期的状态变更，入职记录的 From 为 0
+type StatusChange struct {
+	From      Status
+	To        Status
+	Effective time.Time
+	Reason    string
+}
+
+// Employee 嵌入 Person，和第04节一样带有部门和薪资
+type Employee struct {
+	Person
+	ID         string
+	Department string
+	Salary     float64 // 月薪
+	ManagerID  string  // 空表示没有上级
+	HireDate   time.Time
+	History    []StatusChange // 按生效日期排序
+}
+
+// Status 返回最新状态
+func (e Employee) Status() Status {
+	if len(e.History) == 0 {
+		return 0
+	}
+	return e.History[len(e.History)-1].To
+}
+
+// StatusOn 返回在指定日期生效的状态；入职之前返回 false
+func (e Employee) StatusOn(date time.Time) (Status, bool) {
+	var status Status
+	for _, change := range e.History {
+		if change.Effective.After(date) {
+			break
+		}
+		status = change.To
+	}
+	return status, status != 0
+}
+
+// ActiveOn 报告员工在指定日期是否在职
+func (e Employee) ActiveOn(date time.Time) bool {
+	status, ok := e.StatusOn(date)
+	return ok && status.Active()
+}
+
+func (e Employee) String() string {
+	return fmt.Sprintf("%s(%s)", e.Name, e.ID)
+}
+
+// clone 复制历史记录，避免调用方修改目录内部状态
+func (e *Employee) clone() Employee {
+	c := *e
+	c.History = append([]StatusChange(nil), e.History...)
+	return c
+}
@@ -0,0 +1,322 @@
+package memfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// ========== 打开文件 ==========
+
+// OpenFile 与 os.OpenFile 的语义一致，支持的标志：
+// O_RDONLY / O_WRONLY / O_RDWR、O_CREATE、O_EXCL、O_TRUNC、O_APPEND。
+// 同一个文件可以被多次打开，每个句柄有自己的读写位置，写入对其他句柄立即可见。
+func (fsys *FS) OpenFile(name string, flag int, perm fs.FileMode) (*File, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err := fsys.lookup("open", name)
+	created := false
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && flag&os.O_CREATE != 0:
+		parent, base, perr := fsys.parentOf("open", name)
+		if perr != nil {
+			return nil, perr
+		}
+		if parent.mode.Perm()&0o200 == 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+		}
+		now := fsys.now()
+		n = &node{name: base, mode: perm.Perm(), modTime: now}
+		parent.children[base] = n
+		parent.modTime = now
+		created = true
+	case err != nil:
+		return nil, err
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+
+	if n.isDir() {
+		if writable(flag) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+		}
+		if n.mode.Perm()&0o400 == 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+		}
+		return &File{fsys: fsys, node: n, name: name, flag: flag}, nil
+	}
+
+	// 权限检查（只看属主权限位）。与 os.OpenFile 一样，perm 只约束之后的打开，不影响本次创建
+	if created {
+		return &File{fsys: fsys, node: n, name: name, flag: flag}, nil
+	}
+	if readable(flag) && n.mode.Perm()&0o400 == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	if writable(flag) && n.mode.Perm()&0o200 == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	if flag&os.O_TRUNC != 0 && writable(flag) {
+		n.data = n.data[:0]
+		n.modTime = fsys.now()
+	}
+	return &File{fsys: fsys, node: n, name: name, flag: flag}, nil
+}
+
+// Create 创建或清空文件，以读写方式打开
+func (fsys *FS) Create(name string) (*File, error) {
+	return fsys.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+func readable(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR) != os.O_WRONLY
+}
+
+func writable(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+// ========== 文件句柄 ==========
+
+// File 是打开的文件句柄，实现了
+// io.Reader、io.Writer、io.Seeker、io.Closer、io.ReaderAt、io.WriterAt 和 fs.ReadDirFile
+type File struct {
+	fsys *FS
+	node *node
+	name string
+	flag int
+
+	mu     sync.Mutex // 保护句柄自己的状态（读写位置等）
+	offset int64
+	closed bool
+	dirPos int // ReadDir(n) 分批读取时的位置
+}
+
+var (
+	_ io.ReadWriteSeeker = (*File)(nil)
+	_ io.ReaderAt        = (*File)(nil)
+	_ io.WriterAt        = (*File)(nil)
+	_ io.Closer          = (*File)(nil)
+	_ fs.ReadDirFile     = (*File)(nil)
+)
+
+// Name 返回打开时使用的路径
+func (f *File) Name() string {
+	return f.name
+}
+
+func (f *File) check(op string) error {
+	if f.closed {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	}
+	return nil
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.readAt("read", p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// ReadAt 从指定位置读取，不影响句柄的读写位置；读不满 p 时返回 io.EOF
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if off < 0 {
+		return 0, &fs.PathError{Op: "readat", Path: f.name, Err: fs.ErrInvalid}
+	}
+	n, err := f.readAt("readat", p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *File) readAt(op string, p []byte, off int64) (int, error) {
+	if err := f.check(op); err != nil {
+		return 0, err
+	}
+	if f.node.isDir() {
+		return 0, &fs.PathError{Op: op, Path: f.name, Err: errIsDir}
+	}
+	if !readable(f.flag) {
+		return 0, &fs.PathError{Op: op, Path: f.name, Err: fs.ErrPermission}
+	}
+
+	f.fsys.mu.RLock()
+	defer f.fsys.mu.RUnlock()
+	if off >= int64(len(f.node.data)) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	return copy(p, f.node.data[off:]), nil
+}
+
+// Write 在当前位置写入；O_APPEND 模式下总是写到文件末尾
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.writeAt("write", p, f.offset, f.flag&os.O_APPEND != 0)
+	f.offset = n
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteAt 在指定位置写入，不影响句柄的读写位置（O_APPEND 模式下不允许）
+func (f *File) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.flag&os.O_APPEND != 0 {
+		return 0, &fs.PathError{Op: "writeat", Path: f.name, Err: errors.New("追加模式下不能使用 WriteAt")}
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "writeat", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if _, err := f.writeAt("writeat", p, off, false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeAt 返回写入后的结束位置
+func (f *File) writeAt(op string, p []byte, off int64, appendMode bool) (int64, error) {
+	if err := f.check(op); err != nil {
+		return off, err
+	}
+	if f.node.isDir() {
+		return off, &fs.PathError{Op: op, Path: f.name, Err: errIsDir}
+	}
+	if !writable(f.flag) {
+		return off, &fs.PathError{Op: op, Path: f.name, Err: fs.ErrPermission}
+	}
+
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if appendMode {
+		off = int64(len(f.node.data))
+	}
+	end := off + int64(len(p))
+	if end > int64(len(f.node.data)) {
+		// 超出末尾时扩容，中间的空洞补 0
+		grown := make([]byte, end)
+		copy(grown, f.node.data)
+		f.node.data = grown
+	}
+	copy(f.node.data[off:], p)
+	f.node.modTime = f.fsys.now()
+	return end, nil
+}
+
+// Seek 设置下一次读写的位置
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("seek"); err != nil {
+		return 0, err
+	}
+
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		base = f.offset
+	case io.SeekEnd:
+		f.fsys.mu.RLock()
+		base = int64(len(f.node.data))
+		f.fsys.mu.RUnlock()
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if base+offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.offset = base + offset
+	return f.offset, nil
+}
+
+// Truncate 把文件截断（或用 0 扩展）到 size 字节
+func (f *File) Truncate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("truncate"); err != nil {
+		return err
+	}
+	if !writable(f.flag) || f.node.isDir() || size < 0 {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if size <= int64(len(f.node.data)) {
+		f.node.data = f.node.data[:size]
+	} else {
+		grown := make([]byte, size)
+		copy(grown, f.node.data)
+		f.node.data = grown
+	}
+	f.node.modTime = f.fsys.now()
+	return nil
+}
+
+func (f *File) Stat() (fs.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("stat"); err != nil {
+		return nil, err
+	}
+	f.fsys.mu.RLock()
+	defer f.fsys.mu.RUnlock()
+	return f.node.info(), nil
+}
+
+// ReadDir 实现 fs.ReadDirFile：n <= 0 时返回全部剩余项；n > 0 时分批返回，读完返回 io.EOF
+func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("readdir"); err != nil {
+		return nil, err
+	}
+	if !f.node.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errNotDir}
+	}
+
+	f.fsys.mu.RLock()
+	entries := f.node.entries()
+	f.fsys.mu.RUnlock()
+
+	if f.dirPos > len(entries) {
+		f.dirPos = len(entries)
+	}
+	rest := entries[f.dirPos:]
+	if n <= 0 {
+		f.dirPos += len(rest)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	f.dirPos += n
+	return rest[:n], nil
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("close"); err != nil {
+		return err
+	}
+	f.closed = true
+	return nil
+}
@@ -0,0 +1,289 @@
+// Package memfs 是第04节 File 示例的进阶版本：一个内存文件系统。
+// 文件句柄实现标准库的 io.Reader/Writer/Seeker/Closer/ReaderAt，
+// 文件系统本身实现 io/fs.FS，可以交给 fs.WalkDir、fstest.TestFS 等标准工具使用。
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========== 节点 ==========
+
+// node 是文件或目录，所有字段由 FS.mu 保护
+type node struct {
+	name     string
+	mode     fs.FileMode // 目录带有 fs.ModeDir
+	modTime  time.Time
+	data     []byte           // 文件内容
+	children map[string]*node // 目录项
+}
+
+func (n *node) isDir() bool {
+	return n.mode.IsDir()
+}
+
+func (n *node) info() fileInfo {
+	return fileInfo{name: n.name, size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+// ========== 文件系统 ==========
+
+// FS 是并发安全的内存文件系统，路径使用 io/fs 的规则（斜杠分隔、不以 / 开头，根目录为 "."）
+type FS struct {
+	mu   sync.RWMutex
+	root *node
+	now  func() time.Time
+}
+
+var (
+	_ fs.FS         = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+)
+
+func New() *FS {
+	fsys := &FS{now: time.Now}
+	fsys.root = &node{name: ".", mode: fs.ModeDir | 0o755, modTime: fsys.now(), children: make(map[string]*node)}
+	return fsys
+}
+
+// lookup 查找路径对应的节点；调用方需持有锁
+func (fsys *FS) lookup(op, name string) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	n := fsys.root
+	if name == "." {
+		return n, nil
+	}
+	for _, part := range strings.Split(name, "/") {
+		if !n.isDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: errNotDir}
+		}
+		if n.mode.Perm()&0o100 == 0 {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+		}
+		child, ok := n.children[part]
+		if !ok {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		n = child
+	}
+	return n, nil
+}
+
+// parentOf 返回父目录节点和最后一级名称；调用方需持有锁
+func (fsys *FS) parentOf(op, name string) (*node, string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	dir, base := path.Split(name)
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "" {
+		dir = "."
+	}
+	parent, err := fsys.lookup(op, dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if !parent.isDir() {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: errNotDir}
+	}
+	return parent, base, nil
+}
+
+var (
+	errNotDir = errors.New("不是目录")
+	errIsDir  = errors.New("是一个目录")
+	errNotEmp = errors.New("目录不为空")
+)
+
+// ========== 目录操作 ==========
+
+// Mkdir 创建单级目录，父目录必须存在且可写
+func (fsys *FS) Mkdir(name string, perm fs.FileMode) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	return fsys.mkdir(name, perm)
+}
+
+func (fsys *FS) mkdir(name string, perm fs.FileMode) error {
+	parent, base, err := fsys.parentOf("mkdir", name)
+	if err != nil {
+		return err
+	}
+	if _, exists := parent.children[base]; exists {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if parent.mode.Perm()&0o200 == 0 {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrPermission}
+	}
+	now := fsys.now()
+	parent.children[base] = &node{name: base, mode: fs.ModeDir | perm.Perm(), modTime: now, children: make(map[string]*node)}
+	parent.modTime = now
+	return nil
+}
+
+// MkdirAll 逐级创建目录，已存在的目录会被跳过
+func (fsys *FS) MkdirAll(name string, perm fs.FileMode) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil
+	}
+	parts := strings.Split(name, "/")
+	for i := range parts {
+		sub := strings.Join(parts[:i+1], "/")
+		n, err := fsys.lookup("mkdir", sub)
+		if err == nil {
+			if !n.isDir() {
+				return &fs.PathError{Op: "mkdir", Path: sub, Err: errNotDir}
+			}
+			continue
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := fsys.mkdir(sub, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove 删除文件或空目录
+func (fsys *FS) Remove(name string) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	parent, base, err := fsys.parentOf("remove", name)
+	if err != nil {
+		return err
+	}
+	n, ok := parent.children[base]
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if parent.mode.Perm()&0o200 == 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+	}
+	if n.isDir() && len(n.children) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: errNotEmp}
+	}
+	delete(parent.children, base)
+	parent.modTime = fsys.now()
+	return nil
+}
+
+// Chmod 修改权限位（保留文件类型位）
+func (fsys *FS) Chmod(name string, mode fs.FileMode) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	n, err := fsys.lookup("chmod", name)
+	if err != nil {
+		return err
+	}
+	n.mode = n.mode.Type() | mode.Perm()
+	return nil
+}
+
+// ========== io/fs 接口 ==========
+
+// Open 以只读方式打开文件或目录（实现 fs.FS）
+func (fsys *FS) Open(name string) (fs.File, error) {
+	return fsys.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// Stat 实现 fs.StatFS
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	n, err := fsys.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(), nil
+}
+
+// ReadDir 实现 fs.ReadDirFS，结果按文件名排序
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	n, err := fsys.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	if n.mode.Perm()&0o400 == 0 {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+	}
+	return n.entries(), nil
+}
+
+// 目录项快照（已排序）；调用方需持有锁
+func (n *node) entries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, fs.FileInfoToDirEntry(child.info()))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries
+}
+
+// ReadFile 实现 fs.ReadFileFS
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	n, err := fsys.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if n.isDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errIsDir}
+	}
+	if n.mode.Perm()&0o400 == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return append([]byte(nil), n.data...), nil
+}
+
+// WriteFile 创建或覆盖文件
+func (fsys *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ========== 文件信息 ==========
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() any           { return nil }
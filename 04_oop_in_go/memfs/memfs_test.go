@@ -0,0 +1,107 @@
+package memfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSConformsToIOFS(t *testing.T) {
+	fsys := New()
+	if err := fsys.MkdirAll("docs/notes", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile("docs/test.txt", []byte("Hello, Go!\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile("docs/notes/log.txt", []byte("line\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile("empty.txt", nil, 0o444); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(fsys, "docs/test.txt", "docs/notes/log.txt", "empty.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileReadWriteSeek(t *testing.T) {
+	fsys := New()
+	f, err := fsys.Create("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rwc io.ReadWriteSeeker = f
+	io.WriteString(rwc, "Hello, Go!\n")
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != "Hello, Go!\n" {
+		t.Fatalf("ReadAll = %q, %v", data, err)
+	}
+
+	buf := make([]byte, 2)
+	if _, err := f.ReadAt(buf, 7); err != nil || string(buf) != "Go" {
+		t.Fatalf("ReadAt = %q, %v", buf, err)
+	}
+	if pos, _ := f.Seek(0, io.SeekCurrent); pos != 11 {
+		t.Fatalf("ReadAt 改变了读写位置: %d", pos)
+	}
+
+	f.Close()
+	if _, err := f.Read(buf); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("关闭后 Read err = %v, want fs.ErrClosed", err)
+	}
+}
+
+func TestConcurrentAppend(t *testing.T) {
+	fsys := New()
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := fsys.OpenFile("log.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer f.Close()
+			for j := range 50 {
+				fmt.Fprintf(f, "writer%d-%d\n", i, j)
+			}
+		}()
+	}
+	wg.Wait()
+	data, err := fsys.ReadFile("log.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 200 {
+		t.Fatalf("追加写入 %d 行，want 200", n)
+	}
+}
+
+func TestTruncateAndPermissions(t *testing.T) {
+	fsys := New()
+	fsys.WriteFile("a.txt", []byte("a long line"), 0o644)
+	fsys.WriteFile("a.txt", []byte("short"), 0o644)
+	if data, _ := fsys.ReadFile("a.txt"); string(data) != "short" {
+		t.Fatalf("截断后内容 %q", data)
+	}
+
+	fsys.Chmod("a.txt", 0o444)
+	if _, err := fsys.OpenFile("a.txt", os.O_WRONLY, 0); !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("只读文件以写方式打开 err = %v, want fs.ErrPermission", err)
+	}
+	if _, err := fsys.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Open 不存在的文件 err = %v, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,167 @@
+package payment
+
+import (
+	"sync"
+	"time"
+)
+
+// ========== 熔断器 ==========
+
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 正常放行
+	BreakerOpen                         // 熔断中，直接拒绝
+	BreakerHalfOpen                     // 试探中，只放行一个请求
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "关闭"
+	case BreakerOpen:
+		return "熔断"
+	case BreakerHalfOpen:
+		return "半开"
+	default:
+		return "未知"
+	}
+}
+
+// BreakerConfig 控制熔断器何时打开、何时恢复
+type BreakerConfig struct {
+	Window      int              // 统计最近多少次调用
+	MinRequests int              // 至少有这么多次调用才计算成功率
+	MinSuccess  float64          // 成功率低于该值时熔断，例如 0.5
+	OpenTimeout time.Duration    // 熔断多久后进入半开状态
+	Now         func() time.Time // 时间来源，为 nil 时使用 time.Now（测试时可替换）
+}
+
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		Window:      20,
+		MinRequests: 5,
+		MinSuccess:  0.5,
+		OpenTimeout: 30 * time.Second,
+	}
+}
+
+// CircuitBreaker 按最近 Window 次调用的成功率决定是否熔断（类似 Java 的 Resilience4j）
+type CircuitBreaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	outcomes []bool // 环形缓冲区，true 表示成功
+	next     int
+	filled   int
+	openedAt time.Time
+	probing  bool // 半开状态下是否已有试探请求在进行
+}
+
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.Window <= 0 {
+		cfg.Window = 1
+	}
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &CircuitBreaker{cfg: cfg, now: now, outcomes: make([]bool, cfg.Window)}
+}
+
+// Allow 判断是否放行本次调用；放行后必须调用 Record 报告结果，或用 Release 放弃统计
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record 记录一次调用结果
+func (b *CircuitBreaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+		if success {
+			// 试探成功：恢复，并清空历史
+			b.state = BreakerClosed
+			b.next, b.filled = 0, 0
+		} else {
+			b.trip()
+		}
+		return
+	}
+
+	b.outcomes[b.next] = success
+	b.next = (b.next + 1) % len(b.outcomes)
+	if b.filled < len(b.outcomes) {
+		b.filled++
+	}
+	if b.state == BreakerClosed && b.filled >= b.cfg.MinRequests && b.successRate() < b.cfg.MinSuccess {
+		b.trip()
+	}
+}
+
+// Release 放弃一次已放行的调用，不计入统计；半开状态下允许下一个试探请求
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+	}
+}
+
+func (b *CircuitBreaker) trip() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+}
+
+// State 返回当前状态（熔断超时后会显示为半开）
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// SuccessRate 返回统计窗口内的成功率，没有数据时为 1
+func (b *CircuitBreaker) SuccessRate() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.successRate()
+}
+
+func (b *CircuitBreaker) successRate() float64 {
+	if b.filled == 0 {
+		return 1
+	}
+	ok := 0
+	for i := 0; i < b.filled; i++ {
+		if b.outcomes[i] {
+			ok++
+		}
+	}
+	return float64(ok) / float64(b.filled)
+}
@@ -0,0 +1,119 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ========== HTTP 网关客户端 ==========
+
+// HTTPGateway 通过 HTTP 调用支付渠道（例如 MockProvider），实现 Gateway 接口
+type HTTPGateway struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewHTTPGateway(baseURL string) *HTTPGateway {
+	return &HTTPGateway{
+		BaseURL: baseURL,
+		Client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (g *HTTPGateway) Authorize(ctx context.Context, req AuthorizeRequest) (*Transaction, error) {
+	return g.do(ctx, http.MethodPost, "/v1/authorize", req.IdempotencyKey, req)
+}
+
+func (g *HTTPGateway) Capture(ctx context.Context, txnID string, amount float64, idempotencyKey string) (*Transaction, error) {
+	return g.do(ctx, http.MethodPost, "/v1/transactions/"+url.PathEscape(txnID)+"/capture", idempotencyKey,
+		map[string]float64{"amount": amount})
+}
+
+func (g *HTTPGateway) Refund(ctx context.Context, txnID string, amount float64, idempotencyKey string) (*Transaction, error) {
+	return g.do(ctx, http.MethodPost, "/v1/transactions/"+url.PathEscape(txnID)+"/refund", idempotencyKey,
+		map[string]float64{"amount": amount})
+}
+
+func (g *HTTPGateway) Void(ctx context.Context, txnID string, idempotencyKey string) (*Transaction, error) {
+	return g.do(ctx, http.MethodPost, "/v1/transactions/"+url.PathEscape(txnID)+"/void", idempotencyKey,
+		struct{}{})
+}
+
+func (g *HTTPGateway) Get(ctx context.Context, txnID string) (*Transaction, error) {
+	return g.do(ctx, http.MethodGet, "/v1/transactions/"+url.PathEscape(txnID), "", nil)
+}
+
+func (g *HTTPGateway) do(ctx context.Context, method, path, key string, payload any) (*Transaction, error) {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		// 网络错误：请求可能已经被处理，也可能没有，只能带同一个幂等键重试
+		return nil, &Error{Code: "network", Message: err.Error(), Retryable: true, Err: ErrUnavailable}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &Error{Code: "network", Message: err.Error(), Retryable: true, Err: ErrUnavailable}
+	}
+	if resp.StatusCode >= 300 {
+		return nil, decodeError(resp.StatusCode, data)
+	}
+
+	var txn Transaction
+	if err := json.Unmarshal(data, &txn); err != nil {
+		return nil, fmt.Errorf("解析渠道响应失败: %w", err)
+	}
+	return &txn, nil
+}
+
+// 把 HTTP 错误响应转换成 *Error
+func decodeError(status int, data []byte) error {
+	var eb errorBody
+	json.Unmarshal(data, &eb)
+	if eb.Message == "" {
+		eb.Message = http.StatusText(status)
+	}
+
+	e := &Error{Code: eb.Code, Message: eb.Message}
+	switch {
+	case status == http.StatusPaymentRequired:
+		e.Err = ErrDeclined
+	case status == http.StatusNotFound:
+		e.Err = ErrNotFound
+	case status == http.StatusConflict:
+		e.Err = ErrInvalidState
+	case status == http.StatusUnprocessableEntity:
+		e.Err = ErrConflict
+	case status == http.StatusTooManyRequests || status >= 500:
+		e.Err = ErrUnavailable
+		e.Retryable = true
+	}
+	if e.Code == "" {
+		e.Code = fmt.Sprintf("http_%d", status)
+	}
+	return e
+}
@@ -0,0 +1,108 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// ========== 幂等键 ==========
+
+// NewIdempotencyKey 生成一个随机幂等键。
+// 同一笔业务操作的所有重试必须复用同一个键。
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand 失败说明系统环境有问题
+	}
+	return hex.EncodeToString(b)
+}
+
+// IdempotencyStore 记录每个幂等键第一次请求的指纹和响应，
+// 渠道端（MockProvider）用它来保证重复请求只生效一次。
+//
+// 键在处理前就被原子地占住（Begin），同一个键的并发请求会等第一个请求处理完，
+// 然后直接拿到它的响应，而不是各自再处理一遍。
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*storedResponse
+}
+
+type storedResponse struct {
+	fingerprint string
+	done        chan struct{} // 处理完成（Finish 或 Abort）时关闭
+	finished    bool
+	status      int
+	body        []byte
+}
+
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{entries: make(map[string]*storedResponse)}
+}
+
+// Begin 占用幂等键。
+// first 为 true 表示调用方是第一个处理这个键的请求，处理完必须调用 Finish 或 Abort；
+// 否则 status 和 body 是第一次请求记录下的响应。
+// 同一个键正在处理时 Begin 会等待它结束；键对应的请求内容不同时返回 ErrConflict。
+func (s *IdempotencyStore) Begin(ctx context.Context, key string, request []byte) (status int, body []byte, first bool, err error) {
+	fp := fingerprint(request)
+	for {
+		s.mu.Lock()
+		entry, ok := s.entries[key]
+		if !ok {
+			s.entries[key] = &storedResponse{fingerprint: fp, done: make(chan struct{})}
+			s.mu.Unlock()
+			return 0, nil, true, nil
+		}
+		if entry.fingerprint != fp {
+			s.mu.Unlock()
+			return 0, nil, false, ErrConflict
+		}
+		if entry.finished {
+			s.mu.Unlock()
+			return entry.status, entry.body, false, nil
+		}
+		wait := entry.done
+		s.mu.Unlock()
+
+		select {
+		case <-wait:
+			// 第一个请求放弃了（Abort）时条目已删除，下一轮由本请求接手
+		case <-ctx.Done():
+			return 0, nil, false, ctx.Err()
+		}
+	}
+}
+
+// Finish 记录键对应的响应，并唤醒等待这个键的请求
+func (s *IdempotencyStore) Finish(key string, status int, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || entry.finished {
+		return
+	}
+	entry.status = status
+	entry.body = append([]byte(nil), body...)
+	entry.finished = true
+	close(entry.done)
+}
+
+// Abort 放弃占用的键（请求没有生效，例如渠道暂时不可用），之后的重试会重新处理
+func (s *IdempotencyStore) Abort(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || entry.finished {
+		return
+	}
+	delete(s.entries, key)
+	close(entry.done)
+}
+
+func fingerprint(request []byte) string {
+	sum := sha256.Sum256(request)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,350 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// ========== 本地模拟支付渠道 ==========
+
+// MockProvider 是一个运行在本机的 HTTP 支付渠道，用来代替真实的支付宝/微信接口做联调。
+// 它支持幂等键、异步授权回调，并可以注入故障来演练重试与对账：
+//
+//	POST /v1/authorize                      预授权
+//	POST /v1/transactions/{id}/capture      扣款
+//	POST /v1/transactions/{id}/refund       退款
+//	POST /v1/transactions/{id}/void         撤销预授权
+//	GET  /v1/transactions/{id}              查询交易
+//
+// 导出字段需要在 Start 之前设置。
+type MockProvider struct {
+	Secret      string        // 回调签名密钥
+	CallbackURL string        // 异步结果回调地址，为空则不回调
+	AsyncDelay  time.Duration // 大于 0 时预授权先返回 pending，延迟后再给出结果
+	Limit       float64       // 单笔限额，超过即拒绝；0 表示不限
+
+	mu       sync.Mutex
+	txns     map[string]*Transaction
+	seq      int
+	failNext int // 接下来 N 个写请求在处理前直接返回 503
+	loseNext int // 接下来 N 个写请求处理成功，但响应"丢失"，返回 503
+
+	idem      *IdempotencyStore
+	mux       *http.ServeMux
+	server    *httptest.Server
+	callbacks sync.WaitGroup
+}
+
+func NewMockProvider(secret string) *MockProvider {
+	m := &MockProvider{
+		Secret: secret,
+		txns:   make(map[string]*Transaction),
+		idem:   NewIdempotencyStore(),
+		mux:    http.NewServeMux(),
+	}
+	m.mux.HandleFunc("POST /v1/authorize", m.idempotent(m.handleAuthorize))
+	m.mux.HandleFunc("POST /v1/transactions/{id}/capture", m.idempotent(m.handleCapture))
+	m.mux.HandleFunc("POST /v1/transactions/{id}/refund", m.idempotent(m.handleRefund))
+	m.mux.HandleFunc("POST /v1/transactions/{id}/void", m.idempotent(m.handleVoid))
+	m.mux.HandleFunc("GET /v1/transactions/{id}", m.handleGet)
+	return m
+}
+
+// Start 启动本地 HTTP 服务，返回它的地址
+func (m *MockProvider) Start() string {
+	m.server = httptest.NewServer(m)
+	return m.server.URL
+}
+
+// Close 关闭服务，并等待尚未发出的回调完成
+func (m *MockProvider) Close() {
+	m.callbacks.Wait()
+	if m.server != nil {
+		m.server.Close()
+	}
+}
+
+// FailNext 让接下来 n 个写请求直接失败（请求没有被处理）
+func (m *MockProvider) FailNext(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failNext = n
+}
+
+// LoseResponseNext 让接下来 n 个写请求"处理成功但客户端没收到响应"，
+// 这正是幂等键要解决的场景
+func (m *MockProvider) LoseResponseNext(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loseNext = n
+}
+
+// Transaction 直接读取渠道内部的交易（用于核对）
+func (m *MockProvider) Transaction(id string) (Transaction, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	txn, ok := m.txns[id]
+	if !ok {
+		return Transaction{}, false
+	}
+	return *txn, true
+}
+
+// ========== HTTP 处理 ==========
+
+func (m *MockProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// 处理函数返回 HTTP 状态码和响应体，方便幂等中间件记录
+type handlerFunc func(r *http.Request, body []byte) (int, any)
+
+// idempotent 为写请求加上幂等键检查和故障注入。
+// 键在调用 h 之前就被占住，同一个键的并发请求只有一个会真正执行 h。
+func (m *MockProvider) idempotent(h handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			writeJSON(w, http.StatusBadRequest, errorBody{"bad_request", "缺少 Idempotency-Key 请求头"})
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorBody{"bad_request", err.Error()})
+			return
+		}
+		// 指纹包含路径，防止同一个键被挪用到别的操作上
+		request := append([]byte(r.Method+" "+r.URL.Path+"\n"), body...)
+
+		status, stored, first, err := m.idem.Begin(r.Context(), key, request)
+		switch {
+		case errors.Is(err, ErrConflict):
+			writeJSON(w, http.StatusUnprocessableEntity, errorBody{"idempotency_conflict", "幂等键已被用于不同的请求"})
+			return
+		case err != nil:
+			writeJSON(w, http.StatusServiceUnavailable, errorBody{"unavailable", err.Error()})
+			return
+		case !first:
+			w.Header().Set("Idempotent-Replayed", "true")
+			writeRaw(w, status, stored)
+			return
+		}
+
+		m.mu.Lock()
+		fail := m.failNext > 0
+		if fail {
+			m.failNext--
+		}
+		m.mu.Unlock()
+		if fail {
+			m.idem.Abort(key)
+			writeJSON(w, http.StatusServiceUnavailable, errorBody{"unavailable", "渠道繁忙，请稍后重试"})
+			return
+		}
+
+		status, resp := h(r, body)
+		encoded, _ := json.Marshal(resp)
+		if status < 500 {
+			m.idem.Finish(key, status, encoded)
+		} else {
+			m.idem.Abort(key)
+		}
+
+		m.mu.Lock()
+		lose := m.loseNext > 0
+		if lose {
+			m.loseNext--
+		}
+		m.mu.Unlock()
+		if lose {
+			writeJSON(w, http.StatusServiceUnavailable, errorBody{"unavailable", "网关超时"})
+			return
+		}
+		writeRaw(w, status, encoded)
+	}
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (m *MockProvider) handleAuthorize(r *http.Request, body []byte) (int, any) {
+	var req AuthorizeRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return http.StatusBadRequest, errorBody{"bad_request", "请求体不是合法 JSON"}
+	}
+	if toCents(req.Amount) <= 0 {
+		return http.StatusBadRequest, errorBody{"bad_request", "金额必须大于 0"}
+	}
+	declined := m.Limit > 0 && toCents(req.Amount) > toCents(m.Limit)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	txn := &Transaction{
+		ID:        fmt.Sprintf("txn_%04d", m.seq),
+		Method:    req.Method,
+		Account:   req.Account,
+		Amount:    fromCents(toCents(req.Amount)),
+		UpdatedAt: time.Now(),
+	}
+	m.txns[txn.ID] = txn
+
+	if m.AsyncDelay > 0 {
+		// 异步模式：先受理，稍后通过回调告知结果
+		txn.Status = StatusPending
+		m.callbacks.Add(1)
+		go m.completeLater(txn.ID, declined)
+		return http.StatusAccepted, *txn
+	}
+	if declined {
+		txn.Status = StatusFailed
+		txn.Reason = fmt.Sprintf("超过单笔限额 %.2f", m.Limit)
+		return http.StatusPaymentRequired, errorBody{"declined", txn.Reason}
+	}
+	txn.Status = StatusAuthorized
+	return http.StatusOK, *txn
+}
+
+func (m *MockProvider) handleCapture(r *http.Request, body []byte) (int, any) {
+	var req struct {
+		Amount float64 `json:"amount"`
+	}
+	json.Unmarshal(body, &req)
+	return m.update(r.PathValue("id"), func(txn *Transaction) (int, any) {
+		if txn.Status != StatusAuthorized {
+			return http.StatusConflict, errorBody{"invalid_state", fmt.Sprintf("交易状态为 %s，无法扣款", txn.Status)}
+		}
+		amount := toCents(req.Amount)
+		if amount == 0 {
+			amount = toCents(txn.Amount)
+		}
+		if amount < 0 || amount > toCents(txn.Amount) {
+			return http.StatusBadRequest, errorBody{"bad_request", "扣款金额超出授权金额"}
+		}
+		txn.Status = StatusCaptured
+		txn.Captured = fromCents(amount)
+		return http.StatusOK, *txn
+	})
+}
+
+func (m *MockProvider) handleRefund(r *http.Request, body []byte) (int, any) {
+	var req struct {
+		Amount float64 `json:"amount"`
+	}
+	json.Unmarshal(body, &req)
+	return m.update(r.PathValue("id"), func(txn *Transaction) (int, any) {
+		if txn.Status != StatusCaptured {
+			return http.StatusConflict, errorBody{"invalid_state", fmt.Sprintf("交易状态为 %s，无法退款", txn.Status)}
+		}
+		remaining := toCents(txn.Captured) - toCents(txn.Refunded)
+		amount := toCents(req.Amount)
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount <= 0 || amount > remaining {
+			return http.StatusBadRequest, errorBody{"bad_request", "退款金额超出可退金额"}
+		}
+		txn.Refunded = fromCents(toCents(txn.Refunded) + amount)
+		if toCents(txn.Refunded) == toCents(txn.Captured) {
+			txn.Status = StatusRefunded
+		}
+		return http.StatusOK, *txn
+	})
+}
+
+func (m *MockProvider) handleVoid(r *http.Request, body []byte) (int, any) {
+	return m.update(r.PathValue("id"), func(txn *Transaction) (int, any) {
+		if txn.Status != StatusAuthorized {
+			return http.StatusConflict, errorBody{"invalid_state", fmt.Sprintf("交易状态为 %s，无法撤销", txn.Status)}
+		}
+		txn.Status = StatusVoided
+		return http.StatusOK, *txn
+	})
+}
+
+func (m *MockProvider) handleGet(w http.ResponseWriter, r *http.Request) {
+	txn, ok := m.Transaction(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorBody{"not_found", "交易不存在"})
+		return
+	}
+	writeJSON(w, http.StatusOK, txn)
+}
+
+// 在锁内修改交易
+func (m *MockProvider) update(id string, fn func(txn *Transaction) (int, any)) (int, any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	txn, ok := m.txns[id]
+	if !ok {
+		return http.StatusNotFound, errorBody{"not_found", "交易不存在"}
+	}
+	status, resp := fn(txn)
+	if status == http.StatusOK {
+		txn.UpdatedAt = time.Now()
+	}
+	return status, resp
+}
+
+// ========== 异步回调 ==========
+
+func (m *MockProvider) completeLater(id string, declined bool) {
+	defer m.callbacks.Done()
+	time.Sleep(m.AsyncDelay)
+
+	m.mu.Lock()
+	txn := m.txns[id]
+	if declined {
+		txn.Status = StatusFailed
+		txn.Reason = fmt.Sprintf("超过单笔限额 %.2f", m.Limit)
+	} else {
+		txn.Status = StatusAuthorized
+	}
+	txn.UpdatedAt = time.Now()
+	event := Event{TransactionID: txn.ID, Status: txn.Status, Reason: txn.Reason, Timestamp: txn.UpdatedAt}
+	m.mu.Unlock()
+
+	if m.CallbackURL == "" {
+		return
+	}
+	body, _ := json.Marshal(event)
+	// 回调失败时最多重试 3 次（真实渠道通常会重试更久）
+	for attempt := 0; attempt < 3; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, m.CallbackURL, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SignatureHeader, Sign(m.Secret, time.Now(), body))
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// ========== 工具函数 ==========
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, _ := json.Marshal(v)
+	writeRaw(w, status, body)
+}
+
+func writeRaw(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,115 @@
+// Package payment 是第04节支付示例的进阶版本：
+// 把 PaymentMethod.Pay 拆成"预授权 / 扣款 / 退款 / 撤销"四个动作，
+// 并提供幂等键、异步回调签名校验、本地 HTTP 模拟支付渠道和带重试的编排器。
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ========== 交易状态 ==========
+
+type Status string
+
+const (
+	StatusPending    Status = "pending"    // 已受理，等待渠道异步结果
+	StatusAuthorized Status = "authorized" // 已预授权（冻结资金）
+	StatusCaptured   Status = "captured"   // 已扣款
+	StatusRefunded   Status = "refunded"   // 已全额退款
+	StatusVoided     Status = "voided"     // 预授权已撤销
+	StatusFailed     Status = "failed"     // 失败（被拒绝等）
+)
+
+// IsFinal 判断是否为终态（不会再变化）
+func (s Status) IsFinal() bool {
+	switch s {
+	case StatusCaptured, StatusRefunded, StatusVoided, StatusFailed:
+		return true
+	}
+	return false
+}
+
+// Transaction 是渠道返回的交易快照
+type Transaction struct {
+	ID        string    `json:"id"`
+	Method    string    `json:"method"`
+	Account   string    `json:"account"`
+	Status    Status    `json:"status"`
+	Amount    float64   `json:"amount"`   // 授权金额（元）
+	Captured  float64   `json:"captured"` // 已扣款金额
+	Refunded  float64   `json:"refunded"` // 已退款金额
+	Reason    string    `json:"reason,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AuthorizeRequest 是预授权请求
+type AuthorizeRequest struct {
+	Method         string  `json:"method"` // 支付方式，例如 "支付宝"
+	Account        string  `json:"account"`
+	Amount         float64 `json:"amount"`
+	IdempotencyKey string  `json:"-"`
+}
+
+// ========== 网关接口 ==========
+
+// Gateway 抽象一个支付渠道。
+// 所有写操作都带幂等键：同一个键重复提交只会生效一次，返回第一次的结果。
+type Gateway interface {
+	Authorize(ctx context.Context, req AuthorizeRequest) (*Transaction, error)
+	Capture(ctx context.Context, txnID string, amount float64, idempotencyKey string) (*Transaction, error)
+	Refund(ctx context.Context, txnID string, amount float64, idempotencyKey string) (*Transaction, error)
+	Void(ctx context.Context, txnID string, idempotencyKey string) (*Transaction, error)
+	Get(ctx context.Context, txnID string) (*Transaction, error)
+}
+
+// ========== 错误 ==========
+
+var (
+	ErrDeclined     = errors.New("支付被拒绝")
+	ErrInvalidState = errors.New("交易状态不允许该操作")
+	ErrNotFound     = errors.New("交易不存在")
+	ErrConflict     = errors.New("幂等键已被用于不同的请求")
+	ErrUnavailable  = errors.New("支付渠道暂时不可用")
+	ErrUnknown      = errors.New("交易结果未知")
+	ErrVoidFailed   = errors.New("撤销预授权失败，资金仍被冻结")
+
+	ErrNoIdempotencyKey = errors.New("缺少幂等键")
+)
+
+// Error 是渠道返回的错误，Retryable 表示可以用同一个幂等键安全重试
+type Error struct {
+	Code      string
+	Message   string
+	Retryable bool
+	Err       error // 对应的哨兵错误，便于 errors.Is 判断
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable 判断错误是否可以重试（渠道不可用、超时等）
+func IsRetryable(err error) bool {
+	var pe *Error
+	if errors.As(err, &pe) {
+		return pe.Retryable
+	}
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// 金额统一换算成分再比较，避免浮点误差
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
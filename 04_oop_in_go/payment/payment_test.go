@@ -0,0 +1,155 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestProcessor(t *testing.T) (*MockProvider, *Processor) {
+	t.Helper()
+	provider := NewMockProvider("test-secret")
+	t.Cleanup(provider.Close)
+	p := NewProcessor(NewHTTPGateway(provider.Start()))
+	p.BaseDelay = time.Millisecond
+	return provider, p
+}
+
+// 同一个幂等键的并发请求只授权一次，其余请求拿到同一笔交易
+func TestConcurrentRequestsWithSameKeyAuthorizeOnce(t *testing.T) {
+	provider, _ := newTestProcessor(t)
+	gw := NewHTTPGateway(provider.server.URL)
+
+	const n = 20
+	ids := make([]string, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			txn, err := gw.Authorize(context.Background(), AuthorizeRequest{Method: "支付宝", Amount: 10, IdempotencyKey: "same-key"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids[i] = txn.ID
+		}()
+	}
+	wg.Wait()
+	for _, id := range ids {
+		if id != ids[0] {
+			t.Fatalf("同一个幂等键得到了不同的交易: %v", ids)
+		}
+	}
+	if _, ok := provider.Transaction("txn_0002"); ok {
+		t.Fatal("同一个幂等键创建了多笔交易")
+	}
+}
+
+func TestSameKeyDifferentRequestConflicts(t *testing.T) {
+	provider, _ := newTestProcessor(t)
+	gw := NewHTTPGateway(provider.server.URL)
+	ctx := context.Background()
+	if _, err := gw.Authorize(ctx, AuthorizeRequest{Amount: 10, IdempotencyKey: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gw.Authorize(ctx, AuthorizeRequest{Amount: 20, IdempotencyKey: "k"}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want ErrConflict", err)
+	}
+}
+
+// 渠道暂时不可用时请求没有生效，同一个键重试应当重新处理
+func TestUnavailableReleasesKey(t *testing.T) {
+	provider, _ := newTestProcessor(t)
+	gw := NewHTTPGateway(provider.server.URL)
+	provider.FailNext(1)
+	req := AuthorizeRequest{Amount: 10, IdempotencyKey: "retry-me"}
+	if _, err := gw.Authorize(context.Background(), req); !IsRetryable(err) {
+		t.Fatalf("err = %v, want 可重试错误", err)
+	}
+	txn, err := gw.Authorize(context.Background(), req)
+	if err != nil || txn.Status != StatusAuthorized {
+		t.Fatalf("重试结果 %v, %v", txn, err)
+	}
+}
+
+// 调用方超时后用同一个键重试退款，不会退两次
+func TestRefundRetryWithSameKeyRefundsOnce(t *testing.T) {
+	provider, p := newTestProcessor(t)
+	ctx := context.Background()
+	txn, err := p.Process(ctx, Request{Method: "支付宝", Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Refund(ctx, txn.ID, 30, ""); !errors.Is(err, ErrNoIdempotencyKey) {
+		t.Fatalf("缺少幂等键时 err = %v", err)
+	}
+
+	key := NewIdempotencyKey()
+	p.MaxAttempts = 1
+	provider.LoseResponseNext(1) // 退款已生效，但调用方没收到响应
+	if _, err := p.Refund(ctx, txn.ID, 30, key); !IsRetryable(err) {
+		t.Fatalf("第一次退款 err = %v, want 可重试错误", err)
+	}
+	refunded, err := p.Refund(ctx, txn.ID, 30, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refunded.Refunded != 30 {
+		t.Fatalf("已退款 %.2f，want 30", refunded.Refunded)
+	}
+	if actual, _ := provider.Transaction(txn.ID); actual.Refunded != 30 {
+		t.Fatalf("渠道记录已退款 %.2f，want 30", actual.Refunded)
+	}
+}
+
+// captureFailGateway 让扣款失败，并按 voidErr 决定撤销预授权是否也失败
+type captureFailGateway struct {
+	Gateway
+	voidErr error
+}
+
+func (g captureFailGateway) Capture(ctx context.Context, txnID string, amount float64, key string) (*Transaction, error) {
+	return nil, &Error{Code: "unavailable", Message: "扣款接口超时", Retryable: true, Err: ErrUnavailable}
+}
+
+func (g captureFailGateway) Void(ctx context.Context, txnID string, key string) (*Transaction, error) {
+	if g.voidErr != nil {
+		return nil, g.voidErr
+	}
+	return g.Gateway.Void(ctx, txnID, key)
+}
+
+// 扣款失败后撤销预授权：撤销成功可以换渠道重试；撤销失败时资金仍被冻结，不能再换渠道付一次
+func TestCaptureFailureVoidsAuthorization(t *testing.T) {
+	provider, p := newTestProcessor(t)
+	down := &Error{Code: "unavailable", Message: "维护中", Retryable: true, Err: ErrUnavailable}
+	cases := []struct {
+		name      string
+		voidErr   error
+		status    Status
+		retryable bool
+	}{
+		{"撤销成功", nil, StatusVoided, true},
+		{"撤销失败", down, StatusAuthorized, false},
+	}
+	for _, c := range cases {
+		p.Gateway = captureFailGateway{Gateway: NewHTTPGateway(provider.server.URL), voidErr: c.voidErr}
+		txn, err := p.Process(context.Background(), Request{Method: "银行卡", Amount: 50})
+		if err == nil || txn == nil {
+			t.Fatalf("%s: Process = %v, %v, want 扣款失败", c.name, txn, err)
+		}
+		if actual, _ := provider.Transaction(txn.ID); actual.Status != c.status {
+			t.Errorf("%s: 渠道交易状态 %s, want %s", c.name, actual.Status, c.status)
+		}
+		if IsRetryable(err) != c.retryable || errors.Is(err, ErrVoidFailed) == c.retryable {
+			t.Errorf("%s: err = %v，可重试 %v, want %v", c.name, err, IsRetryable(err), c.retryable)
+		}
+		if !errors.Is(err, ErrUnavailable) {
+			t.Errorf("%s: err = %v, 应当保留扣款失败的原因", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ========== 支付编排 ==========
+
+// Request 是一次完整支付（预授权 + 扣款）的请求
+type Request struct {
+	Method  string
+	Account string
+	Amount  float64
+	// IdempotencyKey 为空时自动生成；调用方在"结果未知"后重试时应传入上一次的键
+	IdempotencyKey string
+}
+
+// Processor 负责把一次支付编排成 预授权 -> (等待异步结果) -> 扣款，
+// 对可重试错误做指数退避重试，并在结果不确定时向渠道查询真实状态（对账）。
+type Processor struct {
+	Gateway        Gateway
+	Notifier       *Notifier     // 可选：有回调时优先等回调，否则轮询
+	MaxAttempts    int           // 每个步骤最多尝试次数
+	BaseDelay      time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	PendingTimeout time.Duration // 等待异步结果的最长时间
+	PollInterval   time.Duration // 轮询交易状态的间隔
+}
+
+func NewProcessor(gw Gateway) *Processor {
+	return &Processor{
+		Gateway:        gw,
+		MaxAttempts:    3,
+		BaseDelay:      100 * time.Millisecond,
+		PendingTimeout: 5 * time.Second,
+		PollInterval:   200 * time.Millisecond,
+	}
+}
+
+// Process 执行一次完整支付，成功时返回已扣款的交易。
+// 返回 ErrUnknown 时说明渠道状态无法确认，调用方应使用同一个幂等键稍后重试。
+func (p *Processor) Process(ctx context.Context, req Request) (*Transaction, error) {
+	key := req.IdempotencyKey
+	if key == "" {
+		key = NewIdempotencyKey()
+	}
+
+	// 1. 预授权
+	txn, err := p.retry(ctx, func() (*Transaction, error) {
+		return p.Gateway.Authorize(ctx, AuthorizeRequest{
+			Method:         req.Method,
+			Account:        req.Account,
+			Amount:         req.Amount,
+			IdempotencyKey: key + ":authorize",
+		})
+	})
+	if err != nil {
+		if IsRetryable(err) {
+			return nil, fmt.Errorf("%w（幂等键 %s）: %v", ErrUnknown, key, err)
+		}
+		return nil, err
+	}
+
+	// 2. 异步渠道：等待最终结果
+	if txn.Status == StatusPending {
+		txn, err = p.awaitResult(ctx, txn.ID)
+		if err != nil {
+			return txn, err
+		}
+	}
+	switch txn.Status {
+	case StatusFailed:
+		return txn, fmt.Errorf("%w: %s", ErrDeclined, txn.Reason)
+	case StatusAuthorized:
+	default:
+		return txn, fmt.Errorf("%w: 预授权后状态为 %s", ErrInvalidState, txn.Status)
+	}
+
+	// 3. 扣款
+	captured, err := p.retry(ctx, func() (*Transaction, error) {
+		return p.Gateway.Capture(ctx, txn.ID, req.Amount, key+":capture")
+	})
+	if err == nil {
+		return captured, nil
+	}
+
+	// 4. 扣款结果不确定：以渠道的真实状态为准（最终状态对账）
+	return p.reconcileCapture(ctx, txn, key, err)
+}
+
+// Refund 对已扣款的交易退款，amount 为 0 表示退还全部剩余金额。
+// key 由调用方生成并保存：超时或返回 ErrUnknown 后重试时必须传入同一个键，否则会重复退款。
+func (p *Processor) Refund(ctx context.Context, txnID string, amount float64, key string) (*Transaction, error) {
+	if key == "" {
+		return nil, ErrNoIdempotencyKey
+	}
+	return p.retry(ctx, func() (*Transaction, error) {
+		return p.Gateway.Refund(ctx, txnID, amount, key+":refund")
+	})
+}
+
+// Void 撤销尚未扣款的预授权，key 的要求与 Refund 相同
+func (p *Processor) Void(ctx context.Context, txnID string, key string) (*Transaction, error) {
+	if key == "" {
+		return nil, ErrNoIdempotencyKey
+	}
+	return p.retry(ctx, func() (*Transaction, error) {
+		return p.Gateway.Void(ctx, txnID, key+":void")
+	})
+}
+
+func (p *Processor) reconcileCapture(ctx context.Context, txn *Transaction, key string, captureErr error) (*Transaction, error) {
+	actual, err := p.retry(ctx, func() (*Transaction, error) {
+		return p.Gateway.Get(ctx, txn.ID)
+	})
+	if err != nil {
+		return txn, fmt.Errorf("%w（幂等键 %s）: 扣款失败且无法查询交易: %v", ErrUnknown, key, captureErr)
+	}
+
+	switch actual.Status {
+	case StatusCaptured:
+		// 扣款其实成功了，只是响应丢失
+		return actual, nil
+	case StatusAuthorized:
+		// 确认没有扣款：撤销预授权，释放用户被冻结的资金
+		voided, voidErr := p.retry(ctx, func() (*Transaction, error) {
+			return p.Gateway.Void(ctx, txn.ID, key+":void")
+		})
+		if voidErr != nil {
+			// 资金还冻结在这个渠道上，不能让调用方换渠道再付一次：返回不可重试的错误
+			return actual, &Error{
+				Code:    "void_failed",
+				Message: fmt.Sprintf("扣款失败且撤销预授权也失败，需要人工处理（幂等键 %s）: %v", key, voidErr),
+				Err:     fmt.Errorf("%w: %w", ErrVoidFailed, captureErr),
+			}
+		}
+		return voided, fmt.Errorf("扣款失败，已撤销预授权: %w", captureErr)
+	default:
+		return actual, fmt.Errorf("扣款失败，交易状态为 %s: %w", actual.Status, captureErr)
+	}
+}
+
+// awaitResult 等待 pending 交易出结果：优先等回调，超时或没有回调时轮询渠道
+func (p *Processor) awaitResult(ctx context.Context, txnID string) (*Transaction, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, p.PendingTimeout)
+	defer cancel()
+
+	if p.Notifier != nil {
+		if _, err := p.Notifier.Wait(waitCtx, txnID); err == nil {
+			// 回调只是通知，最终以查询结果为准
+			return p.retry(ctx, func() (*Transaction, error) {
+				return p.Gateway.Get(ctx, txnID)
+			})
+		}
+	}
+
+	ticker := time.NewTicker(p.PollInterval)
+	defer ticker.Stop()
+	for {
+		txn, err := p.Gateway.Get(ctx, txnID)
+		if err == nil && txn.Status != StatusPending {
+			return txn, nil
+		}
+		select {
+		case <-waitCtx.Done():
+			if txn == nil {
+				txn = &Transaction{ID: txnID, Status: StatusPending}
+			}
+			return txn, fmt.Errorf("%w: 等待交易 %s 的结果超时", ErrUnknown, txnID)
+		case <-ticker.C:
+		}
+	}
+}
+
+// retry 对可重试错误做指数退避重试，不可重试的错误立即返回
+func (p *Processor) retry(ctx context.Context, op func() (*Transaction, error)) (*Transaction, error) {
+	attempts := max(p.MaxAttempts, 1)
+	delay := p.BaseDelay
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		txn, err := op()
+		if err == nil {
+			return txn, nil
+		}
+		lastErr = err
+		if !IsRetryable(err) || attempt == attempts {
+			break
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, errors.Join(lastErr, ctx.Err())
+		}
+		delay *= 2
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,218 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ========== 支付方式 ==========
+
+// Method 与 example.go 中的 PaymentMethod 方法集相同，
+// 所以 Alipay、WeChatPay 可以直接交给 Router 使用
+type Method interface {
+	Pay(amount float64) error
+	GetName() string
+}
+
+// ContextMethod 是支持取消的支付方式，Router 会把调用方的 ctx 传给 PayContext
+type ContextMethod interface {
+	Method
+	PayContext(ctx context.Context, amount float64) error
+}
+
+// GatewayMethod 把 Processor 包装成 Method：Pay 内部走完整的 预授权 -> 扣款 流程
+type GatewayMethod struct {
+	Name      string
+	Account   string
+	Processor *Processor
+}
+
+// Pay 供不传 ctx 的调用方使用（如 example.go 的 ProcessPayment），需要超时或取消时用 PayContext
+func (g GatewayMethod) Pay(amount float64) error {
+	return g.PayContext(context.Background(), amount)
+}
+
+func (g GatewayMethod) PayContext(ctx context.Context, amount float64) error {
+	_, err := g.Processor.Process(ctx, Request{Method: g.Name, Account: g.Account, Amount: amount})
+	return err
+}
+
+func (g GatewayMethod) GetName() string {
+	return g.Name
+}
+
+// ========== 路由规则 ==========
+
+// Route 描述一种支付方式及其适用的金额范围
+type Route struct {
+	Method    Method
+	MinAmount float64 // 最小金额（含）
+	MaxAmount float64 // 最大金额（含），0 表示不限
+}
+
+func (r Route) accepts(amount float64) bool {
+	cents := toCents(amount)
+	if cents < toCents(r.MinAmount) {
+		return false
+	}
+	return r.MaxAmount == 0 || cents <= toCents(r.MaxAmount)
+}
+
+// ========== 支付结果 ==========
+
+// Attempt 记录对某个支付方式的一次尝试（或跳过）
+type Attempt struct {
+	Method   string
+	Skipped  string // 非空表示没有尝试，记录跳过原因
+	Err      error
+	Duration time.Duration
+}
+
+// Result 是一次路由支付的结构化结果
+type Result struct {
+	Amount   float64
+	Method   string // 最终成功的支付方式，失败时为空
+	Attempts []Attempt
+	Err      error
+}
+
+func (r Result) Success() bool {
+	return r.Err == nil
+}
+
+func (r Result) String() string {
+	var sb strings.Builder
+	if r.Success() {
+		fmt.Fprintf(&sb, "支付成功: %.2f 元，使用 %s", r.Amount, r.Method)
+	} else {
+		fmt.Fprintf(&sb, "支付失败: %.2f 元，%v", r.Amount, r.Err)
+	}
+	for i, a := range r.Attempts {
+		switch {
+		case a.Skipped != "":
+			fmt.Fprintf(&sb, "\n  %d. %s 跳过（%s）", i+1, a.Method, a.Skipped)
+		case a.Err != nil:
+			fmt.Fprintf(&sb, "\n  %d. %s 失败: %v", i+1, a.Method, a.Err)
+		default:
+			fmt.Fprintf(&sb, "\n  %d. %s 成功", i+1, a.Method)
+		}
+	}
+	return sb.String()
+}
+
+// ========== 路由器 ==========
+
+// Router 按规则挑选支付方式：用户偏好优先 -> 金额范围过滤 -> 跳过熔断中的方式，
+// 遇到可重试错误时自动切换到下一个方式，每种方式的成功率由独立的熔断器统计。
+type Router struct {
+	routes []Route
+	cfg    BreakerConfig
+
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+func NewRouter(cfg BreakerConfig, routes ...Route) *Router {
+	return &Router{
+		routes:   routes,
+		cfg:      cfg,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+// Breaker 返回某个支付方式的熔断器
+func (r *Router) Breaker(method string) *CircuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.breakers[method]
+	if !ok {
+		b = NewCircuitBreaker(r.cfg)
+		r.breakers[method] = b
+	}
+	return b
+}
+
+// Pay 按偏好顺序 preferred（支付方式名称）尝试支付。
+// ctx 会传给实现了 ContextMethod 的支付方式；ctx 结束后不再尝试下一个方式。
+func (r *Router) Pay(ctx context.Context, amount float64, preferred ...string) Result {
+	result := Result{Amount: amount}
+
+	for _, route := range r.candidates(preferred) {
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+			return result
+		}
+		name := route.Method.GetName()
+		if !route.accepts(amount) {
+			result.Attempts = append(result.Attempts, Attempt{Method: name, Skipped: "金额超出范围"})
+			continue
+		}
+		breaker := r.Breaker(name)
+		if !breaker.Allow() {
+			result.Attempts = append(result.Attempts, Attempt{Method: name, Skipped: "熔断中"})
+			continue
+		}
+
+		start := time.Now()
+		var err error
+		if m, ok := route.Method.(ContextMethod); ok {
+			err = m.PayContext(ctx, amount)
+		} else {
+			err = route.Method.Pay(amount)
+		}
+		if ctx.Err() != nil {
+			// 调用方超时或取消，说明不了支付方式本身是否可用
+			breaker.Release()
+		} else {
+			breaker.Record(healthy(err))
+		}
+		result.Attempts = append(result.Attempts, Attempt{Method: name, Err: err, Duration: time.Since(start)})
+
+		if err == nil {
+			result.Method = name
+			result.Err = nil // 之前失败的尝试记录在 Attempts 中
+			return result
+		}
+		result.Err = err
+		if !IsRetryable(err) {
+			// 被拒绝、结果未知等情况不能换渠道再付一次，否则可能重复扣款
+			return result
+		}
+	}
+
+	if result.Err == nil {
+		result.Err = fmt.Errorf("%w: 没有可用的支付方式", ErrUnavailable)
+	}
+	return result
+}
+
+// healthy 判断一次支付结果能否说明支付方式本身可用。
+// 只有渠道不可用、结果未知这类传输/可用性错误才算失败；
+// 被拒绝、超出限额等业务结果说明渠道工作正常，记为成功，否则几张被拒的卡就会让所有用户都用不了这个方式。
+func healthy(err error) bool {
+	return err == nil || !IsRetryable(err) && !errors.Is(err, ErrUnknown)
+}
+
+// candidates 返回尝试顺序：偏好的方式在前（按偏好顺序），其余按注册顺序
+func (r *Router) candidates(preferred []string) []Route {
+	ordered := make([]Route, 0, len(r.routes))
+	used := make(map[int]bool)
+	for _, name := range preferred {
+		for i, route := range r.routes {
+			if !used[i] && route.Method.GetName() == name {
+				ordered = append(ordered, route)
+				used[i] = true
+			}
+		}
+	}
+	for i, route := range r.routes {
+		if !used[i] {
+			ordered = append(ordered, route)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,144 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// stubMethod 按 err 返回固定结果，记录收到的 ctx
+type stubMethod struct {
+	name string
+	err  error
+	ctxs *[]context.Context
+}
+
+func (s stubMethod) Pay(amount float64) error { return s.err }
+func (s stubMethod) GetName() string          { return s.name }
+
+type ctxMethod struct{ stubMethod }
+
+func (c ctxMethod) PayContext(ctx context.Context, amount float64) error {
+	*c.ctxs = append(*c.ctxs, ctx)
+	return c.err
+}
+
+func TestDeclinesDoNotTripBreaker(t *testing.T) {
+	declined := &Error{Code: "declined", Message: "余额不足", Err: ErrDeclined}
+	router := NewRouter(DefaultBreakerConfig(), Route{Method: stubMethod{name: "银行卡", err: declined}})
+	for range 10 {
+		if res := router.Pay(context.Background(), 10); !errors.Is(res.Err, ErrDeclined) {
+			t.Fatalf("err = %v, want ErrDeclined", res.Err)
+		}
+	}
+	b := router.Breaker("银行卡")
+	if b.State() != BreakerClosed || b.SuccessRate() != 1 {
+		t.Fatalf("被拒绝后熔断器状态 %v，成功率 %.2f", b.State(), b.SuccessRate())
+	}
+}
+
+func TestUnavailableTripsBreaker(t *testing.T) {
+	down := &Error{Code: "unavailable", Message: "维护中", Retryable: true, Err: ErrUnavailable}
+	unknown := fmt.Errorf("%w: 扣款结果无法确认", ErrUnknown)
+	router := NewRouter(DefaultBreakerConfig(),
+		Route{Method: stubMethod{name: "银行卡", err: down}},
+		Route{Method: stubMethod{name: "网关", err: unknown}},
+	)
+	for range 5 {
+		router.Pay(context.Background(), 10, "银行卡")
+		router.Pay(context.Background(), 10, "网关")
+	}
+	for _, name := range []string{"银行卡", "网关"} {
+		if state := router.Breaker(name).State(); state != BreakerOpen {
+			t.Errorf("%s 熔断器状态 %v，want 熔断", name, state)
+		}
+	}
+}
+
+func TestRouterPassesCallerContext(t *testing.T) {
+	type key struct{}
+	var ctxs []context.Context
+	router := NewRouter(DefaultBreakerConfig(), Route{Method: ctxMethod{stubMethod{name: "网关", ctxs: &ctxs}}})
+	ctx := context.WithValue(context.Background(), key{}, "caller")
+	if res := router.Pay(ctx, 10); res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if len(ctxs) != 1 || ctxs[0].Value(key{}) != "caller" {
+		t.Fatalf("PayContext 没有收到调用方的 ctx")
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := router.Pay(canceled, 10); !errors.Is(res.Err, context.Canceled) || len(res.Attempts) != 0 {
+		t.Fatalf("ctx 已取消时 Pay = %v", res)
+	}
+}
+
+// funcMethod 由 pay 决定每次支付的结果
+type funcMethod struct {
+	name string
+	pay  func(ctx context.Context) error
+}
+
+func (f funcMethod) Pay(amount float64) error { return f.pay(context.Background()) }
+func (f funcMethod) GetName() string          { return f.name }
+
+func (f funcMethod) PayContext(ctx context.Context, amount float64) error { return f.pay(ctx) }
+
+// 调用方自己超时或取消不算支付方式失败，也不能占住半开状态的试探名额
+func TestCallerTimeoutDoesNotCountAgainstBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := DefaultBreakerConfig()
+	cfg.Now = func() time.Time { return now }
+
+	down := &Error{Code: "unavailable", Message: "维护中", Retryable: true, Err: ErrUnavailable}
+	var fail bool
+	router := NewRouter(cfg, Route{Method: funcMethod{name: "网关", pay: func(ctx context.Context) error {
+		if fail {
+			return down
+		}
+		<-ctx.Done() // 一直等到调用方放弃
+		return ctx.Err()
+	}}})
+	b := router.Breaker("网关")
+
+	payWithCanceledCaller := func() Result {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		return router.Pay(ctx, 10)
+	}
+	for range 10 {
+		if res := payWithCanceledCaller(); !errors.Is(res.Err, context.DeadlineExceeded) {
+			t.Fatalf("err = %v, want DeadlineExceeded", res.Err)
+		}
+	}
+	if b.State() != BreakerClosed || b.SuccessRate() != 1 {
+		t.Fatalf("调用方超时后熔断器状态 %v，成功率 %.2f, want 关闭、1", b.State(), b.SuccessRate())
+	}
+
+	// 真正的渠道故障让熔断器打开，超时后进入半开
+	fail = true
+	for range cfg.MinRequests {
+		router.Pay(context.Background(), 10)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("熔断器状态 %v, want 熔断", b.State())
+	}
+	now = now.Add(cfg.OpenTimeout)
+
+	// 试探请求被调用方取消：不恢复也不重新熔断，下一个请求仍然可以试探
+	fail = false
+	payWithCanceledCaller()
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("试探被取消后状态 %v, want 半开", b.State())
+	}
+	if !b.Allow() {
+		t.Fatal("试探被取消后没有释放试探名额")
+	}
+	b.Record(true)
+	if b.State() != BreakerClosed {
+		t.Fatalf("试探成功后状态 %v, want 关闭", b.State())
+	}
+}
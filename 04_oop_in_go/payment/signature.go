@@ -0,0 +1,65 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========== 回调签名 ==========
+
+// SignatureHeader 是回调请求中携带签名的请求头，格式为 "t=<unix秒>,v1=<hex>"
+const SignatureHeader = "X-Payment-Signature"
+
+var ErrBadSignature = errors.New("回调签名校验失败")
+
+// Sign 用共享密钥对 "时间戳.请求体" 做 HMAC-SHA256，返回请求头的值
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + ts + ",v1=" + computeMAC(secret, ts, body)
+}
+
+// Verify 校验签名，并拒绝时间戳偏差超过 tolerance 的请求（防重放）
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, mac string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			mac = value
+		}
+	}
+	if ts == "" || mac == "" {
+		return fmt.Errorf("%w: 签名头格式错误", ErrBadSignature)
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: 时间戳不合法", ErrBadSignature)
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > tolerance || skew < -tolerance {
+		return fmt.Errorf("%w: 时间戳超出允许范围", ErrBadSignature)
+	}
+
+	expected := computeMAC(secret, ts, body)
+	// 使用常量时间比较，防止时序攻击
+	if !hmac.Equal([]byte(mac), []byte(expected)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func computeMAC(secret, ts string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
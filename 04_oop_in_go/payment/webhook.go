@@ -0,0 +1,109 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ========== 异步回调 ==========
+
+// Event 是渠道推送的交易状态变化
+type Event struct {
+	TransactionID string    `json:"transaction_id"`
+	Status        Status    `json:"status"`
+	Reason        string    `json:"reason,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Notifier 接收渠道回调：校验签名后记录事件，并唤醒等待该交易的 goroutine。
+// 它实现了 http.Handler，可以直接挂到回调地址上。
+type Notifier struct {
+	Secret    string
+	Tolerance time.Duration // 允许的时间戳偏差
+
+	mu      sync.Mutex
+	events  map[string]Event
+	waiters map[string][]chan Event
+}
+
+func NewNotifier(secret string) *Notifier {
+	return &Notifier{
+		Secret:    secret,
+		Tolerance: 5 * time.Minute,
+		events:    make(map[string]Event),
+		waiters:   make(map[string][]chan Event),
+	}
+}
+
+func (n *Notifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "读取请求失败", http.StatusBadRequest)
+		return
+	}
+	if err := Verify(n.Secret, r.Header.Get(SignatureHeader), body, time.Now(), n.Tolerance); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "事件格式错误", http.StatusBadRequest)
+		return
+	}
+	n.Publish(event)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Publish 记录事件并通知等待者（同一交易重复推送是正常的，只保留最新的）
+func (n *Notifier) Publish(event Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if old, ok := n.events[event.TransactionID]; ok && old.Timestamp.After(event.Timestamp) {
+		return // 乱序到达的旧事件
+	}
+	n.events[event.TransactionID] = event
+	for _, ch := range n.waiters[event.TransactionID] {
+		ch <- event // 缓冲为 1，不会阻塞
+	}
+	delete(n.waiters, event.TransactionID)
+}
+
+// Wait 等待某笔交易的回调；如果回调已经先到达，立即返回
+func (n *Notifier) Wait(ctx context.Context, txnID string) (Event, error) {
+	n.mu.Lock()
+	if event, ok := n.events[txnID]; ok {
+		n.mu.Unlock()
+		return event, nil
+	}
+	ch := make(chan Event, 1)
+	n.waiters[txnID] = append(n.waiters[txnID], ch)
+	n.mu.Unlock()
+
+	select {
+	case event := <-ch:
+		return event, nil
+	case <-ctx.Done():
+		n.removeWaiter(txnID, ch)
+		return Event{}, ctx.Err()
+	}
+}
+
+func (n *Notifier) removeWaiter(txnID string, target chan Event) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	list := n.waiters[txnID]
+	for i, ch := range list {
+		if ch == target {
+			n.waiters[txnID] = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(n.waiters[txnID]) == 0 {
+		delete(n.waiters, txnID)
+	}
+}
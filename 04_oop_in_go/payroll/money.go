@@ -0,0 +1,55 @@
+// Package payroll 根据 hr.Employee 的月薪计算工资条：
+// 按计薪周期折算试用期和离职员工的工资，扣除社保公积金，按可替换的税率表计算个税，
+// 再加上奖金、补贴并减去其他扣款，得到实发工资。
+package payroll
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ========== 金额 ==========
+
+// Money 以分为单位，避免浮点数累加误差
+type Money int64
+
+// Yuan 把以元为单位的金额转换为 Money（四舍五入到分）
+func Yuan(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+// Mul 按比例计算金额，四舍五入到分
+func (m Money) Mul(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+// ========== 计薪周期 ==========
+
+// Period 是计薪周期，Start 和 End 都包含在内（按日期计算，忽略时分秒）
+type Period struct {
+	Start, End time.Time
+}
+
+// Month 返回某个自然月的计薪周期
+func Month(year int, month time.Month) Period {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return Period{Start: start, End: start.AddDate(0, 1, -1)}
+}
+
+// Days 返回周期内的天数
+func (p Period) Days() int {
+	return int(p.End.Sub(p.Start).Hours()/24) + 1
+}
+
+func (p Period) String() string {
+	return p.Start.Format(time.DateOnly) + " ~ " + p.End.Format(time.DateOnly)
+}
@@ -0,0 +1,220 @@
+package payroll
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang_study/04_oop_in_go/hr"
+)
+
+// ========== 调整项 ==========
+
+// AdjustmentKind 决定调整项是否计税、是否计入应发
+type AdjustmentKind int
+
+const (
+	Bonus     AdjustmentKind = iota + 1 // 奖金：计入应发，计税
+	Allowance                           // 补贴：计入应发，不计税
+	Deduction                           // 扣款：税后从实发中扣除
+)
+
+// Adjustment 是一笔奖金、补贴或扣款，Amount 为正数
+type Adjustment struct {
+	Kind   AdjustmentKind
+	Name   string
+	Amount Money
+}
+
+// ========== 工资条 ==========
+
+// Line 是工资条上的一行
+type Line struct {
+	Name   string
+	Amount Money
+	Note   string
+}
+
+// Payslip 是一个员工在一个计薪周期的工资条
+type Payslip struct {
+	Employee   hr.Employee
+	Period     Period
+	Earnings   []Line // 基本工资（按状态分段）、奖金、补贴
+	Insurance  []Line // 社保公积金个人部分
+	Deductions []Line // 税后扣款
+
+	Gross          Money // 应发合计
+	InsuranceTotal Money
+	Taxable        Money // 应纳税所得额（起征点之前）
+	Tax            Money
+	Net            Money // 实发
+}
+
+// ========== 计算引擎 ==========
+
+var ErrNotEmployed = errors.New("计薪周期内不在职")
+
+// Engine 组合税率表、社保规则和试用期工资比例
+type Engine struct {
+	Tax           TaxTable
+	Insurance     SocialInsurance
+	ProbationRate float64 // 试用期工资占月薪的比例
+}
+
+// NewEngine 使用按月税率表、常见社保比例和 80% 试用期工资
+func NewEngine() *Engine {
+	return &Engine{Tax: ChinaMonthly(), Insurance: DefaultInsurance(), ProbationRate: 0.8}
+}
+
+// segment 是周期内状态相同的一段连续日期
+type segment struct {
+	status   hr.Status
+	from, to time.Time
+	days     int
+}
+
+// segments 按天查询员工状态，把相邻且状态相同的日期合并。
+// 离职的生效日期当天不再计薪；入职之前的日期不在任何一段中。
+func segments(e hr.Employee, p Period) []segment {
+	var segs []segment
+	for day := p.Start; !day.After(p.End); day = day.AddDate(0, 0, 1) {
+		status, ok := e.StatusOn(day)
+		if !ok || !status.Active() {
+			continue
+		}
+		if n := len(segs); n > 0 && segs[n-1].status == status && segs[n-1].to.AddDate(0, 0, 1).Equal(day) {
+			segs[n-1].to = day
+			segs[n-1].days++
+			continue
+		}
+		segs = append(segs, segment{status: status, from: day, to: day, days: 1})
+	}
+	return segs
+}
+
+// Calculate 计算工资条：
+//
+//	基本工资 = 月薪 × 在职天数 / 周期天数（试用期再乘以 ProbationRate）
+//	应发     = 基本工资 + 奖金 + 补贴
+//	社保     = 缴费基数 × 各项比例（周期内在职过就按整月缴纳）
+//	个税     = Tax(基本工资 + 奖金 - 社保)
+//	实发     = 应发 - 社保 - 个税 - 扣款
+func (en *Engine) Calculate(e hr.Employee, p Period, adjustments ...Adjustment) (*Payslip, error) {
+	segs := segments(e, p)
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("%v %s: %w", e, p, ErrNotEmployed)
+	}
+
+	ps := &Payslip{Employee: e, Period: p}
+	monthly := Yuan(e.Salary)
+	total := p.Days()
+	taxable := Money(0)
+	for _, s := range segs {
+		rate := 1.0
+		note := fmt.Sprintf("%s %s~%s %d/%d 天", s.status, s.from.Format("01-02"), s.to.Format("01-02"), s.days, total)
+		if s.status == hr.Probation {
+			rate = en.ProbationRate
+			note += fmt.Sprintf("，按 %.0f%% 发放", rate*100)
+		}
+		pay := monthly.Mul(rate * float64(s.days) / float64(total))
+		ps.Earnings = append(ps.Earnings, Line{Name: "基本工资", Amount: pay, Note: note})
+		taxable += pay
+	}
+
+	for _, adj := range adjustments {
+		line := Line{Name: adj.Name, Amount: adj.Amount}
+		switch adj.Kind {
+		case Bonus:
+			line.Note = "计税"
+			ps.Earnings = append(ps.Earnings, line)
+			taxable += adj.Amount
+		case Allowance:
+			line.Note = "免税"
+			ps.Earnings = append(ps.Earnings, line)
+		case Deduction:
+			ps.Deductions = append(ps.Deductions, line)
+		default:
+			return nil, fmt.Errorf("调整项 %q 类型无效: %d", adj.Name, adj.Kind)
+		}
+	}
+	for _, line := range ps.Earnings {
+		ps.Gross += line.Amount
+	}
+
+	base := en.Insurance.Base(monthly)
+	for _, item := range en.Insurance.Items {
+		amount := base.Mul(item.Rate)
+		ps.Insurance = append(ps.Insurance, Line{
+			Name:   item.Name,
+			Amount: amount,
+			Note:   fmt.Sprintf("基数 %v × %.1f%%", base, item.Rate*100),
+		})
+		ps.InsuranceTotal += amount
+	}
+
+	ps.Taxable = max(taxable-ps.InsuranceTotal, 0)
+	ps.Tax = en.Tax.Tax(ps.Taxable)
+	ps.Net = ps.Gross - ps.InsuranceTotal - ps.Tax
+	for _, line := range ps.Deductions {
+		ps.Net -= line.Amount
+	}
+	return ps, nil
+}
+
+// ========== 输出 ==========
+
+// WriteTo 输出文本格式的工资条（实现 io.WriterTo）
+func (ps *Payslip) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, ps.String())
+	return int64(n), err
+}
+
+func (ps *Payslip) String() string {
+	var b strings.Builder
+	rule := strings.Repeat("-", 52) + "\n"
+	row := func(name string, amount Money, note string) {
+		line := fmt.Sprintf("  %s %12v  %s", pad(name, 10), amount, note)
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+
+	fmt.Fprintf(&b, "工资条  %v  %s  %s\n", ps.Employee, ps.Employee.Department, ps.Period)
+	b.WriteString(rule)
+	b.WriteString("收入\n")
+	for _, line := range ps.Earnings {
+		row(line.Name, line.Amount, line.Note)
+	}
+	row("应发合计", ps.Gross, "")
+	b.WriteString("社保公积金\n")
+	for _, line := range ps.Insurance {
+		row(line.Name, -line.Amount, line.Note)
+	}
+	b.WriteString("个税\n")
+	row("个人所得税", -ps.Tax, fmt.Sprintf("应纳税所得额 %v", ps.Taxable))
+	if len(ps.Deductions) > 0 {
+		b.WriteString("其他扣款\n")
+		for _, line := range ps.Deductions {
+			row(line.Name, -line.Amount, line.Note)
+		}
+	}
+	b.WriteString(rule)
+	row("实发工资", ps.Net, "")
+	return b.String()
+}
+
+// pad 按显示宽度补齐空格（中文字符占两列）
+func pad(s string, width int) string {
+	w := 0
+	for _, r := range s {
+		if r > 0x7f {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	if w >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-w)
+}
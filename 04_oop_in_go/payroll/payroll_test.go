@@ -0,0 +1,164 @@
+package payroll
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang_study/04_oop_in_go/hr"
+)
+
+// go test ./04_oop_in_go/payroll -update 用本次结果重新生成 testdata 下的黄金文件
+var update = flag.Bool("update", false, "重新生成黄金文件")
+
+func day(t testing.TB, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// newDirectory 建立 2025 年 3 月的人员变动：王五月中转正，李四月中离职，赵六月中入职，孙七下月才入职
+func newDirectory(t *testing.T) *hr.Directory {
+	t.Helper()
+	dir := hr.NewDirectory()
+	hire := func(id, name, dept string, salary float64, hired string) {
+		e := hr.Employee{
+			Person:     hr.Person{Name: name, BirthDate: day(t, "1990-01-01")},
+			ID:         id,
+			Department: dept,
+			Salary:     salary,
+		}
+		if err := dir.Hire(e, day(t, hired)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hire("E001", "张三", "管理层", 50000, "2020-01-01")
+	hire("E002", "李四", "技术部", 30000, "2021-03-01")
+	hire("E003", "王五", "技术部", 20000, "2025-02-01")
+	hire("E004", "赵六", "市场部", 4000, "2025-03-10")
+	hire("E005", "孙七", "市场部", 15000, "2025-04-01")
+	for _, err := range []error{
+		dir.Confirm("E001", day(t, "2020-04-01")),
+		dir.Confirm("E002", day(t, "2021-06-01")),
+		dir.Confirm("E003", day(t, "2025-03-16")),
+		dir.Resign("E002", day(t, "2025-03-21"), "个人原因"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMonthlyPayslipsGolden(t *testing.T) {
+	dir := newDirectory(t)
+	engine := NewEngine()
+	period := Month(2025, time.March)
+	adjustments := map[string][]Adjustment{
+		"E001": {
+			{Kind: Bonus, Name: "季度奖金", Amount: Yuan(10000)},
+			{Kind: Allowance, Name: "餐补", Amount: Yuan(500)},
+		},
+		"E002": {
+			{Kind: Deduction, Name: "设备未归还", Amount: Yuan(200)},
+		},
+	}
+
+	var b bytes.Buffer
+	var total Money
+	for _, e := range dir.All() {
+		slip, err := engine.Calculate(e, period, adjustments[e.ID]...)
+		if e.ID == "E005" {
+			if !errors.Is(err, ErrNotEmployed) {
+				t.Fatalf("%v 计薪周期内未入职 err = %v, want ErrNotEmployed", e, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slip.Net != slip.Gross-slip.InsuranceTotal-slip.Tax-sum(slip.Deductions) {
+			t.Errorf("%v 实发 %v 与各项合计不一致", e, slip.Net)
+		}
+		slip.WriteTo(&b)
+		b.WriteString("\n")
+		total += slip.Net
+	}
+	fmt.Fprintf(&b, "实发合计: %v\n", total)
+	compareGolden(t, "march.golden", b.Bytes())
+}
+
+func TestSwappableTaxTable(t *testing.T) {
+	dir := newDirectory(t)
+	zhang, _ := dir.Get("E001")
+	flat := &Engine{Tax: Flat(0.2), Insurance: SocialInsurance{}, ProbationRate: 1}
+	slip, err := flat.Calculate(zhang, Month(2025, time.March))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slip.Net != Yuan(40000) {
+		t.Fatalf("固定 20%% 税率、不缴社保时实发 %v, want 40000.00", slip.Net)
+	}
+}
+
+// 每一档上限前后 1 元取值，锁定累进税率表的边界
+func TestBracketEdgesGolden(t *testing.T) {
+	table := ChinaMonthly()
+	step := Yuan(1)
+	points := []Money{0, table.Threshold - step, table.Threshold, table.Threshold + step}
+	for _, bracket := range table.Brackets {
+		if bracket.UpTo == 0 {
+			continue
+		}
+		edge := table.Threshold + bracket.UpTo
+		points = append(points, edge-step, edge, edge+step)
+	}
+	points = append(points, table.Threshold+Yuan(200000))
+
+	var b strings.Builder
+	var prev Money
+	for _, taxable := range points {
+		tax := table.Tax(taxable)
+		if tax < prev {
+			t.Errorf("应纳税所得额 %v 的个税 %v 小于前一档 %v", taxable, tax, prev)
+		}
+		prev = tax
+		fmt.Fprintf(&b, "应纳税所得额 %12v  个税 %12v\n", taxable, tax)
+	}
+	compareGolden(t, "brackets.golden", []byte(b.String()))
+}
+
+func sum(lines []Line) Money {
+	var total Money
+	for _, l := range lines {
+		total += l.Amount
+	}
+	return total
+}
+
+func compareGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v（首次运行请加 -update 生成）", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("与 %s 不一致，运行 go test -update 查看变化\n got:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,112 @@
+package payroll
+
+import "math"
+
+// ========== 个税 ==========
+
+// TaxTable 根据应纳税所得额（已扣除社保公积金）计算个税，可以替换成任意规则
+type TaxTable interface {
+	Tax(taxable Money) Money
+}
+
+// Bracket 是累进税率表的一档：超过上一档上限、不超过 UpTo 的部分按 Rate 计税。
+// UpTo 为 0 表示没有上限（必须是最后一档）。
+type Bracket struct {
+	UpTo Money
+	Rate float64
+}
+
+// Progressive 是超额累进税率：先减去起征点，再分档计税
+type Progressive struct {
+	Threshold Money
+	Brackets  []Bracket
+}
+
+// Tax 分档累加后统一四舍五入到分，避免每档各自舍入产生误差
+func (p Progressive) Tax(taxable Money) Money {
+	over := taxable - p.Threshold
+	if over <= 0 {
+		return 0
+	}
+	tax := 0.0
+	lower := Money(0)
+	for _, b := range p.Brackets {
+		upper := b.UpTo
+		if upper == 0 || upper > over {
+			upper = over
+		}
+		if upper > lower {
+			tax += float64(upper-lower) * b.Rate
+		}
+		if b.UpTo == 0 || over <= b.UpTo {
+			break
+		}
+		lower = b.UpTo
+	}
+	return Money(math.Round(tax))
+}
+
+// ChinaMonthly 是按月计算的综合所得税率表（起征点 5000 元，七级超额累进）
+func ChinaMonthly() Progressive {
+	return Progressive{
+		Threshold: Yuan(5000),
+		Brackets: []Bracket{
+			{UpTo: Yuan(3000), Rate: 0.03},
+			{UpTo: Yuan(12000), Rate: 0.10},
+			{UpTo: Yuan(25000), Rate: 0.20},
+			{UpTo: Yuan(35000), Rate: 0.25},
+			{UpTo: Yuan(55000), Rate: 0.30},
+			{UpTo: Yuan(80000), Rate: 0.35},
+			{Rate: 0.45},
+		},
+	}
+}
+
+// Flat 是固定比例税率（例如劳务报酬的简化计算）
+type Flat float64
+
+func (f Flat) Tax(taxable Money) Money {
+	if taxable <= 0 {
+		return 0
+	}
+	return taxable.Mul(float64(f))
+}
+
+// ========== 社保公积金 ==========
+
+// Contribution 是个人缴纳的一项社保或公积金
+type Contribution struct {
+	Name string
+	Rate float64
+}
+
+// SocialInsurance 按缴费基数计算个人缴纳部分，基数限制在 [MinBase, MaxBase] 之间
+type SocialInsurance struct {
+	MinBase, MaxBase Money
+	Items            []Contribution
+}
+
+// Base 返回月薪对应的缴费基数
+func (s SocialInsurance) Base(salary Money) Money {
+	if s.MinBase > 0 && salary < s.MinBase {
+		return s.MinBase
+	}
+	if s.MaxBase > 0 && salary > s.MaxBase {
+		return s.MaxBase
+	}
+	return salary
+}
+
+// DefaultInsurance 是常见的个人缴费比例：养老 8%、医疗 2%、失业 0.5%、公积金 12%
+func DefaultInsurance() SocialInsurance {
+	return SocialInsurance{
+		MinBase: Yuan(5000),
+		MaxBase: Yuan(35000),
+		Items: []Contribution{
+			{Name: "养老保险", Rate: 0.08},
+			{Name: "医疗保险", Rate: 0.02},
+			{Name: "失业保险", Rate: 0.005},
+			{Name: "住房公积金", Rate: 0.12},
+		},
+	}
+}
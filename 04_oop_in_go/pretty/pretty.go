@@ -0,0 +1,389 @@
+// Package pretty 是第04节 Describe/ProcessValue 类型开关的通用版本：
+// 用反射遍历任意值，支持结构体、按键排序的 map、切片、指针和循环引用，
+// 优先使用 fmt.Stringer 和按类型注册的自定义格式化函数，
+// 输出可以是缩进的多行格式、单行格式或带颜色的终端格式。
+package pretty
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ========== 打印器 ==========
+
+// Printer 保存输出选项和自定义格式化函数，并发安全
+type Printer struct {
+	Indent   string // 每级缩进；为空时输出单行
+	Color    bool   // 是否使用 ANSI 颜色
+	MaxDepth int    // 最大嵌套深度，0 表示不限制
+
+	mu         sync.RWMutex
+	exact      map[reflect.Type]func(reflect.Value) string
+	interfaces []ifaceFormatter // 按注册顺序匹配
+}
+
+type ifaceFormatter struct {
+	typ    reflect.Type
+	format func(reflect.Value) string
+}
+
+// New 返回缩进两个空格、不带颜色的打印器
+func New() *Printer {
+	return &Printer{Indent: "  "}
+}
+
+// SingleLine 返回单行输出的打印器
+func SingleLine() *Printer {
+	return &Printer{}
+}
+
+// Colored 返回缩进且带颜色的打印器
+func Colored() *Printer {
+	return &Printer{Indent: "  ", Color: true}
+}
+
+// Register 为类型 T 注册格式化函数。T 是接口时，所有实现了 T 的类型都会使用它；
+// 具体类型的注册优先于接口，接口之间按注册顺序匹配。
+func Register[T any](p *Printer, format func(T) string) {
+	typ := reflect.TypeFor[T]()
+	fn := func(v reflect.Value) string {
+		return format(v.Interface().(T))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if typ.Kind() == reflect.Interface {
+		p.interfaces = append(p.interfaces, ifaceFormatter{typ: typ, format: fn})
+		return
+	}
+	if p.exact == nil {
+		p.exact = make(map[reflect.Type]func(reflect.Value) string)
+	}
+	p.exact[typ] = fn
+}
+
+// Sprint 返回 v 的格式化结果
+func (p *Printer) Sprint(v any) string {
+	st := &state{p: p, indent: p.Indent, visiting: make(map[visitKey]bool)}
+	if v == nil {
+		st.nilValue("")
+	} else {
+		st.value(reflect.ValueOf(v), 0)
+	}
+	return st.b.String()
+}
+
+// Fprint 把格式化结果和换行写入 w
+func (p *Printer) Fprint(w io.Writer, v any) error {
+	_, err := fmt.Fprintln(w, p.Sprint(v))
+	return err
+}
+
+// Print 把格式化结果写到标准输出
+func (p *Printer) Print(v any) {
+	p.Fprint(os.Stdout, v)
+}
+
+var defaultPrinter = New()
+
+// Default 返回全局打印器（缩进、无颜色）
+func Default() *Printer {
+	return defaultPrinter
+}
+
+// Sprint 使用全局打印器格式化
+func Sprint(v any) string {
+	return defaultPrinter.Sprint(v)
+}
+
+// Print 使用全局打印器输出到标准输出
+func Print(v any) {
+	defaultPrinter.Print(v)
+}
+
+// ========== 颜色 ==========
+
+const (
+	colorReset  = "\x1b[0m"
+	colorString = "\x1b[32m" // 绿色
+	colorNumber = "\x1b[36m" // 青色
+	colorConst  = "\x1b[33m" // 黄色：bool、nil
+	colorType   = "\x1b[34m" // 蓝色
+	colorCustom = "\x1b[35m" // 紫色：Stringer 和自定义格式化
+	colorNote   = "\x1b[31m" // 红色：循环引用、超出深度
+)
+
+// ========== 遍历 ==========
+
+// visitKey 标识当前路径上的引用（指针、map、切片），用于发现循环
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+type state struct {
+	p        *Printer
+	indent   string
+	b        strings.Builder
+	visiting map[visitKey]bool
+}
+
+func (st *state) write(color, s string) {
+	if st.p.Color && color != "" {
+		st.b.WriteString(color + s + colorReset)
+		return
+	}
+	st.b.WriteString(s)
+}
+
+func (st *state) nilValue(typeName string) {
+	if typeName == "" {
+		st.write(colorConst, "nil")
+		return
+	}
+	st.write(colorType, "("+typeName+")")
+	st.write(colorConst, "(nil)")
+}
+
+// custom 尝试自定义格式化函数和 Stringer/error，成功返回 true
+func (st *state) custom(v reflect.Value) bool {
+	if !v.CanInterface() {
+		return false
+	}
+	st.p.mu.RLock()
+	fn, ok := st.p.exact[v.Type()]
+	if !ok {
+		for _, f := range st.p.interfaces {
+			if v.Type().Implements(f.typ) {
+				fn, ok = f.format, true
+				break
+			}
+		}
+	}
+	st.p.mu.RUnlock()
+	if ok {
+		st.write(colorCustom, fn(v))
+		return true
+	}
+
+	// nil 指针调用 String() 很可能 panic，交给普通逻辑打印
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return false
+	}
+	switch x := v.Interface().(type) {
+	case error:
+		st.write(colorCustom, x.Error())
+		return true
+	case fmt.Stringer:
+		st.write(colorCustom, x.String())
+		return true
+	}
+	return false
+}
+
+func (st *state) value(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		st.nilValue("")
+		return
+	}
+	if st.custom(v) {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		st.write(colorConst, strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		st.write(colorNumber, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		st.write(colorNumber, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		st.write(colorNumber, strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))
+	case reflect.Complex64, reflect.Complex128:
+		st.write(colorNumber, strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()))
+	case reflect.String:
+		st.write(colorString, strconv.Quote(v.String()))
+	case reflect.Interface:
+		if v.IsNil() {
+			st.nilValue("")
+			return
+		}
+		st.value(v.Elem(), depth)
+	case reflect.Pointer:
+		st.pointer(v, depth)
+	case reflect.Struct:
+		st.structValue(v, depth)
+	case reflect.Map:
+		st.mapValue(v, depth)
+	case reflect.Slice, reflect.Array:
+		st.list(v, depth)
+	default:
+		// chan、func、unsafe.Pointer：地址每次运行都不同，只打印类型
+		if v.IsNil() {
+			st.nilValue(v.Type().String())
+			return
+		}
+		st.write(colorType, "<"+v.Type().String()+">")
+	}
+}
+
+// enter 把引用加入当前路径；已经在路径上说明出现了循环
+func (st *state) enter(v reflect.Value) (visitKey, bool) {
+	key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if st.visiting[key] {
+		st.write(colorNote, "<循环引用 "+v.Type().String()+">")
+		return key, false
+	}
+	st.visiting[key] = true
+	return key, true
+}
+
+func (st *state) tooDeep(depth int) bool {
+	if st.p.MaxDepth > 0 && depth >= st.p.MaxDepth {
+		st.write(colorNote, "{...}")
+		return true
+	}
+	return false
+}
+
+func (st *state) pointer(v reflect.Value, depth int) {
+	if v.IsNil() {
+		st.nilValue(v.Type().String())
+		return
+	}
+	key, ok := st.enter(v)
+	if !ok {
+		return
+	}
+	defer delete(st.visiting, key)
+	st.b.WriteString("&")
+	st.value(v.Elem(), depth)
+}
+
+// ========== 复合类型 ==========
+
+// entry 是复合类型里的一项：结构体字段、map 键值对或切片元素
+type entry struct {
+	key   string // 字段名或格式化后的键；切片元素为空
+	value reflect.Value
+}
+
+// composite 按缩进设置输出 Type{ a, b } 或多行格式
+func (st *state) composite(typeName string, entries []entry, depth int) {
+	st.write(colorType, typeName)
+	if len(entries) == 0 {
+		st.b.WriteString("{}")
+		return
+	}
+	if st.tooDeep(depth + 1) {
+		return
+	}
+	st.b.WriteString("{")
+	multiline := st.indent != ""
+	for i, e := range entries {
+		if multiline {
+			st.b.WriteString("\n" + strings.Repeat(st.indent, depth+1))
+		} else if i > 0 {
+			st.b.WriteString(", ")
+		}
+		if e.key != "" {
+			st.b.WriteString(e.key + ": ")
+		}
+		st.value(e.value, depth+1)
+		if multiline {
+			st.b.WriteString(",")
+		}
+	}
+	if multiline {
+		st.b.WriteString("\n" + strings.Repeat(st.indent, depth))
+	}
+	st.b.WriteString("}")
+}
+
+func (st *state) structValue(v reflect.Value, depth int) {
+	t := v.Type()
+	entries := make([]entry, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		entries = append(entries, entry{key: t.Field(i).Name, value: v.Field(i)})
+	}
+	st.composite(t.String(), entries, depth)
+}
+
+func (st *state) list(v reflect.Value, depth int) {
+	if v.Kind() == reflect.Slice {
+		if v.IsNil() {
+			st.nilValue(v.Type().String())
+			return
+		}
+		key, ok := st.enter(v)
+		if !ok {
+			return
+		}
+		defer delete(st.visiting, key)
+	}
+	entries := make([]entry, v.Len())
+	for i := range entries {
+		entries[i] = entry{value: v.Index(i)}
+	}
+	st.composite(v.Type().String(), entries, depth)
+}
+
+func (st *state) mapValue(v reflect.Value, depth int) {
+	if v.IsNil() {
+		st.nilValue(v.Type().String())
+		return
+	}
+	key, ok := st.enter(v)
+	if !ok {
+		return
+	}
+	defer delete(st.visiting, key)
+
+	keys := v.MapKeys()
+	sortKeys(keys)
+	entries := make([]entry, len(keys))
+	for i, k := range keys {
+		// 键用同样的规则格式化，但总是单行
+		ks := &state{p: st.p, visiting: st.visiting}
+		ks.value(k, 0)
+		entries[i] = entry{key: ks.b.String(), value: v.MapIndex(k)}
+	}
+	st.composite(v.Type().String(), entries, depth)
+}
+
+// sortKeys 让 map 输出稳定：数字按大小、字符串按字典序、其他类型按 %v 文本
+func sortKeys(keys []reflect.Value) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Kind() == reflect.Interface {
+			a = a.Elem()
+		}
+		if b.Kind() == reflect.Interface {
+			b = b.Elem()
+		}
+		if a.Kind() == b.Kind() {
+			switch a.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return a.Int() < b.Int()
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				return a.Uint() < b.Uint()
+			case reflect.Float32, reflect.Float64:
+				return a.Float() < b.Float()
+			case reflect.String:
+				return a.String() < b.String()
+			case reflect.Bool:
+				return !a.Bool() && b.Bool()
+			}
+		}
+		return fmt.Sprint(a) < fmt.Sprint(b)
+	})
+}
@@ -0,0 +1,149 @@
+package pretty
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type node struct {
+	Name string
+	Next *node
+}
+
+type pair struct {
+	A, B *node
+}
+
+type celsius float64
+
+type shape interface{ Area() float64 }
+
+type square struct{ Side float64 }
+
+func (s square) Area() float64 { return s.Side * s.Side }
+
+func TestSprint(t *testing.T) {
+	self := &node{Name: "a"}
+	self.Next = self
+
+	ring := &node{Name: "a", Next: &node{Name: "b"}}
+	ring.Next.Next = ring
+
+	shared := &node{Name: "x"}
+
+	loop := make([]any, 1)
+	loop[0] = loop
+
+	// 同一底层数组、长度不同的切片不是同一个引用，visitKey 要区分 len
+	prefix := make([]any, 2)
+	prefix[0] = prefix[:1]
+
+	cases := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"自引用指针", self, `&pretty.node{Name: "a", Next: <循环引用 *pretty.node>}`},
+		{"两个节点的环", ring, `&pretty.node{Name: "a", Next: &pretty.node{Name: "b", Next: <循环引用 *pretty.node>}}`},
+		{"共享但无环的指针", pair{shared, shared}, `pretty.pair{A: &pretty.node{Name: "x", Next: (*pretty.node)(nil)}, B: &pretty.node{Name: "x", Next: (*pretty.node)(nil)}}`},
+		{"自引用切片", loop, `[]interface {}{<循环引用 []interface {}>}`},
+		{"长度不同的子切片", prefix, `[]interface {}{[]interface {}{<循环引用 []interface {}>}, nil}`},
+		{"整数键按大小排序", map[int]string{10: "x", 2: "y", -1: "z"}, `map[int]string{-1: "z", 2: "y", 10: "x"}`},
+		{"字符串键按字典序", map[string]int{"b": 2, "a": 1, "c": 3}, `map[string]int{"a": 1, "b": 2, "c": 3}`},
+		{"混合类型键", map[any]bool{"b": true, 1: false, "a": true}, `map[interface {}]bool{1: false, "a": true, "b": true}`},
+		{"nil", nil, "nil"},
+		{"nil 指针", (*node)(nil), "(*pretty.node)(nil)"},
+		{"nil 接口字段", struct{ Err error }{}, "struct { Err error }{Err: nil}"},
+		{"nil map 和切片", struct {
+			M map[string]int
+			S []int
+		}{}, "struct { M map[string]int; S []int }{M: (map[string]int)(nil), S: ([]int)(nil)}"},
+		{"error 和 Stringer", []any{errors.New("出错了"), 1500 * time.Millisecond}, "[]interface {}{出错了, 1.5s}"},
+	}
+	p := SingleLine()
+	for _, c := range cases {
+		if got := p.Sprint(c.v); got != c.want {
+			t.Errorf("%s:\n got %s\nwant %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	chain := &node{Name: "a", Next: &node{Name: "b", Next: &node{Name: "c"}}}
+	cases := []struct {
+		depth int
+		want  string
+	}{
+		{1, `&pretty.node{...}`},
+		{2, `&pretty.node{Name: "a", Next: &pretty.node{...}}`},
+		{0, `&pretty.node{Name: "a", Next: &pretty.node{Name: "b", Next: &pretty.node{Name: "c", Next: (*pretty.node)(nil)}}}`},
+	}
+	for _, c := range cases {
+		p := &Printer{MaxDepth: c.depth}
+		if got := p.Sprint(chain); got != c.want {
+			t.Errorf("MaxDepth %d:\n got %s\nwant %s", c.depth, got, c.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	p := SingleLine()
+	Register(p, func(c celsius) string { return fmt.Sprintf("%.1f℃", float64(c)) })
+	Register(p, func(d time.Duration) string { return fmt.Sprintf("%dms", d.Milliseconds()) })
+	Register(p, func(s shape) string { return fmt.Sprintf("面积 %g", s.Area()) })
+
+	cases := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"具体类型", celsius(36.55), "36.5℃"},
+		{"优先于 Stringer", 2 * time.Second, "2000ms"},
+		{"接口", square{Side: 3}, "面积 9"},
+		{"嵌套在 map 中", map[string]celsius{"室外": -3, "室内": 21}, `map[string]pretty.celsius{"室内": 21.0℃, "室外": -3.0℃}`},
+		{"未注册的类型不受影响", 36.5, "36.5"},
+	}
+	for _, c := range cases {
+		if got := p.Sprint(c.v); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+	if got := SingleLine().Sprint(celsius(1)); got != "1" {
+		t.Errorf("注册只影响对应的打印器，另一个打印器输出 %s, want 1", got)
+	}
+}
+
+func TestIndentAndColor(t *testing.T) {
+	v := struct {
+		Name string
+		Tags []string
+		Ok   bool
+	}{"go", []string{"a"}, true}
+
+	want := `struct { Name string; Tags []string; Ok bool }{
+  Name: "go",
+  Tags: []string{
+    "a",
+  },
+  Ok: true,
+}`
+	// Color 为 false 时不输出任何转义序列
+	if got := New().Sprint(v); got != want {
+		t.Errorf("多行输出:\n got %s\nwant %s", got, want)
+	}
+
+	colored := Colored().Sprint(v)
+	if plain := strings.NewReplacer(
+		colorReset, "", colorString, "", colorNumber, "", colorConst, "", colorType, "",
+	).Replace(colored); plain != want {
+		t.Errorf("去掉颜色后应与无颜色输出相同:\n got %s\nwant %s", plain, want)
+	}
+	for _, s := range []string{colorString + `"go"` + colorReset, colorConst + "true" + colorReset} {
+		if !strings.Contains(colored, s) {
+			t.Errorf("彩色输出缺少 %q:\n%s", s, colored)
+		}
+	}
+}
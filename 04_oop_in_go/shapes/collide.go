@@ -0,0 +1,134 @@
+package shapes
+
+import "math"
+
+// ========== 点与图形 ==========
+
+// Contains 判断世界坐标中的点是否落在图形内（含边界）
+func (p Placed) Contains(pt Point) bool {
+	if !p.Bounds().Contains(pt) {
+		return false
+	}
+	local := p.ToLocal(pt)
+	g := localGeometry(p.Shape)
+	switch g.kind {
+	case geomCircle:
+		return math.Hypot(local.X, local.Y) <= g.radius
+	case geomEllipse:
+		if g.a == 0 || g.b == 0 {
+			return false
+		}
+		x, y := local.X/g.a, local.Y/g.b
+		return x*x+y*y <= 1
+	default:
+		return pointInPolygon(local, g.pts)
+	}
+}
+
+// 射线法判断点是否在多边形内，落在边上也算在内
+func pointInPolygon(pt Point, poly []Point) bool {
+	n := len(poly)
+	inside := false
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if segmentDist(pt, a, b) < 1e-9 {
+			return true
+		}
+		if (a.Y > pt.Y) != (b.Y > pt.Y) &&
+			pt.X < (b.X-a.X)*(pt.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ========== 图形与图形 ==========
+
+// Intersects 判断两个图形是否重叠（边界接触也算）。
+// 圆与圆、圆与多边形是精确判断；椭圆会被近似成多边形。
+func Intersects(a, b Placed) bool {
+	if !a.Bounds().Overlaps(b.Bounds()) {
+		return false
+	}
+
+	ga, gb := localGeometry(a.Shape), localGeometry(b.Shape)
+	switch {
+	case ga.kind == geomCircle && gb.kind == geomCircle:
+		ra, rb := ga.radius*math.Abs(a.scale()), gb.radius*math.Abs(b.scale())
+		return a.Pos.Dist(b.Pos) <= ra+rb
+	case ga.kind == geomCircle:
+		return circleHitsPolygon(a.Pos, ga.radius*math.Abs(a.scale()), b.worldPolygon())
+	case gb.kind == geomCircle:
+		return circleHitsPolygon(b.Pos, gb.radius*math.Abs(b.scale()), a.worldPolygon())
+	default:
+		return polygonsIntersect(a.worldPolygon(), b.worldPolygon())
+	}
+}
+
+func circleHitsPolygon(center Point, radius float64, poly []Point) bool {
+	if pointInPolygon(center, poly) {
+		return true
+	}
+	for i := range poly {
+		if segmentDist(center, poly[i], poly[(i+1)%len(poly)]) <= radius {
+			return true
+		}
+	}
+	return false
+}
+
+func polygonsIntersect(p, q []Point) bool {
+	if len(p) == 0 || len(q) == 0 {
+		return false
+	}
+	// 任意两条边相交
+	for i := range p {
+		a1, a2 := p[i], p[(i+1)%len(p)]
+		for j := range q {
+			if segmentsIntersect(a1, a2, q[j], q[(j+1)%len(q)]) {
+				return true
+			}
+		}
+	}
+	// 边不相交时，只可能是一个完全包含另一个
+	return pointInPolygon(p[0], q) || pointInPolygon(q[0], p)
+}
+
+// ========== 线段工具 ==========
+
+func cross(o, a, b Point) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
+
+func onSegment(p, a, b Point) bool {
+	return math.Min(a.X, b.X) <= p.X && p.X <= math.Max(a.X, b.X) &&
+		math.Min(a.Y, b.Y) <= p.Y && p.Y <= math.Max(a.Y, b.Y)
+}
+
+func segmentsIntersect(a1, a2, b1, b2 Point) bool {
+	d1 := cross(b1, b2, a1)
+	d2 := cross(b1, b2, a2)
+	d3 := cross(a1, a2, b1)
+	d4 := cross(a1, a2, b2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	// 共线或端点接触
+	return (d1 == 0 && onSegment(a1, b1, b2)) ||
+		(d2 == 0 && onSegment(a2, b1, b2)) ||
+		(d3 == 0 && onSegment(b1, a1, a2)) ||
+		(d4 == 0 && onSegment(b2, a1, a2))
+}
+
+// 点到线段的最短距离
+func segmentDist(p, a, b Point) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lenSq := dx*dx + dy*dy
+	if lenSq == 0 {
+		return p.Dist(a)
+	}
+	t := ((p.X-a.X)*dx + (p.Y-a.Y)*dy) / lenSq
+	t = math.Max(0, math.Min(1, t))
+	return p.Dist(Point{a.X + t*dx, a.Y + t*dy})
+}
@@ -0,0 +1,177 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// ========== 空间索引（均匀网格） ==========
+
+// Grid 把平面划分成边长为 CellSize 的格子，每个图形登记到它包围盒覆盖的所有格子里。
+// 查询时只需检查相关格子中的图形，而不必遍历全部图形。
+// 覆盖格子过多的大图形不登记到格子里，单独存放，每次查询都逐个检查。
+type Grid struct {
+	mu       sync.RWMutex
+	cellSize float64
+	cells    map[cellKey][]int
+	large    []int   // 覆盖超过 maxCellsPerItem 个格子的图形
+	lo, hi   cellKey // 已登记格子的范围，查询时把扫描范围限制在其中
+	items    []Placed
+}
+
+type cellKey struct {
+	X int
+	Y int
+}
+
+// 一个图形最多登记到这么多格子里，超过时改为放进 large
+const maxCellsPerItem = 1024
+
+// 格子坐标的绝对值上限，保证转换成 int 不会溢出
+const maxCellCoord = 1 << 40
+
+func NewGrid(cellSize float64) *Grid {
+	if cellSize <= 0 || math.IsNaN(cellSize) || math.IsInf(cellSize, 0) {
+		cellSize = 1
+	}
+	return &Grid{cellSize: cellSize, cells: make(map[cellKey][]int)}
+}
+
+// Insert 登记图形，返回它在索引中的编号；包围盒含 NaN 或无穷大时返回 ErrInvalidGeometry
+func (g *Grid) Insert(p Placed) (int, error) {
+	box := p.Bounds()
+	if !box.finite() {
+		return -1, invalid("包围盒 %v 不是有限数字", box)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := len(g.items)
+	g.items = append(g.items, p)
+	x0, y0, x1, y1 := g.cellRange(box)
+	if (x1-x0+1)*(y1-y0+1) > maxCellsPerItem || max(-x0, -y0, x1, y1) > maxCellCoord {
+		g.large = append(g.large, id)
+		return id, nil
+	}
+	lo, hi := cellKey{int(x0), int(y0)}, cellKey{int(x1), int(y1)}
+	if len(g.cells) == 0 {
+		g.lo, g.hi = lo, hi
+	} else {
+		g.lo = cellKey{min(g.lo.X, lo.X), min(g.lo.Y, lo.Y)}
+		g.hi = cellKey{max(g.hi.X, hi.X), max(g.hi.Y, hi.Y)}
+	}
+	for x := lo.X; x <= hi.X; x++ {
+		for y := lo.Y; y <= hi.Y; y++ {
+			k := cellKey{x, y}
+			g.cells[k] = append(g.cells[k], id)
+		}
+	}
+	return id, nil
+}
+
+// Len 返回已登记的图形数量
+func (g *Grid) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.items)
+}
+
+// Query 返回与矩形区域重叠的图形编号（升序）；区域含 NaN 或无穷大时返回 nil
+func (g *Grid) Query(area AABB) []int {
+	if !area.finite() {
+		return nil
+	}
+	w, h := area.Max.X-area.Min.X, area.Max.Y-area.Min.Y
+	probe := Place(Rectangle{Width: w, Height: h}).
+		Translate(area.Min.X+w/2, area.Min.Y+h/2)
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.collect(area, func(p Placed) bool {
+		return Intersects(p, probe)
+	})
+}
+
+// QueryPoint 返回包含该点的图形编号（升序）
+func (g *Grid) QueryPoint(pt Point) []int {
+	box := AABB{Min: pt, Max: pt}
+	if !box.finite() {
+		return nil
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.collect(box, func(p Placed) bool {
+		return p.Contains(pt)
+	})
+}
+
+// Get 按编号取回图形
+func (g *Grid) Get(id int) Placed {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.items[id]
+}
+
+// 先用格子筛出候选，再用 match 做精确判断；调用方需持有读锁
+func (g *Grid) collect(area AABB, match func(Placed) bool) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	check := func(id int) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		if match(g.items[id]) {
+			ids = append(ids, id)
+		}
+	}
+
+	// 扫描范围限制在已登记的格子内；范围里的格子比已登记的格子还多时，改为遍历已登记的格子
+	x0, y0, x1, y1 := g.cellRange(area)
+	x0, y0 = max(x0, float64(g.lo.X)), max(y0, float64(g.lo.Y))
+	x1, y1 = min(x1, float64(g.hi.X)), min(y1, float64(g.hi.Y))
+	switch {
+	case len(g.cells) == 0 || x0 > x1 || y0 > y1:
+	case (x1-x0+1)*(y1-y0+1) > float64(len(g.cells)):
+		for k, cell := range g.cells {
+			if float64(k.X) >= x0 && float64(k.X) <= x1 && float64(k.Y) >= y0 && float64(k.Y) <= y1 {
+				for _, id := range cell {
+					check(id)
+				}
+			}
+		}
+	default:
+		for x := int(x0); x <= int(x1); x++ {
+			for y := int(y0); y <= int(y1); y++ {
+				for _, id := range g.cells[cellKey{x, y}] {
+					check(id)
+				}
+			}
+		}
+	}
+	for _, id := range g.large {
+		if g.items[id].Bounds().Overlaps(area) {
+			check(id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// cellRange 返回包围盒覆盖的格子坐标范围。用浮点数表示，超出 int 范围也不会溢出
+func (g *Grid) cellRange(box AABB) (x0, y0, x1, y1 float64) {
+	return math.Floor(box.Min.X / g.cellSize), math.Floor(box.Min.Y / g.cellSize),
+		math.Floor(box.Max.X / g.cellSize), math.Floor(box.Max.Y / g.cellSize)
+}
+
+// finite 报告包围盒的坐标是否都是有限数字
+func (b AABB) finite() bool {
+	for _, v := range []float64{b.Min.X, b.Min.Y, b.Max.X, b.Max.Y} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
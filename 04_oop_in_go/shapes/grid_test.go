@@ -0,0 +1,108 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+// 与逐个检查的结果对比
+func TestGridQueryMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	g := NewGrid(5)
+	var all []Placed
+	for range 200 {
+		var s Shape = Circle{Radius: 0.5 + r.Float64()*3}
+		if r.Intn(2) == 0 {
+			s = Rectangle{Width: 1 + r.Float64()*8, Height: 1 + r.Float64()*8}
+		}
+		p := Place(s).Translate(r.Float64()*200-100, r.Float64()*200-100).Rotate(r.Float64() * math.Pi)
+		if _, err := g.Insert(p); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, p)
+	}
+	// 覆盖整个平面的大图形不登记到格子里，也要能查到
+	huge := Place(Rectangle{Width: 1e9, Height: 1e9})
+	if _, err := g.Insert(huge); err != nil {
+		t.Fatal(err)
+	}
+	all = append(all, huge)
+
+	for range 200 {
+		x, y := r.Float64()*240-120, r.Float64()*240-120
+		w, h := r.Float64()*40, r.Float64()*40
+		area := AABB{Min: Point{x, y}, Max: Point{x + w, y + h}}
+		probe := Place(Rectangle{Width: w, Height: h}).Translate(x+w/2, y+h/2)
+		var want []int
+		for id, p := range all {
+			if Intersects(p, probe) {
+				want = append(want, id)
+			}
+		}
+		if got := g.Query(area); !slices.Equal(got, want) {
+			t.Fatalf("Query(%v) = %v, want %v", area, got, want)
+		}
+
+		pt := Point{x, y}
+		want = want[:0]
+		for id, p := range all {
+			if p.Contains(pt) {
+				want = append(want, id)
+			}
+		}
+		if got := g.QueryPoint(pt); !slices.Equal(got, want) && !(len(got) == 0 && len(want) == 0) {
+			t.Fatalf("QueryPoint(%v) = %v, want %v", pt, got, want)
+		}
+	}
+}
+
+// 超大的查询区域和图形不能按格子数循环（否则要循环约 1e11 次）
+func TestGridHugeExtentsAreFast(t *testing.T) {
+	g := NewGrid(1)
+	for i := range 10 {
+		if _, err := g.Insert(Place(Circle{Radius: 1}).Translate(float64(i*10), 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := g.Insert(Place(Circle{Radius: 2e5}).Translate(1e300, -1e300)); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	got := g.Query(AABB{Min: Point{-2e5, -2e5}, Max: Point{2e5, 2e5}})
+	if len(got) != 10 {
+		t.Errorf("Query 返回 %d 个图形，want 10", len(got))
+	}
+	if got := g.Query(AABB{Min: Point{-1e301, -1e301}, Max: Point{1e301, 1e301}}); len(got) != 11 {
+		t.Errorf("覆盖全平面的 Query 返回 %d 个图形，want 11", len(got))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("大范围查询耗时 %v", elapsed)
+	}
+}
+
+func TestGridRejectsNonFiniteBounds(t *testing.T) {
+	g := NewGrid(1)
+	for _, p := range []Placed{
+		Place(Circle{Radius: math.NaN()}),
+		Place(Circle{Radius: 1}).Translate(math.Inf(1), 0),
+		Place(Rectangle{Width: 1, Height: 1}).Rotate(math.NaN()),
+	} {
+		if _, err := g.Insert(p); !errors.Is(err, ErrInvalidGeometry) {
+			t.Errorf("Insert(%v) err = %v, want ErrInvalidGeometry", p, err)
+		}
+	}
+	if g.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", g.Len())
+	}
+	if got := g.Query(AABB{Min: Point{math.NaN(), 0}, Max: Point{1, 1}}); got != nil {
+		t.Errorf("NaN 区域的 Query = %v, want nil", got)
+	}
+	if got := g.QueryPoint(Point{math.Inf(-1), 0}); got != nil {
+		t.Errorf("无穷远点的 QueryPoint = %v, want nil", got)
+	}
+}
@@ -0,0 +1,65 @@
+package shapes
+
+import "math"
+
+// ========== 图形轮廓 ==========
+
+// vertices 返回多边形类图形在局部坐标系中的顶点，
+// 坐标已平移到包围盒左下角为原点。圆、椭圆等曲线图形返回 false。
+func vertices(s Shape) ([]Point, bool) {
+	var pts []Point
+	switch v := s.(type) {
+	case Rectangle:
+		pts = []Point{{0, 0}, {v.Width, 0}, {v.Width, v.Height}, {0, v.Height}}
+	case Triangle:
+		// P0-P1 长度为 SideA，P1-P2 为 SideB，P2-P0 为 SideC（余弦定理求 P2）
+		x := 0.0
+		if v.SideA != 0 {
+			x = (v.SideA*v.SideA + v.SideC*v.SideC - v.SideB*v.SideB) / (2 * v.SideA)
+		}
+		y := math.Sqrt(math.Max(0, v.SideC*v.SideC-x*x))
+		pts = []Point{{0, 0}, {v.SideA, 0}, {x, y}}
+	case Polygon:
+		pts = append([]Point(nil), v.Points...)
+	case RegularNgon:
+		if v.N < 3 {
+			return nil, false
+		}
+		n := float64(v.N)
+		radius := v.Side / (2 * math.Sin(math.Pi/n))
+		// 起始角让底边保持水平
+		for k := 0; k < v.N; k++ {
+			theta := -math.Pi/2 + math.Pi/n + 2*math.Pi*float64(k)/n
+			pts = append(pts, Point{radius * math.Cos(theta), radius * math.Sin(theta)})
+		}
+	default:
+		return nil, false
+	}
+	return normalize(pts), true
+}
+
+// 把顶点平移到包围盒左下角为原点
+func normalize(pts []Point) []Point {
+	if len(pts) == 0 {
+		return pts
+	}
+	minX, minY := pts[0].X, pts[0].Y
+	for _, p := range pts[1:] {
+		minX = math.Min(minX, p.X)
+		minY = math.Min(minY, p.Y)
+	}
+	out := make([]Point, len(pts))
+	for i, p := range pts {
+		out[i] = Point{p.X - minX, p.Y - minY}
+	}
+	return out
+}
+
+// 顶点的包围盒尺寸
+func extent(pts []Point) (w, h float64) {
+	for _, p := range pts {
+		w = math.Max(w, p.X)
+		h = math.Max(h, p.Y)
+	}
+	return w, h
+}
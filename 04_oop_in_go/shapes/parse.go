@@ -0,0 +1,177 @@
+package shapes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ========== 文本格式 ==========
+
+// Parse 解析一行文本描述，例如：
+//
+//	circle r=5
+//	rectangle width=4 height=5
+//	polygon points=0,0;4,0;4,3
+func (r *Registry) Parse(spec string) (Shape, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("图形描述为空")
+	}
+
+	params := make(Params)
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("参数格式错误: %q（应为 key=value）", field)
+		}
+		params[strings.ToLower(key)] = value
+	}
+	return r.Build(fields[0], params)
+}
+
+// ParseText 逐行解析文本，空行和 # 开头的注释行会被跳过
+func (r *Registry) ParseText(reader io.Reader) ([]Shape, error) {
+	var shapes []Shape
+	scanner := bufio.NewScanner(reader)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		shape, err := r.Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", lineNo, err)
+		}
+		shapes = append(shapes, shape)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return shapes, nil
+}
+
+// ========== JSON 格式 ==========
+
+// ParseJSON 解析单个 JSON 对象或 JSON 数组，例如：
+//
+//	{"type":"rectangle","width":4,"height":5}
+//	[{"type":"circle","r":5},{"type":"polygon","points":[[0,0],[4,0],[4,3]]}]
+func (r *Registry) ParseJSON(data []byte) ([]Shape, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		shape, err := r.parseJSONObject(data)
+		if err != nil {
+			return nil, err
+		}
+		return []Shape{shape}, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("JSON 格式错误: %w", err)
+	}
+	shapes := make([]Shape, 0, len(items))
+	for i, item := range items {
+		shape, err := r.parseJSONObject(item)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个图形: %w", i+1, err)
+		}
+		shapes = append(shapes, shape)
+	}
+	return shapes, nil
+}
+
+func (r *Registry) parseJSONObject(data []byte) (Shape, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("JSON 格式错误: %w", err)
+	}
+
+	name, ok := fields["type"].(string)
+	if !ok {
+		return nil, fmt.Errorf("缺少字符串字段 type")
+	}
+	delete(fields, "type")
+
+	params := make(Params, len(fields))
+	for key, value := range fields {
+		raw, err := jsonValueToParam(value)
+		if err != nil {
+			return nil, fmt.Errorf("字段 %s: %w", key, err)
+		}
+		params[strings.ToLower(key)] = raw
+	}
+	return r.Build(name, params)
+}
+
+// 把 JSON 值转成与文本格式相同的字符串表示
+func jsonValueToParam(value any) (string, error) {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case string:
+		return v, nil
+	case []any:
+		// 顶点数组：[[x1,y1],[x2,y2],...] -> "x1,y1;x2,y2"
+		pairs := make([]string, 0, len(v))
+		for _, item := range v {
+			xy, ok := item.([]any)
+			if !ok || len(xy) != 2 {
+				return "", fmt.Errorf("顶点应为 [x, y] 数组")
+			}
+			x, okX := xy[0].(float64)
+			y, okY := xy[1].(float64)
+			if !okX || !okY {
+				return "", fmt.Errorf("顶点坐标必须是数字")
+			}
+			pairs = append(pairs, strconv.FormatFloat(x, 'g', -1, 64)+","+strconv.FormatFloat(y, 'g', -1, 64))
+		}
+		return strings.Join(pairs, ";"), nil
+	default:
+		return "", fmt.Errorf("不支持的值类型 %T", value)
+	}
+}
+
+// ========== 文件 ==========
+
+// ParseFile 根据扩展名选择格式：.json 按 JSON 解析，其余按文本逐行解析
+func (r *Registry) ParseFile(path string) ([]Shape, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var shapes []Shape
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		shapes, err = r.ParseJSON(data)
+	} else {
+		shapes, err = r.ParseText(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return shapes, nil
+}
+
+// ========== 默认注册表的快捷函数 ==========
+
+func Parse(spec string) (Shape, error) {
+	return defaultRegistry.Parse(spec)
+}
+
+func ParseJSON(data []byte) ([]Shape, error) {
+	return defaultRegistry.ParseJSON(data)
+}
+
+func ParseFile(path string) ([]Shape, error) {
+	return defaultRegistry.ParseFile(path)
+}
@@ -0,0 +1,230 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ========== 参数 ==========
+
+// Params 是解析出来的图形参数（键 -> 原始文本值）。
+// 文本格式 "circle r=5" 和 JSON 格式 {"type":"circle","r":5} 都会被统一成 Params。
+type Params map[string]string
+
+// Float 按顺序查找第一个存在的键并解析为 float64，用于支持别名（如 r / radius）
+func (p Params) Float(keys ...string) (float64, error) {
+	for _, key := range keys {
+		raw, ok := p[key]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, fmt.Errorf("参数 %s 不是合法数字: %q", key, raw)
+		}
+		return v, nil
+	}
+	return 0, fmt.Errorf("缺少参数 %s", strings.Join(keys, "/"))
+}
+
+// Int 与 Float 类似，但要求是整数
+func (p Params) Int(keys ...string) (int, error) {
+	for _, key := range keys {
+		raw, ok := p[key]
+		if !ok {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return 0, fmt.Errorf("参数 %s 不是合法整数: %q", key, raw)
+		}
+		return v, nil
+	}
+	return 0, fmt.Errorf("缺少参数 %s", strings.Join(keys, "/"))
+}
+
+// Points 解析顶点列表，格式为 "x1,y1;x2,y2;..."
+func (p Params) Points(key string) ([]Point, error) {
+	raw, ok := p[key]
+	if !ok {
+		return nil, fmt.Errorf("缺少参数 %s", key)
+	}
+	var points []Point
+	for _, pair := range strings.Split(raw, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		xy := strings.Split(pair, ",")
+		if len(xy) != 2 {
+			return nil, fmt.Errorf("顶点格式错误: %q（应为 x,y）", pair)
+		}
+		x, errX := strconv.ParseFloat(strings.TrimSpace(xy[0]), 64)
+		y, errY := strconv.ParseFloat(strings.TrimSpace(xy[1]), 64)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("顶点坐标不是合法数字: %q", pair)
+		}
+		points = append(points, Point{X: x, Y: y})
+	}
+	return points, nil
+}
+
+// ========== 注册表 ==========
+
+// Constructor 根据参数构造一个图形
+type Constructor func(p Params) (Shape, error)
+
+var ErrUnknownShape = errors.New("未知的图形类型")
+
+// Registry 保存图形类型名到构造函数的映射（类似 Java 的工厂 + SPI 插件）
+type Registry struct {
+	mu           sync.RWMutex
+	constructors map[string]Constructor
+}
+
+func NewRegistry() *Registry {
+	return &Registry{constructors: make(map[string]Constructor)}
+}
+
+// Register 注册一个图形类型，重复注册同名类型会返回错误
+func (r *Registry) Register(name string, ctor Constructor) error {
+	name = normalizeName(name)
+	if name == "" || ctor == nil {
+		return fmt.Errorf("图形类型名和构造函数不能为空")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.constructors[name]; exists {
+		return fmt.Errorf("图形类型 %s 已注册", name)
+	}
+	r.constructors[name] = ctor
+	return nil
+}
+
+// Names 返回已注册的类型名（已排序）
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.constructors))
+	for name := range r.constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build 用已注册的构造函数创建图形，类型名与 Register 一样忽略大小写和首尾空白
+func (r *Registry) Build(name string, p Params) (Shape, error) {
+	r.mu.RLock()
+	ctor, ok := r.constructors[normalizeName(name)]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownShape, name)
+	}
+	shape, err := ctor(p)
+	if err != nil {
+		return nil, fmt.Errorf("构造 %s 失败: %w", name, err)
+	}
+	return shape, nil
+}
+
+// normalizeName 统一类型名的写法，Register 和 Build 必须使用同一规则
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ========== 默认注册表 ==========
+
+var defaultRegistry = NewRegistry()
+
+// Default 返回包级别的默认注册表，内置图形都注册在这里
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Register 向默认注册表注册图形类型
+func Register(name string, ctor Constructor) error {
+	return defaultRegistry.Register(name, ctor)
+}
+
+// 内置图形在包初始化时注册
+func init() {
+	mustRegister("circle", func(p Params) (Shape, error) {
+		r, err := p.Float("r", "radius")
+		if err != nil {
+			return nil, err
+		}
+		return NewCircle(r)
+	})
+
+	mustRegister("rectangle", func(p Params) (Shape, error) {
+		w, err := p.Float("w", "width")
+		if err != nil {
+			return nil, err
+		}
+		h, err := p.Float("h", "height")
+		if err != nil {
+			return nil, err
+		}
+		return NewRectangle(w, h)
+	})
+
+	mustRegister("triangle", func(p Params) (Shape, error) {
+		a, err := p.Float("a", "side_a")
+		if err != nil {
+			return nil, err
+		}
+		b, err := p.Float("b", "side_b")
+		if err != nil {
+			return nil, err
+		}
+		c, err := p.Float("c", "side_c")
+		if err != nil {
+			return nil, err
+		}
+		return NewTriangle(a, b, c)
+	})
+
+	mustRegister("polygon", func(p Params) (Shape, error) {
+		points, err := p.Points("points")
+		if err != nil {
+			return nil, err
+		}
+		return NewPolygon(points)
+	})
+
+	mustRegister("ellipse", func(p Params) (Shape, error) {
+		a, err := p.Float("a", "rx")
+		if err != nil {
+			return nil, err
+		}
+		b, err := p.Float("b", "ry")
+		if err != nil {
+			return nil, err
+		}
+		return NewEllipse(a, b)
+	})
+
+	mustRegister("ngon", func(p Params) (Shape, error) {
+		n, err := p.Int("n", "sides")
+		if err != nil {
+			return nil, err
+		}
+		side, err := p.Float("s", "side")
+		if err != nil {
+			return nil, err
+		}
+		return NewRegularNgon(n, side)
+	})
+}
+
+func mustRegister(name string, ctor Constructor) {
+	if err := defaultRegistry.Register(name, ctor); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,197 @@
+// Package shapes 是第04节图形练习的进阶版本：
+// 提供可注册的图形类型，并支持从文本或 JSON 解析出 Shape。
+package shapes
+
+import (
+	"fmt"
+	"math"
+)
+
+// ========== 图形接口 ==========
+
+// Shape 与 exercise.go 中的 Shape 方法集完全相同，
+// 因此这里的图形也能直接传给练习里的 TotalArea、CompareAreas。
+type Shape interface {
+	Area() float64
+	Perimeter() float64
+	GetName() string
+}
+
+// Point 表示平面上的一个点
+type Point struct {
+	X float64
+	Y float64
+}
+
+// 两点之间的距离
+func (p Point) Dist(q Point) float64 {
+	return math.Hypot(p.X-q.X, p.Y-q.Y)
+}
+
+// ========== 圆形 ==========
+
+type Circle struct {
+	Radius float64
+}
+
+func (c Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+func (c Circle) Perimeter() float64 {
+	return 2 * math.Pi * c.Radius
+}
+
+func (c Circle) GetName() string {
+	return "圆形"
+}
+
+// ========== 矩形 ==========
+
+type Rectangle struct {
+	Width  float64
+	Height float64
+}
+
+func (r Rectangle) Area() float64 {
+	return r.Width * r.Height
+}
+
+func (r Rectangle) Perimeter() float64 {
+	return 2 * (r.Width + r.Height)
+}
+
+func (r Rectangle) GetName() string {
+	return "矩形"
+}
+
+// ========== 三角形 ==========
+
+type Triangle struct {
+	SideA float64
+	SideB float64
+	SideC float64
+}
+
+// 直接套用海伦公式时，针状三角形（一条边极短）的 s-a 会发生严重的相减抵消。
+// 这里使用 Kahan 的稳定写法：先把边排序为 a >= b >= c，再按固定的括号顺序计算。
+func (t Triangle) Area() float64 {
+	a, b, c := t.SideA, t.SideB, t.SideC
+	if a < b {
+		a, b = b, a
+	}
+	if b < c {
+		b, c = c, b
+	}
+	if a < b {
+		a, b = b, a
+	}
+	product := (a + (b + c)) * (c - (a - b)) * (c + (a - b)) * (a + (b - c))
+	if product <= 0 {
+		return 0 // 退化三角形
+	}
+	return math.Sqrt(product) / 4
+}
+
+func (t Triangle) Perimeter() float64 {
+	return t.SideA + t.SideB + t.SideC
+}
+
+func (t Triangle) GetName() string {
+	return "三角形"
+}
+
+// ========== 多边形 ==========
+
+// Polygon 由按顺序排列的顶点组成（首尾自动相连）
+type Polygon struct {
+	Points []Point
+}
+
+// 鞋带公式（Shoelace formula）
+func (p Polygon) Area() float64 {
+	n := len(p.Points)
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		a, b := p.Points[i], p.Points[(i+1)%n]
+		sum += a.X*b.Y - b.X*a.Y
+	}
+	return math.Abs(sum) / 2
+}
+
+func (p Polygon) Perimeter() float64 {
+	n := len(p.Points)
+	total := 0.0
+	for i := 0; i < n; i++ {
+		total += p.Points[i].Dist(p.Points[(i+1)%n])
+	}
+	return total
+}
+
+func (p Polygon) GetName() string {
+	return "多边形"
+}
+
+// ========== 椭圆 ==========
+
+// Ellipse 的 A、B 分别是两个半轴长
+type Ellipse struct {
+	A float64
+	B float64
+}
+
+func (e Ellipse) Area() float64 {
+	return math.Pi * e.A * e.B
+}
+
+// 椭圆周长没有初等公式，这里使用 Ramanujan 第二近似公式
+func (e Ellipse) Perimeter() float64 {
+	sum := e.A + e.B
+	if sum == 0 {
+		return 0
+	}
+	h := (e.A - e.B) * (e.A - e.B) / (sum * sum)
+	return math.Pi * sum * (1 + 3*h/(10+math.Sqrt(4-3*h)))
+}
+
+func (e Ellipse) GetName() string {
+	return "椭圆"
+}
+
+// ========== 正 N 边形 ==========
+
+// RegularNgon 由边数 N 和边长 Side 描述
+type RegularNgon struct {
+	N    int
+	Side float64
+}
+
+func (r RegularNgon) Area() float64 {
+	n := float64(r.N)
+	return n * r.Side * r.Side / (4 * math.Tan(math.Pi/n))
+}
+
+func (r RegularNgon) Perimeter() float64 {
+	return float64(r.N) * r.Side
+}
+
+func (r RegularNgon) GetName() string {
+	return fmt.Sprintf("正%d边形", r.N)
+}
+
+// ========== 带颜色的图形（组合） ==========
+
+type ColoredShape struct {
+	Shape
+	Color string
+}
+
+// ========== 工具函数 ==========
+
+func TotalArea(shapes ...Shape) float64 {
+	total := 0.0
+	for _, shape := range shapes {
+		total += shape.Area()
+	}
+	return total
+}
@@ -0,0 +1,206 @@
+package shapes
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// quickConfig 使用固定种子，失败时可以复现
+func quickConfig(gen func(args []reflect.Value, r *rand.Rand)) *quick.Config {
+	return &quick.Config{MaxCount: 2000, Rand: rand.New(rand.NewSource(1)), Values: gen}
+}
+
+// 在 [1e-3, 1e3) 之间按对数均匀取值，覆盖不同数量级
+func randLength(r *rand.Rand) float64 {
+	return math.Pow(10, r.Float64()*6-3)
+}
+
+func closeTo(got, want, relTol float64) bool {
+	return math.Abs(got-want) <= relTol*math.Max(math.Abs(want), math.SmallestNonzeroFloat64)
+}
+
+func TestCircleAndRectangleMatchMath(t *testing.T) {
+	gen := func(args []reflect.Value, r *rand.Rand) {
+		args[0] = reflect.ValueOf(randLength(r))
+		args[1] = reflect.ValueOf(randLength(r))
+	}
+	prop := func(a, b float64) bool {
+		c, err := NewCircle(a)
+		if err != nil {
+			return false
+		}
+		rect, err := NewRectangle(a, b)
+		if err != nil {
+			return false
+		}
+		return closeTo(c.Area(), math.Pi*math.Pow(a, 2), 1e-15) &&
+			closeTo(c.Perimeter(), 2*math.Pi*a, 1e-15) &&
+			closeTo(rect.Area(), a*b, 1e-15) &&
+			closeTo(rect.Perimeter(), 2*(a+b), 1e-15)
+	}
+	if err := quick.Check(prop, quickConfig(gen)); err != nil {
+		t.Error(err)
+	}
+}
+
+// 三条边由平面上的三个随机点算出，参考面积用叉积（行列式）计算
+func TestTriangleAreaMatchesCrossProduct(t *testing.T) {
+	gen := func(args []reflect.Value, r *rand.Rand) {
+		for i := range args {
+			args[i] = reflect.ValueOf(Point{X: r.Float64()*200 - 100, Y: r.Float64()*200 - 100})
+		}
+	}
+	prop := func(p, q, s Point) bool {
+		want := math.Abs(cross(p, q, s)) / 2
+		tri, err := NewTriangle(p.Dist(q), q.Dist(s), s.Dist(p))
+		if err != nil {
+			// 三点几乎共线时边长可能刚好不满足严格的三角形不等式
+			return want < 1e-9
+		}
+		// 边长本身带有舍入误差，误差按最长边的平方估计
+		longest := max(p.Dist(q), q.Dist(s), s.Dist(p))
+		return math.Abs(tri.Area()-want) <= 1e-12*longest*longest
+	}
+	if err := quick.Check(prop, quickConfig(gen)); err != nil {
+		t.Error(err)
+	}
+}
+
+// 等腰针状三角形：两腰为 1、底边 c 极短，精确面积为 (c/2)·sqrt(1-(c/2)²)。
+// 直接套用海伦公式时 s-a 的相减抵消会放大舍入误差，Kahan 写法应保持相对误差很小。
+func TestNeedleTriangleArea(t *testing.T) {
+	gen := func(args []reflect.Value, r *rand.Rand) {
+		args[0] = reflect.ValueOf(math.Pow(10, -1-r.Float64()*11)) // [1e-12, 1e-1)
+	}
+	prop := func(c float64) bool {
+		tri, err := NewTriangle(1, 1, c)
+		if err != nil {
+			return false
+		}
+		want := c / 2 * math.Sqrt(1-c*c/4)
+		return closeTo(tri.Area(), want, 1e-12)
+	}
+	if err := quick.Check(prop, quickConfig(gen)); err != nil {
+		t.Error(err)
+	}
+
+	// 对比：朴素的海伦公式在同样的输入下达不到这个精度
+	a, b, c := 1.0, 1.0, 1e-10
+	s := (a + b + c) / 2
+	heron := math.Sqrt(s * (s - a) * (s - b) * (s - c))
+	want := c / 2
+	if closeTo(heron, want, 1e-12) {
+		t.Fatalf("海伦公式在针状三角形上应当失真，得到 %g，精确值 %g", heron, want)
+	}
+	if got := (Triangle{SideA: a, SideB: b, SideC: c}).Area(); !closeTo(got, want, 1e-12) {
+		t.Errorf("Area() = %g, want %g", got, want)
+	}
+}
+
+func TestNewTriangleRequiresTriangleInequality(t *testing.T) {
+	gen := func(args []reflect.Value, r *rand.Rand) {
+		for i := range args {
+			args[i] = reflect.ValueOf(randLength(r))
+		}
+	}
+	prop := func(a, b, c float64) bool {
+		_, err := NewTriangle(a, b, c)
+		valid := a < b+c && b < a+c && c < a+b
+		return valid == (err == nil) && (valid || errors.Is(err, ErrInvalidGeometry))
+	}
+	if err := quick.Check(prop, quickConfig(gen)); err != nil {
+		t.Error(err)
+	}
+}
+
+// 正 N 边形的面积公式与按顶点构造的多边形（鞋带公式）结果一致
+func TestRegularNgonMatchesPolygon(t *testing.T) {
+	gen := func(args []reflect.Value, r *rand.Rand) {
+		args[0] = reflect.ValueOf(3 + r.Intn(50))
+		args[1] = reflect.ValueOf(randLength(r))
+	}
+	prop := func(n int, side float64) bool {
+		ngon, err := NewRegularNgon(n, side)
+		if err != nil {
+			return false
+		}
+		radius := side / (2 * math.Sin(math.Pi/float64(n)))
+		points := make([]Point, n)
+		for i := range points {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+			points[i] = Point{X: radius * cos, Y: radius * sin}
+		}
+		poly, err := NewPolygon(points)
+		if err != nil {
+			return false
+		}
+		return closeTo(ngon.Area(), poly.Area(), 1e-9) && closeTo(ngon.Perimeter(), poly.Perimeter(), 1e-9)
+	}
+	if err := quick.Check(prop, quickConfig(gen)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEllipseWithEqualAxesIsCircle(t *testing.T) {
+	gen := func(args []reflect.Value, r *rand.Rand) {
+		args[0] = reflect.ValueOf(randLength(r))
+	}
+	prop := func(r float64) bool {
+		e, err := NewEllipse(r, r)
+		if err != nil {
+			return false
+		}
+		return closeTo(e.Area(), math.Pi*r*r, 1e-15) && closeTo(e.Perimeter(), 2*math.Pi*r, 1e-15)
+	}
+	if err := quick.Check(prop, quickConfig(gen)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConstructorsRejectInvalidLengths(t *testing.T) {
+	for _, v := range []float64{0, -1, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		checks := map[string]error{}
+		_, checks["NewCircle"] = NewCircle(v)
+		_, checks["NewRectangle"] = NewRectangle(1, v)
+		_, checks["NewTriangle"] = NewTriangle(v, 1, 1)
+		_, checks["NewEllipse"] = NewEllipse(v, 1)
+		_, checks["NewRegularNgon"] = NewRegularNgon(5, v)
+		for name, err := range checks {
+			if !errors.Is(err, ErrInvalidGeometry) {
+				t.Errorf("%s(%v) err = %v, want ErrInvalidGeometry", name, v, err)
+			}
+		}
+	}
+	if _, err := NewPolygon([]Point{{0, 0}, {1, 1}, {2, 2}}); !errors.Is(err, ErrInvalidGeometry) {
+		t.Errorf("共线多边形 err = %v, want ErrInvalidGeometry", err)
+	}
+}
+
+// Register 和 Build 对类型名使用同一规则：忽略大小写和首尾空白
+func TestRegistryNormalizesNames(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("  Unit Square ", func(p Params) (Shape, error) { return NewRectangle(1, 1) }); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Names(); !reflect.DeepEqual(got, []string{"unit square"}) {
+		t.Fatalf("Names() = %q, want [unit square]", got)
+	}
+	for _, name := range []string{"unit square", "UNIT SQUARE", " Unit Square\t"} {
+		if s, err := r.Build(name, Params{}); err != nil || s.Area() != 1 {
+			t.Errorf("Build(%q) = %v, %v", name, s, err)
+		}
+	}
+	if err := r.Register("unit square ", func(p Params) (Shape, error) { return NewCircle(1) }); err == nil {
+		t.Error("只差空白和大小写的类型名应当视为重复注册")
+	}
+	if _, err := r.Build("unit  square", Params{}); !errors.Is(err, ErrUnknownShape) {
+		t.Errorf("中间空白不同的类型名 err = %v, want ErrUnknownShape", err)
+	}
+	if s, err := Default().Build(" Circle ", Params{"r": "1"}); err != nil || !closeTo(s.Area(), math.Pi, 1e-12) {
+		t.Errorf("Default().Build(\" Circle \") = %v, %v", s, err)
+	}
+}
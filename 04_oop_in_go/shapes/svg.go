@@ -0,0 +1,206 @@
+package shapes
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// ========== SVG 渲染 ==========
+
+// SVGRenderer 把一组图形按"货架"方式排版后输出为 SVG：
+// 从左到右依次摆放，超出 MaxWidth 就换行，每个图形下方标注名称、面积和周长。
+type SVGRenderer struct {
+	Scale     float64 // 每个长度单位对应的像素数
+	MaxWidth  float64 // 画布最大宽度（像素）
+	Padding   float64 // 图形之间的间距（像素）
+	FontSize  float64 // 标签字号（像素）
+	FillColor string  // 没有指定颜色时的填充色
+}
+
+// NewSVGRenderer 返回一套默认参数
+func NewSVGRenderer() SVGRenderer {
+	return SVGRenderer{
+		Scale:     10,
+		MaxWidth:  800,
+		Padding:   20,
+		FontSize:  12,
+		FillColor: "#cccccc",
+	}
+}
+
+// 常见中文颜色名到 CSS 颜色的映射，其他值（如 red、#ff0000）原样输出
+var colorNames = map[string]string{
+	"红色": "red",
+	"橙色": "orange",
+	"黄色": "yellow",
+	"绿色": "green",
+	"青色": "cyan",
+	"蓝色": "blue",
+	"紫色": "purple",
+	"黑色": "black",
+	"白色": "white",
+	"灰色": "gray",
+	"粉色": "pink",
+}
+
+// 排版后的一个格子
+type cell struct {
+	shape  Shape
+	color  string
+	label  string
+	x, y   float64 // 格子左上角
+	w, h   float64 // 图形本身的像素尺寸
+	width  float64 // 格子宽度（图形与标签取较大者）
+	height float64 // 格子高度（含标签）
+}
+
+// Render 把 shapes 渲染成完整的 SVG 文档写入 w
+func (r SVGRenderer) Render(w io.Writer, shapes []Shape) error {
+	cells := make([]*cell, 0, len(shapes))
+	for _, s := range shapes {
+		cells = append(cells, r.measure(s))
+	}
+	canvasW, canvasH := r.layout(cells)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		num(canvasW), num(canvasH), num(canvasW), num(canvasH))
+	for _, c := range cells {
+		r.drawCell(bw, c)
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// 计算单个图形的尺寸、颜色和标签
+func (r SVGRenderer) measure(s Shape) *cell {
+	c := &cell{color: r.FillColor}
+
+	// 剥开 ColoredShape，取出颜色和真正的图形（支持多层嵌套）
+	for {
+		if cs, ok := s.(ColoredShape); ok {
+			if c.color == r.FillColor && cs.Color != "" {
+				c.color = cs.Color
+			}
+			s = cs.Shape
+			continue
+		}
+		if cs, ok := s.(*ColoredShape); ok && cs != nil {
+			if c.color == r.FillColor && cs.Color != "" {
+				c.color = cs.Color
+			}
+			s = cs.Shape
+			continue
+		}
+		break
+	}
+	if css, ok := colorNames[c.color]; ok {
+		c.color = css
+	}
+	c.shape = s
+
+	c.label = fmt.Sprintf("%s 面积=%.2f 周长=%.2f", s.GetName(), s.Area(), s.Perimeter())
+	c.w, c.h = r.shapeSize(s)
+	c.width = math.Max(c.w, r.textWidth(c.label))
+	c.height = c.h + r.FontSize*2
+	return c
+}
+
+// 图形本身的像素尺寸
+func (r SVGRenderer) shapeSize(s Shape) (float64, float64) {
+	switch v := s.(type) {
+	case Circle:
+		return 2 * v.Radius * r.Scale, 2 * v.Radius * r.Scale
+	case Ellipse:
+		return 2 * v.A * r.Scale, 2 * v.B * r.Scale
+	}
+	if pts, ok := vertices(s); ok {
+		w, h := extent(pts)
+		return w * r.Scale, h * r.Scale
+	}
+	// 未知图形：画一个等面积的正方形
+	side := math.Sqrt(math.Max(0, s.Area())) * r.Scale
+	return side, side
+}
+
+// 估算标签宽度：中文按 1 个字号宽，ASCII 按 0.6 个字号宽
+func (r SVGRenderer) textWidth(text string) float64 {
+	width := 0.0
+	for _, ch := range text {
+		if utf8.RuneLen(ch) > 1 {
+			width += r.FontSize
+		} else {
+			width += r.FontSize * 0.6
+		}
+	}
+	return width
+}
+
+// 货架排版：返回画布尺寸
+func (r SVGRenderer) layout(cells []*cell) (float64, float64) {
+	x, y := r.Padding, r.Padding
+	rowHeight, canvasW := 0.0, 0.0
+	for _, c := range cells {
+		if x > r.Padding && x+c.width+r.Padding > r.MaxWidth {
+			x = r.Padding
+			y += rowHeight + r.Padding
+			rowHeight = 0
+		}
+		c.x, c.y = x, y
+		x += c.width + r.Padding
+		rowHeight = math.Max(rowHeight, c.height)
+		canvasW = math.Max(canvasW, x)
+	}
+	if len(cells) == 0 {
+		return 2 * r.Padding, 2 * r.Padding
+	}
+	return canvasW, y + rowHeight + r.Padding
+}
+
+// 画出一个格子：图形在格子内水平居中，标签在图形下方
+func (r SVGRenderer) drawCell(w io.Writer, c *cell) {
+	left := c.x + (c.width-c.w)/2
+	top := c.y
+	fill := html.EscapeString(c.color)
+	style := fmt.Sprintf(`fill="%s" stroke="black" stroke-width="1"`, fill)
+
+	switch v := c.shape.(type) {
+	case Circle:
+		fmt.Fprintf(w, `  <circle cx="%s" cy="%s" r="%s" %s/>`+"\n",
+			num(left+c.w/2), num(top+c.h/2), num(v.Radius*r.Scale), style)
+	case Ellipse:
+		fmt.Fprintf(w, `  <ellipse cx="%s" cy="%s" rx="%s" ry="%s" %s/>`+"\n",
+			num(left+c.w/2), num(top+c.h/2), num(v.A*r.Scale), num(v.B*r.Scale), style)
+	default:
+		if pts, ok := vertices(c.shape); ok {
+			coords := make([]string, len(pts))
+			for i, p := range pts {
+				// SVG 的 y 轴向下，这里翻转成数学坐标系的朝向
+				coords[i] = num(left+p.X*r.Scale) + "," + num(top+c.h-p.Y*r.Scale)
+			}
+			fmt.Fprintf(w, `  <polygon points="%s" %s/>`+"\n", strings.Join(coords, " "), style)
+		} else {
+			fmt.Fprintf(w, `  <rect x="%s" y="%s" width="%s" height="%s" %s stroke-dasharray="4"/>`+"\n",
+				num(left), num(top), num(c.w), num(c.h), style)
+		}
+	}
+
+	fmt.Fprintf(w, `  <text x="%s" y="%s" font-size="%s" text-anchor="middle">%s</text>`+"\n",
+		num(c.x+c.width/2), num(top+c.h+r.FontSize*1.5), num(r.FontSize), html.EscapeString(c.label))
+}
+
+// 统一的数字格式：保留两位小数并去掉多余的 0，保证输出稳定
+func num(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package shapes
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// go test ./04_oop_in_go/shapes -run SVG -update 重新生成 testdata 中的 golden 文件
+var update = flag.Bool("update", false, "重新生成 golden 文件")
+
+// blob 是渲染器不认识的图形，按等面积的虚线正方形绘制
+type blob struct{}
+
+func (blob) Area() float64      { return 9 }
+func (blob) Perimeter() float64 { return 12 }
+func (blob) GetName() string    { return "色块 <自定义>" }
+
+func TestSVGRendererGolden(t *testing.T) {
+	parsed, err := ParseFile(filepath.Join("testdata", "shapes.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	drawList := []Shape{
+		ColoredShape{Shape: parsed[0], Color: "红色"},
+		parsed[1],
+		ColoredShape{Shape: ColoredShape{Shape: parsed[2], Color: "蓝色"}, Color: "绿色"}, // 外层颜色优先
+		&ColoredShape{Shape: blob{}, Color: "#ff8800"},
+	}
+	drawList = append(drawList, parsed[3:]...)
+
+	var buf bytes.Buffer
+	if err := NewSVGRenderer().Render(&buf, drawList); err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, filepath.Join("testdata", "shapes.svg"), buf.Bytes())
+}
+
+func TestSVGRendererEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewSVGRenderer().Render(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="40" viewBox="0 0 40 40">` + "\n</svg>\n"
+	if buf.String() != want {
+		t.Errorf("空列表输出:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 golden 文件失败（用 -update 生成）: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("输出与 %s 不一致（确认无误后用 -update 更新）\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
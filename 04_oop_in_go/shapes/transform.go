@@ -0,0 +1,210 @@
+package shapes
+
+import "math"
+
+// ========== 带位置的图形 ==========
+
+// Placed 把一个图形放到平面上：先按 Factor 等比缩放、再绕自身中心旋转 Angle（弧度），
+// 最后把中心移动到 Pos。图形的局部坐标系以它的包围盒中心为原点。
+//
+// Placed 嵌入了 Shape，并覆盖 Area/Perimeter 以反映缩放，
+// 所以仍然可以传给 TotalArea、CompareAreas 等只认识 Shape 的函数。
+type Placed struct {
+	Shape
+	Pos    Point
+	Angle  float64
+	Factor float64
+}
+
+// Place 把图形放在原点，不旋转、不缩放
+func Place(s Shape) Placed {
+	if p, ok := s.(Placed); ok {
+		return p
+	}
+	return Placed{Shape: s, Factor: 1}
+}
+
+// Translate 平移
+func (p Placed) Translate(dx, dy float64) Placed {
+	p.Pos.X += dx
+	p.Pos.Y += dy
+	return p
+}
+
+// Rotate 绕图形自身中心旋转（弧度，逆时针为正）
+func (p Placed) Rotate(theta float64) Placed {
+	p.Angle += theta
+	return p
+}
+
+// Scale 以图形自身中心等比缩放
+func (p Placed) Scale(k float64) Placed {
+	p.Factor = p.scale() * k
+	return p
+}
+
+// 零值 Placed 的 Factor 为 0，按 1 处理
+func (p Placed) scale() float64 {
+	if p.Factor == 0 {
+		return 1
+	}
+	return p.Factor
+}
+
+func (p Placed) Area() float64 {
+	k := p.scale()
+	return p.Shape.Area() * k * k
+}
+
+func (p Placed) Perimeter() float64 {
+	return p.Shape.Perimeter() * math.Abs(p.scale())
+}
+
+// ToWorld 把局部坐标转换为世界坐标
+func (p Placed) ToWorld(local Point) Point {
+	k := p.scale()
+	sin, cos := math.Sincos(p.Angle)
+	x, y := local.X*k, local.Y*k
+	return Point{X: p.Pos.X + x*cos - y*sin, Y: p.Pos.Y + x*sin + y*cos}
+}
+
+// ToLocal 把世界坐标转换为局部坐标（ToWorld 的逆变换）
+func (p Placed) ToLocal(world Point) Point {
+	k := p.scale()
+	sin, cos := math.Sincos(p.Angle)
+	x, y := world.X-p.Pos.X, world.Y-p.Pos.Y
+	return Point{X: (x*cos + y*sin) / k, Y: (-x*sin + y*cos) / k}
+}
+
+// ========== 轴对齐包围盒 ==========
+
+// AABB 是轴对齐包围盒（Axis-Aligned Bounding Box）
+type AABB struct {
+	Min Point
+	Max Point
+}
+
+// Overlaps 判断两个包围盒是否相交（边界接触也算）
+func (b AABB) Overlaps(o AABB) bool {
+	return b.Min.X <= o.Max.X && o.Min.X <= b.Max.X &&
+		b.Min.Y <= o.Max.Y && o.Min.Y <= b.Max.Y
+}
+
+// Contains 判断点是否在包围盒内
+func (b AABB) Contains(pt Point) bool {
+	return pt.X >= b.Min.X && pt.X <= b.Max.X && pt.Y >= b.Min.Y && pt.Y <= b.Max.Y
+}
+
+// Bounds 返回图形在世界坐标系中的包围盒
+func (p Placed) Bounds() AABB {
+	g := localGeometry(p.Shape)
+	k := math.Abs(p.scale())
+	switch g.kind {
+	case geomCircle:
+		r := g.radius * k
+		return AABB{Min: Point{p.Pos.X - r, p.Pos.Y - r}, Max: Point{p.Pos.X + r, p.Pos.Y + r}}
+	case geomEllipse:
+		// 旋转椭圆的包围盒半宽/半高有解析解
+		sin, cos := math.Sincos(p.Angle)
+		a, b := g.a*k, g.b*k
+		hw := math.Hypot(a*cos, b*sin)
+		hh := math.Hypot(a*sin, b*cos)
+		return AABB{Min: Point{p.Pos.X - hw, p.Pos.Y - hh}, Max: Point{p.Pos.X + hw, p.Pos.Y + hh}}
+	default:
+		return boundsOf(p.worldPolygon())
+	}
+}
+
+func boundsOf(pts []Point) AABB {
+	if len(pts) == 0 {
+		return AABB{}
+	}
+	box := AABB{Min: pts[0], Max: pts[0]}
+	for _, pt := range pts[1:] {
+		box.Min.X = math.Min(box.Min.X, pt.X)
+		box.Min.Y = math.Min(box.Min.Y, pt.Y)
+		box.Max.X = math.Max(box.Max.X, pt.X)
+		box.Max.Y = math.Max(box.Max.Y, pt.Y)
+	}
+	return box
+}
+
+// ========== 局部几何 ==========
+
+type geomKind int
+
+const (
+	geomPolygon geomKind = iota
+	geomCircle
+	geomEllipse
+)
+
+// geometry 是图形在局部坐标系（包围盒中心为原点）中的几何描述
+type geometry struct {
+	kind   geomKind
+	radius float64 // 圆
+	a, b   float64 // 椭圆半轴
+	pts    []Point // 多边形顶点
+}
+
+// 椭圆参与相交检测时近似成的多边形边数
+const ellipseSegments = 64
+
+func localGeometry(s Shape) geometry {
+	s = unwrapColor(s)
+	switch v := s.(type) {
+	case Circle:
+		return geometry{kind: geomCircle, radius: v.Radius}
+	case Ellipse:
+		return geometry{kind: geomEllipse, a: v.A, b: v.B}
+	}
+
+	pts, ok := vertices(s)
+	if !ok {
+		// 未知图形：与 SVG 渲染一致，按等面积正方形处理
+		side := math.Sqrt(math.Max(0, s.Area()))
+		pts = []Point{{0, 0}, {side, 0}, {side, side}, {0, side}}
+	}
+	w, h := extent(pts)
+	centered := make([]Point, len(pts))
+	for i, pt := range pts {
+		centered[i] = Point{pt.X - w/2, pt.Y - h/2}
+	}
+	return geometry{kind: geomPolygon, pts: centered}
+}
+
+// 剥开 ColoredShape，颜色不影响几何
+func unwrapColor(s Shape) Shape {
+	for {
+		switch v := s.(type) {
+		case ColoredShape:
+			s = v.Shape
+		case *ColoredShape:
+			s = v.Shape
+		default:
+			return s
+		}
+	}
+}
+
+// worldPolygon 返回世界坐标下的多边形顶点（椭圆会被近似，圆返回 nil）
+func (p Placed) worldPolygon() []Point {
+	g := localGeometry(p.Shape)
+	var local []Point
+	switch g.kind {
+	case geomCircle:
+		return nil
+	case geomEllipse:
+		for i := 0; i < ellipseSegments; i++ {
+			theta := 2 * math.Pi * float64(i) / ellipseSegments
+			local = append(local, Point{g.a * math.Cos(theta), g.b * math.Sin(theta)})
+		}
+	default:
+		local = g.pts
+	}
+	world := make([]Point, len(local))
+	for i, pt := range local {
+		world[i] = p.ToWorld(pt)
+	}
+	return world
+}
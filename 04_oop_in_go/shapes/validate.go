@@ -0,0 +1,100 @@
+package shapes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ========== 几何校验 ==========
+
+// ErrInvalidGeometry 表示尺寸不合法（负数、NaN、不满足三角形不等式等），
+// 可以用 errors.Is 判断
+var ErrInvalidGeometry = errors.New("几何参数不合法")
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidGeometry, fmt.Sprintf(format, args...))
+}
+
+// 检查长度是否为有限正数
+func checkLength(name string, v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return invalid("%s 必须是有限数字，实际为 %v", name, v)
+	}
+	if v <= 0 {
+		return invalid("%s 必须大于 0，实际为 %v", name, v)
+	}
+	return nil
+}
+
+// ========== 带校验的构造函数 ==========
+
+func NewCircle(radius float64) (Circle, error) {
+	if err := checkLength("半径", radius); err != nil {
+		return Circle{}, err
+	}
+	return Circle{Radius: radius}, nil
+}
+
+func NewRectangle(width, height float64) (Rectangle, error) {
+	if err := checkLength("宽", width); err != nil {
+		return Rectangle{}, err
+	}
+	if err := checkLength("高", height); err != nil {
+		return Rectangle{}, err
+	}
+	return Rectangle{Width: width, Height: height}, nil
+}
+
+// NewTriangle 除了检查边长为正数，还要求满足三角形不等式（任意两边之和大于第三边）
+func NewTriangle(a, b, c float64) (Triangle, error) {
+	for _, side := range []struct {
+		name  string
+		value float64
+	}{{"边 a", a}, {"边 b", b}, {"边 c", c}} {
+		if err := checkLength(side.name, side.value); err != nil {
+			return Triangle{}, err
+		}
+	}
+	if a >= b+c || b >= a+c || c >= a+b {
+		return Triangle{}, invalid("边长 %v, %v, %v 不满足三角形不等式", a, b, c)
+	}
+	return Triangle{SideA: a, SideB: b, SideC: c}, nil
+}
+
+// NewPolygon 要求至少 3 个顶点、坐标有限，且面积不为 0（顶点不能全部共线）
+func NewPolygon(points []Point) (Polygon, error) {
+	if len(points) < 3 {
+		return Polygon{}, invalid("多边形至少需要 3 个顶点，实际为 %d 个", len(points))
+	}
+	for i, p := range points {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsInf(p.X, 0) || math.IsInf(p.Y, 0) {
+			return Polygon{}, invalid("第 %d 个顶点坐标不是有限数字", i+1)
+		}
+	}
+	poly := Polygon{Points: points}
+	if poly.Area() == 0 {
+		return Polygon{}, invalid("多边形面积为 0（顶点共线）")
+	}
+	return poly, nil
+}
+
+func NewEllipse(a, b float64) (Ellipse, error) {
+	if err := checkLength("半轴 a", a); err != nil {
+		return Ellipse{}, err
+	}
+	if err := checkLength("半轴 b", b); err != nil {
+		return Ellipse{}, err
+	}
+	return Ellipse{A: a, B: b}, nil
+}
+
+func NewRegularNgon(n int, side float64) (RegularNgon, error) {
+	if n < 3 {
+		return RegularNgon{}, invalid("正多边形至少 3 条边，实际为 %d", n)
+	}
+	if err := checkLength("边长", side); err != nil {
+		return RegularNgon{}, err
+	}
+	return RegularNgon{N: n, Side: side}, nil
+}
@@ -0,0 +1,121 @@
+// Package zoo 把第04节的 Speaker/Dog/Cat 示例扩展成一个小型模拟：
+// 动物按种类注册，叫、吃、移动等行为通过接口和装饰器组合，
+// 不需要 DescribeAnimalSwitch 那样的类型开关；给定种子后模拟结果完全确定。
+package zoo
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// ========== 行为接口 ==========
+
+// Speaker 决定动物怎么叫，返回空字符串表示这一轮不出声
+type Speaker interface {
+	Speak(a *Animal, rng *rand.Rand) string
+}
+
+// Eater 决定动物吃什么；返回 ok=false 表示不吃
+type Eater interface {
+	Eat(a *Animal, rng *rand.Rand) (food string, ok bool)
+}
+
+// Mover 决定动物怎么移动，直接修改 a.X、a.Y，返回动作描述
+type Mover interface {
+	Move(a *Animal, rng *rand.Rand) string
+}
+
+// 函数适配器，方便用闭包实现行为
+type (
+	SpeakFunc func(a *Animal, rng *rand.Rand) string
+	EatFunc   func(a *Animal, rng *rand.Rand) (string, bool)
+	MoveFunc  func(a *Animal, rng *rand.Rand) string
+)
+
+func (f SpeakFunc) Speak(a *Animal, rng *rand.Rand) string     { return f(a, rng) }
+func (f EatFunc) Eat(a *Animal, rng *rand.Rand) (string, bool) { return f(a, rng) }
+func (f MoveFunc) Move(a *Animal, rng *rand.Rand) string       { return f(a, rng) }
+
+// ========== 基础行为 ==========
+
+// Sound 总是发出同一种叫声
+type Sound string
+
+func (s Sound) Speak(a *Animal, rng *rand.Rand) string {
+	return string(s)
+}
+
+// Diet 从食谱里随机挑一种
+type Diet []string
+
+func (d Diet) Eat(a *Animal, rng *rand.Rand) (string, bool) {
+	if len(d) == 0 {
+		return "", false
+	}
+	return d[rng.Intn(len(d))], true
+}
+
+// Walk 每次向随机方向走 1~Step 格
+type Walk struct {
+	Verb string // 动作名称，如"跑"、"飞"
+	Step int
+}
+
+var directions = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+func (w Walk) Move(a *Animal, rng *rand.Rand) string {
+	step := 1
+	if w.Step > 1 {
+		step += rng.Intn(w.Step)
+	}
+	d := directions[rng.Intn(len(directions))]
+	a.X += d[0] * step
+	a.Y += d[1] * step
+	return fmt.Sprintf("%s到 (%d,%d)", w.Verb, a.X, a.Y)
+}
+
+// ========== 装饰器 ==========
+
+// Repeat 把叫声重复 n 次
+func Repeat(s Speaker, n int) Speaker {
+	return SpeakFunc(func(a *Animal, rng *rand.Rand) string {
+		sound := s.Speak(a, rng)
+		if sound == "" || n <= 1 {
+			return sound
+		}
+		return strings.Repeat(sound, n)
+	})
+}
+
+// Quiet 以概率 p 保持安静
+func Quiet(s Speaker, p float64) Speaker {
+	return SpeakFunc(func(a *Animal, rng *rand.Rand) string {
+		if rng.Float64() < p {
+			return ""
+		}
+		return s.Speak(a, rng)
+	})
+}
+
+// Picky 只吃允许的食物，其他的拒绝
+func Picky(e Eater, allowed ...string) Eater {
+	return EatFunc(func(a *Animal, rng *rand.Rand) (string, bool) {
+		food, ok := e.Eat(a, rng)
+		if !ok || !slices.Contains(allowed, food) {
+			return food, false
+		}
+		return food, true
+	})
+}
+
+// Lazy 体力低于 minEnergy 时原地休息
+func Lazy(m Mover, minEnergy int) Mover {
+	return MoveFunc(func(a *Animal, rng *rand.Rand) string {
+		if a.Energy < minEnergy {
+			return ""
+		}
+		return m.Move(a, rng)
+	})
+}
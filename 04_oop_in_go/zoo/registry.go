@@ -0,0 +1,128 @@
+package zoo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ========== 种类与动物 ==========
+
+// Kind 描述一种动物：显示名称加上三种行为，nil 表示没有这种行为
+type Kind struct {
+	Name  string // 注册名，如 "dog"
+	Label string // 显示名，如 "狗"
+	Speak Speaker
+	Eat   Eater
+	Move  Mover
+}
+
+// Animal 是一只具体的动物，状态由模拟更新
+type Animal struct {
+	Name   string
+	Kind   *Kind
+	X, Y   int
+	Energy int // 0~100，移动消耗，吃东西恢复
+	Hunger int // 0~100，每轮增加，吃东西清零
+}
+
+// Describe 取代类型开关：描述信息来自种类本身
+func (a *Animal) Describe() string {
+	return fmt.Sprintf("这是一只%s，名字是 %s", a.Kind.Label, a.Name)
+}
+
+func (a *Animal) String() string {
+	return fmt.Sprintf("%s(%s)", a.Name, a.Kind.Label)
+}
+
+// ========== 注册表 ==========
+
+var ErrUnknownKind = errors.New("未知的动物种类")
+
+// Registry 按名称保存动物种类，并发安全
+type Registry struct {
+	mu    sync.RWMutex
+	kinds map[string]*Kind
+}
+
+func NewRegistry() *Registry {
+	return &Registry{kinds: make(map[string]*Kind)}
+}
+
+// Register 注册种类，同名种类会被覆盖
+func (r *Registry) Register(k Kind) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.kinds[k.Name] = &k
+}
+
+// Clone 返回注册表的副本，在副本上注册不会影响原来的注册表
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := NewRegistry()
+	for name, k := range r.kinds {
+		c.kinds[name] = k
+	}
+	return c
+}
+
+// Names 返回已注册的种类名（已排序）
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.kinds))
+	for name := range r.kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New 创建指定种类的动物，初始体力 100、饥饿 0
+func (r *Registry) New(kind, name string) (*Animal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	k, ok := r.kinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+	}
+	return &Animal{Name: name, Kind: k, Energy: 100}, nil
+}
+
+var defaultRegistry = NewRegistry()
+
+// Default 返回预先注册了 dog、cat、duck 的全局注册表
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Register 向全局注册表注册种类
+func Register(k Kind) {
+	defaultRegistry.Register(k)
+}
+
+func init() {
+	Register(Kind{
+		Name:  "dog",
+		Label: "狗",
+		Speak: Sound("汪汪汪"),
+		Eat:   Diet{"骨头", "狗粮", "肉"},
+		Move:  Walk{Verb: "跑", Step: 3},
+	})
+	Register(Kind{
+		Name:  "cat",
+		Label: "猫",
+		Speak: Quiet(Sound("喵喵喵"), 0.5),
+		Eat:   Picky(Diet{"鱼", "猫粮", "青菜"}, "鱼", "猫粮"),
+		Move:  Lazy(Walk{Verb: "走", Step: 2}, 60),
+	})
+	Register(Kind{
+		Name:  "duck",
+		Label: "鸭子",
+		Speak: Sound("嘎嘎"),
+		Eat:   Diet{"小鱼", "水草"},
+		Move:  Walk{Verb: "飞", Step: 5},
+	})
+}
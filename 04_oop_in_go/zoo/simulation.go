@@ -0,0 +1,127 @@
+package zoo
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// ========== 模拟 ==========
+
+// 每轮的数值规则
+const (
+	hungerPerTick = 10 // 每轮增加的饥饿
+	hungryAt      = 50 // 饥饿达到该值时优先吃东西
+	moveCost      = 15 // 每次移动消耗的体力
+	restGain      = 10 // 休息恢复的体力
+	eatGain       = 30 // 吃东西恢复的体力
+)
+
+// Simulation 按轮次推进所有动物。同样的种子、同样的动物加入顺序会得到完全相同的日志。
+type Simulation struct {
+	rng     *rand.Rand
+	animals []*Animal
+	tick    int
+	log     []string
+}
+
+func NewSimulation(seed int64) *Simulation {
+	return &Simulation{rng: rand.New(rand.NewSource(seed))}
+}
+
+// Add 加入动物，每轮按加入顺序行动
+func (s *Simulation) Add(animals ...*Animal) {
+	s.animals = append(s.animals, animals...)
+}
+
+// Run 推进 n 轮，返回这几轮产生的日志
+func (s *Simulation) Run(n int) []string {
+	start := len(s.log)
+	for i := 0; i < n; i++ {
+		s.step()
+	}
+	return s.log[start:]
+}
+
+// Log 返回从开始到现在的全部日志
+func (s *Simulation) Log() []string {
+	return append([]string(nil), s.log...)
+}
+
+// WriteLog 把全部日志逐行写入 w
+func (s *Simulation) WriteLog(w io.Writer) error {
+	for _, line := range s.log {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Simulation) logf(a *Animal, format string, args ...any) {
+	line := fmt.Sprintf("t=%03d %s ", s.tick, a) + fmt.Sprintf(format, args...)
+	s.log = append(s.log, line)
+}
+
+// step 推进一轮：饿了先吃；否则随机选择叫或移动；什么都没做就休息
+func (s *Simulation) step() {
+	s.tick++
+	for _, a := range s.animals {
+		a.Hunger = min(a.Hunger+hungerPerTick, 100)
+		if a.Hunger >= hungryAt && s.eat(a) {
+			continue
+		}
+		acted := false
+		if s.rng.Intn(2) == 0 {
+			acted = s.speak(a)
+		} else {
+			acted = s.move(a)
+		}
+		if !acted {
+			a.Energy = min(a.Energy+restGain, 100)
+			s.logf(a, "休息 体力=%d", a.Energy)
+		}
+	}
+}
+
+func (s *Simulation) eat(a *Animal) bool {
+	if a.Kind.Eat == nil {
+		return false
+	}
+	food, ok := a.Kind.Eat.Eat(a, s.rng)
+	if !ok {
+		if food != "" {
+			s.logf(a, "不吃%s", food)
+		}
+		return false
+	}
+	a.Hunger = 0
+	a.Energy = min(a.Energy+eatGain, 100)
+	s.logf(a, "吃了%s 体力=%d", food, a.Energy)
+	return true
+}
+
+func (s *Simulation) speak(a *Animal) bool {
+	if a.Kind.Speak == nil {
+		return false
+	}
+	sound := a.Kind.Speak.Speak(a, s.rng)
+	if sound == "" {
+		return false
+	}
+	s.logf(a, "说: %s", sound)
+	return true
+}
+
+func (s *Simulation) move(a *Animal) bool {
+	if a.Kind.Move == nil || a.Energy < moveCost {
+		return false
+	}
+	action := a.Kind.Move.Move(a, s.rng)
+	if action == "" {
+		return false
+	}
+	a.Energy -= moveCost
+	s.logf(a, "%s 体力=%d", action, a.Energy)
+	return true
+}
@@ -0,0 +1,92 @@
+package zoo
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// go test ./04_oop_in_go/zoo -update 重新生成 testdata 中的 golden 文件
+var update = flag.Bool("update", false, "重新生成 golden 文件")
+
+// lessonKinds 返回第04节示例使用的注册表：默认种类加上鹦鹉和柴犬
+func lessonKinds() *Registry {
+	kinds := Default().Clone()
+	kinds.Register(Kind{
+		Name:  "parrot",
+		Label: "鹦鹉",
+		Speak: Repeat(Sound("你好！"), 2),
+		Eat:   Diet{"瓜子", "苹果"},
+		Move:  Walk{Verb: "飞", Step: 2},
+	})
+	kinds.Register(Kind{
+		Name:  "shiba",
+		Label: "柴犬",
+		Speak: SpeakFunc(func(a *Animal, rng *rand.Rand) string {
+			return a.Name + "（柴犬）: 汪汪汪！"
+		}),
+		Eat:  Diet{"狗粮"},
+		Move: Lazy(Walk{Verb: "跑", Step: 2}, 50),
+	})
+	return kinds
+}
+
+// 同样的种子、同样的加入顺序，日志与 golden 文件逐字节一致
+func TestSimulationGolden(t *testing.T) {
+	kinds := lessonKinds()
+	sim := NewSimulation(42)
+	for _, spec := range [][2]string{{"dog", "旺财"}, {"cat", "小白"}, {"parrot", "波利"}, {"shiba", "小柴"}} {
+		animal, err := kinds.New(spec[0], spec[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sim.Add(animal)
+	}
+	sim.Run(6)
+
+	var buf bytes.Buffer
+	if err := sim.WriteLog(&buf); err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, filepath.Join("testdata", "simulation.golden"), buf.Bytes())
+}
+
+func TestRegistry(t *testing.T) {
+	kinds := lessonKinds()
+	if got, want := kinds.Names(), []string{"cat", "dog", "duck", "parrot", "shiba"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+	// 在副本上注册不影响全局注册表
+	if got, want := Default().Names(), []string{"cat", "dog", "duck"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Default().Names() = %v, want %v", got, want)
+	}
+	if _, err := kinds.New("tiger", "大虎"); !errors.Is(err, ErrUnknownKind) {
+		t.Fatalf("New(tiger) err = %v, want ErrUnknownKind", err)
+	}
+	a, err := kinds.New("parrot", "波利")
+	if err != nil || a.Describe() != "这是一只鹦鹉，名字是 波利" || a.Energy != 100 {
+		t.Fatalf("New(parrot) = %+v, %v", a, err)
+	}
+}
+
+func compareGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 golden 文件失败（用 -update 生成）: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("输出与 %s 不一致（确认无误后用 -update 更新）\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"runtime"
 	"sync"
 	"time"
+
+	"golang_study/06_concurrency_advanced/pipeline"
+	"golang_study/06_concurrency_advanced/workerpool"
 )
 
 // ==================== 示例1：基本 Goroutine ====================
@@ -285,57 +291,56 @@ func demoProducerConsumer() {
 // ==================== 示例12：管道模式 ====================
 
 // 生成数据
-func generate(nums ...int) <-chan int {
-	out := make(chan int)
-	go func() {
-		for _, n := range nums {
-			out <- n
-		}
-		close(out)
-	}()
-	return out
+func generate(ctx context.Context, nums ...int) <-chan int {
+	return pipeline.Generate(ctx, nums...)
 }
 
 // 平方
-func square(in <-chan int) <-chan int {
-	out := make(chan int)
-	go func() {
-		for n := range in {
-			out <- n * n
-		}
-		close(out)
-	}()
-	return out
+func square(ctx context.Context, in <-chan int) <-chan int {
+	return pipeline.Map(ctx, in, func(n int) int { return n * n })
 }
 
 // 过滤偶数
-func filterEven(in <-chan int) <-chan int {
-	out := make(chan int)
-	go func() {
-		for n := range in {
-			if n%2 == 0 {
-				out <- n
-			}
-		}
-		close(out)
-	}()
-	return out
+func filterEven(ctx context.Context, in <-chan int) <-chan int {
+	return pipeline.Filter(ctx, in, func(n int) bool { return n%2 == 0 })
 }
 
 func demoPipeline() {
 	fmt.Println("==================== 示例12：管道模式 ====================")
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// 构建管道: 生成 -> 平方 -> 过滤偶数
-	nums := generate(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
-	squared := square(nums)
-	filtered := filterEven(squared)
+	nums := generate(ctx, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	squared := square(ctx, nums)
+	filtered := filterEven(ctx, squared)
 
 	// 消费结果
 	fmt.Println("管道结果（平方后的偶数）:")
 	for result := range filtered {
 		fmt.Printf("%d ", result)
 	}
-	fmt.Println("\n")
+	fmt.Println()
+
+	// 并行平方：最多 4 个 goroutine 同时计算，结果仍按输入顺序输出
+	slowSquare := func(n int) int {
+		time.Sleep(time.Duration(11-n) * 10 * time.Millisecond) // 越靠前越慢
+		return n * n
+	}
+	fmt.Println("并行平方（ParallelMap，按输入顺序）:")
+	for result := range pipeline.ParallelMap(ctx, generate(ctx, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 4, slowSquare) {
+		fmt.Printf("%d ", result)
+	}
+	fmt.Println()
+
+	// 提前停止消费：只取第一个结果后取消 ctx，三个阶段的 goroutine 都会退出
+	before := runtime.NumGoroutine()
+	early, stop := context.WithCancel(context.Background())
+	first := <-filterEven(early, square(early, generate(early, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)))
+	stop()
+	time.Sleep(10 * time.Millisecond) // 等待各阶段处理取消
+	fmt.Printf("只取第一个结果 %d 后取消，goroutine 数: %d → %d\n\n", first, before, runtime.NumGoroutine())
 }
 
 // ==================== 示例13：Worker 池 ====================
@@ -350,54 +355,62 @@ type Result struct {
 	Sum int
 }
 
-func workerPool(id int, jobs <-chan Job, results chan<- Result, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	for job := range jobs {
-		fmt.Printf("Worker %d: 处理任务 %d\n", id, job.ID)
-		time.Sleep(100 * time.Millisecond) // 模拟处理
+// workerPool 处理一个任务；jobs/results 通道和 WaitGroup 由 workerpool.Pool 管理
+func workerPool(ctx context.Context, job Job) (Result, error) {
+	fmt.Printf("Worker %d: 处理任务 %d\n", workerpool.WorkerID(ctx), job.ID)
+	time.Sleep(100 * time.Millisecond) // 模拟处理
 
-		// 计算字符串长度作为结果
-		result := Result{
-			Job: job,
-			Sum: len(job.Data),
-		}
-		results <- result
-	}
+	// 计算字符串长度作为结果
+	return Result{Job: job, Sum: len(job.Data)}, nil
 }
 
 func demoWorkerPool() {
 	fmt.Println("==================== 示例13：Worker 池 ====================")
 
-	jobs := make(chan Job, 10)
-	results := make(chan Result, 10)
-	var wg sync.WaitGroup
-
-	// 启动 3 个 worker
-	for i := 1; i <= 3; i++ {
-		wg.Add(1)
-		go workerPool(i, jobs, results, &wg)
-	}
+	// 3 个 worker，队列容量 10；Ordered 按提交顺序输出结果
+	pool := workerpool.New(context.Background(), workerPool, workerpool.Options{
+		Workers:   3,
+		QueueSize: 10,
+		Ordered:   true,
+	})
 
 	// 发送任务
 	go func() {
 		for i := 1; i <= 9; i++ {
-			jobs <- Job{ID: i, Data: fmt.Sprintf("Task-%d", i)}
+			pool.Submit(context.Background(), Job{ID: i, Data: fmt.Sprintf("Task-%d", i)})
 		}
-		close(jobs)
+		pool.Shutdown(context.Background())
 	}()
 
-	// 关闭结果 channel
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// 收集结果
+	// 收集结果（按提交顺序）
 	fmt.Println("\n收集结果:")
-	for result := range results {
-		fmt.Printf("任务 %d 完成，结果: %d\n", result.Job.ID, result.Sum)
+	for r := range pool.Results() {
+		if r.Err != nil {
+			fmt.Printf("任务 %d 失败: %v\n", r.Input.ID, r.Err)
+			continue
+		}
+		fmt.Printf("任务 %d 完成，结果: %d（Worker %d）\n", r.Value.Job.ID, r.Value.Sum, r.Worker)
+	}
+
+	// Drain：不再接收新任务，丢弃还没开始的任务，只等正在执行的完成
+	slow := workerpool.New(context.Background(), func(ctx context.Context, n int) (int, error) {
+		time.Sleep(50 * time.Millisecond)
+		return n * n, nil
+	}, workerpool.Options{Workers: 1, QueueSize: 5})
+	for i := 1; i <= 5; i++ {
+		slow.Submit(context.Background(), i)
+	}
+	time.Sleep(20 * time.Millisecond) // 等第 1 个任务开始执行
+	go slow.Drain()
+	done, dropped := 0, 0
+	for r := range slow.Results() {
+		if errors.Is(r.Err, workerpool.ErrDropped) {
+			dropped++
+		} else {
+			done++
+		}
 	}
+	fmt.Printf("Drain: 完成 %d 个，丢弃 %d 个\n", done, dropped)
 	fmt.Println()
 }
 
@@ -1,80 +1,106 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
-	"sync"
+	"sort"
 	"time"
+
+	"golang_study/06_concurrency_advanced/scheduler"
+	"golang_study/06_concurrency_advanced/workerpool"
 )
 
-type Task struct {
-	ID int
-}
+// 任务持久化到磁盘、中断后可继续处理的版本见 06_concurrency_advanced/taskqueue.go
 
-type Result struct {
-	TaskID   int
-	WorkerID int
-	Duration time.Duration
-}
+// Task 带优先级、截止时间和重试次数，由 scheduler 按优先级（带老化）调度
+type Task = scheduler.Task
 
-func worker(id int, tasks <-chan Task, results chan<- Result, wg *sync.WaitGroup) { //只能读 tasks， 只能写 results
-	defer wg.Done() // 确保在函数结束时调用 Done
+var errFlaky = errors.New("模拟的临时故障")
 
-	for task := range tasks {
-		fmt.Printf("Worker-%d: 开始处理任务 %d\n", id, task.ID)
+func worker(ctx context.Context, task Task, attempt int) error {
+	id := workerpool.WorkerID(ctx)
+	fmt.Printf("Worker-%d: 开始处理任务 %d（优先级 %v，第 %d 次）\n", id, task.ID, task.Priority, attempt)
 
-		// 模拟处理时间
-		start := time.Now()
-		processTime := time.Duration(rand.Intn(400)+100) * time.Millisecond
-		time.Sleep(processTime)
-		duration := time.Since(start)
+	// 模拟处理时间
+	start := time.Now()
+	processTime := time.Duration(rand.Intn(400)+100) * time.Millisecond
+	time.Sleep(processTime)
+	duration := time.Since(start)
 
-		results <- Result{
-			TaskID:   task.ID,
-			WorkerID: id,
-			Duration: duration,
-		}
-		fmt.Printf("Worker-%d: 完成任务 %d，耗时 %v\n", id, task.ID, duration)
+	// 20% 的概率失败，由调度器按指数退避重试
+	if rand.Intn(5) == 0 {
+		fmt.Printf("Worker-%d: 任务 %d 失败，耗时 %v\n", id, task.ID, duration)
+		return errFlaky
 	}
+	fmt.Printf("Worker-%d: 完成任务 %d，耗时 %v\n", id, task.ID, duration)
+	return nil
 }
 
-func collector(results <-chan Result, done chan<- struct{}) {
+func collector(reports <-chan scheduler.Report, done chan<- struct{}) {
 	count := 0
-	var totalDuration time.Duration
-	for result := range results {
-		fmt.Printf("Collector: 任务 %d 由 Worker-%d 完成，耗时 %v\n", result.TaskID, result.WorkerID, result.Duration)
-		count++
-		totalDuration += result.Duration
+	outcomes := make(map[scheduler.Outcome]int)
+	latencies := make(map[scheduler.Priority][]time.Duration)
+	for r := range reports {
+		outcomes[r.Outcome]++
+		switch r.Outcome {
+		case scheduler.Done:
+			count++
+			latencies[r.Task.Priority] = append(latencies[r.Task.Priority], r.Latency())
+			fmt.Printf("Collector: 任务 %d（%v）由 Worker-%d 完成，执行 %d 次，总耗时 %v\n",
+				r.Task.ID, r.Task.Priority, r.Worker, r.Attempts, r.Latency().Round(time.Millisecond))
+		default:
+			fmt.Printf("Collector: 任务 %d（%v）%v，执行 %d 次: %v\n", r.Task.ID, r.Task.Priority, r.Outcome, r.Attempts, r.Err)
+		}
 	}
 
 	// 打印最终统计
 	fmt.Println("\n===== 统计信息 =====")
 	fmt.Println("总完成任务数:", count)
-	if count > 0 {
-		fmt.Printf("平均处理时间: %v\n", totalDuration/time.Duration(count))
+	fmt.Printf("失败: %d，过期丢弃: %d\n", outcomes[scheduler.Failed], outcomes[scheduler.Expired])
+
+	// 按优先级统计从提交到完成的时间（包括排队和重试）
+	fmt.Println("\n数量  平均        P90         最大        优先级")
+	for _, p := range highestFirst(scheduler.PriorityValues()) {
+		ds := latencies[p]
+		if len(ds) == 0 {
+			continue
+		}
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		var total time.Duration
+		for _, d := range ds {
+			total += d
+		}
+		p90 := ds[(len(ds)*9-1)/10]
+		avg := total / time.Duration(len(ds))
+		fmt.Printf("%4d  %-10v  %-10v  %-10v  %v\n", len(ds),
+			avg.Round(time.Millisecond), p90.Round(time.Millisecond), ds[len(ds)-1].Round(time.Millisecond), p)
 	}
 	done <- struct{}{}
 }
 
+// highestFirst 按优先级从高到低排列
+func highestFirst(ps []scheduler.Priority) []scheduler.Priority {
+	sort.Slice(ps, func(i, j int) bool { return ps[i] > ps[j] })
+	return ps
+}
+
 func main() {
 	// 设置随机种子，确保每次运行的随机数不同
 	rand.Seed(time.Now().UnixNano())
 
-	tasks := make(chan Task, 10)
-	results := make(chan Result, 20)
 	done := make(chan struct{})
 
-	var wg sync.WaitGroup
-
-	// 启动 worker
-	numWorkers := 3
-	for i := 1; i <= numWorkers; i++ {
-		wg.Add(1)
-		go worker(i, tasks, results, &wg)
-	}
+	// 3 个 worker；每多等 1 秒有效优先级提高一级，失败后 200ms 起指数退避
+	sched := scheduler.New(context.Background(), worker, scheduler.Options{
+		Workers: 3,
+		Aging:   time.Second,
+		Backoff: 200 * time.Millisecond,
+	})
 
 	// 启动 collector
-	go collector(results, done)
+	go collector(sched.Reports(), done)
 
 	// 生成任务（5秒超时）
 	fmt.Println("===== 启动任务处理系统 (限时 5 秒) =====")
@@ -83,25 +109,27 @@ func main() {
 
 	//循环生产20个任务
 	go func() {
+		defer sched.Close() // 不再提交任务，等待已提交的任务（包括重试）处理完后关闭报告通道
 		for i := 1; i <= 20; i++ {
 			select {
 			case <-timer.C:
 				fmt.Println("任务生成超时，停止生成新任务。")
-				close(tasks)
 				return
 			default:
-				tasks <- Task{ID: i}
-				fmt.Printf("主程序: 生成任务 %d\n", i)
-				time.Sleep(200 * time.Millisecond) // 模拟任务生成间隔
+				task := Task{
+					ID:         i,
+					Priority:   scheduler.Priority(rand.Intn(4)),
+					MaxRetries: 2,
+				}
+				// 每 4 个任务中有一个必须在 1.5 秒内完成
+				if i%4 == 0 {
+					task.Deadline = time.Now().Add(1500 * time.Millisecond)
+				}
+				sched.Submit(task)
+				fmt.Printf("主程序: 生成任务 %d（优先级 %v）\n", i, task.Priority)
+				time.Sleep(100 * time.Millisecond) // 模拟任务生成间隔
 			}
 		}
-		close(tasks) // 关闭任务通道，表示没有更多任务
-	}()
-
-	// 等待所有 worker 完成
-	go func() {
-		wg.Wait()
-		close(results) // 关闭结果通道，表示没有更多结果
 	}()
 
 	// 等待 collector 完成
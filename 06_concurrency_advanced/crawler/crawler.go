@@ -0,0 +1,445 @@
+// Package crawler 是第06节练习的真实 HTTP 爬虫，替代随机睡眠加抛硬币的 crawl：
+//
+//   - 从种子 URL 出发按层广度优先抓取，深度不超过 MaxDepth（种子为第 0 层）
+//   - 只跟随与种子同一主机的链接，其他主机的链接只记录不抓取
+//   - URL 规范化后去重，http://golang.org/ 和 https://golang.org 只抓一次
+//   - 每一层的页面交给 workerpool 并发抓取
+//   - 礼貌抓取：遵守 robots.txt（Disallow、Allow、Crawl-delay），限制每个主机的请求速率和并发连接数，
+//     以及所有主机合计的请求速率
+//   - 重定向由爬虫自己跟随，只跟随同一主机内的；重定向到其他主机时把目标地址当作页面中的链接，
+//     和其他链接一样按主机过滤、去重
+//   - 每个结果带有状态码、错误分类、字节数和重定向链；超时、连接失败、429 和 5xx 按带抖动的指数退避重试，
+//     并遵守 Retry-After
+//
+// http.Client 可以注入，测试时指向 httptest.Server，不需要访问网络。
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+var (
+	ErrBadURL     = errors.New("无效的 URL")
+	ErrStatus     = errors.New("HTTP 状态码表示失败")
+	ErrDisallowed = errors.New("robots.txt 禁止抓取")
+	ErrRobots     = errors.New("robots.txt 暂时无法获取")
+	ErrTooLarge   = errors.New("页面超过大小限制")
+	ErrRedirects  = errors.New("重定向次数过多")
+)
+
+// maxRedirects 是一个页面最多跟随的重定向次数，和 http.Client 的默认值相同
+const maxRedirects = 10
+
+// Options 配置爬虫，零值字段使用默认值
+type Options struct {
+	Client       *http.Client       // 默认是超时 10 秒的 http.Client；抓取页面时不使用它的 CheckRedirect
+	UserAgent    string             // 默认 "golang_study-crawler/1.0"，也用来选择 robots.txt 中的规则组
+	MaxDepth     int                // 最大深度，0 表示只抓种子
+	MaxBodyBytes int64              // 页面大小上限，超过时结果为 TooLarge，默认 1 MiB
+	Pool         workerpool.Options // 抓取页面的 worker 池，默认 4 个 worker
+
+	// 以下为 0 时不限制。robots.txt 的 Crawl-delay 会把对应主机的速率进一步降低。
+	HostQPS         float64 // 每个主机每秒的请求数
+	HostBurst       int     // 每个主机允许的突发请求数，默认 1
+	HostConcurrency int     // 每个主机同时进行的请求数
+	GlobalQPS       float64 // 所有主机合计每秒的请求数
+
+	// 可重试的失败（见 Category.Retryable）最多重试 Retries 次，
+	// 第 n 次重试前等待 Backoff × 2^(n-1)（不超过 MaxBackoff）的 50%~100%。
+	// 429 和 503 响应带有 Retry-After 时至少等待这么久，要求的时间超过 MaxBackoff 时不再重试。
+	Retries    int           // 默认 0，不重试
+	Backoff    time.Duration // 默认 200ms
+	MaxBackoff time.Duration // 默认 Backoff 的 32 倍
+
+	IgnoreRobots bool             // 不获取也不遵守 robots.txt
+	Clock        workerpool.Clock // 限速和重试等待使用的时钟，默认使用真实时间
+}
+
+// Result 是一个页面的抓取结果
+type Result struct {
+	URL      string // 规范化后的 URL
+	Depth    int
+	From     string        // 发现该页面的页面，种子为空
+	Status   int           // HTTP 状态码，请求失败时为 0
+	Links    []string      // 页面中的链接；重定向到其他主机时只有重定向的目标地址
+	Duration time.Duration // 所有请求（包括重试）本身的耗时，不包括限速和退避的等待
+	Worker   int
+	Err      error // 最后一次请求的错误
+
+	Category  Category
+	Bytes     int64    // 读取的响应体字节数
+	Redirects []string // 依次重定向到的地址，最后一个是最终地址；没有重定向时为空
+	Attempts  int      // 请求次数（包括重试），没有发出请求时为 0
+}
+
+// Stats 是一次 Crawl 的统计
+type Stats struct {
+	Pages      int              // 已抓取的页面数（包括失败的）
+	Duplicates int              // 因为已抓取或已排队而跳过的 URL 数
+	External   int              // 因为不是种子主机而跳过的链接数
+	TooDeep    int              // 因为超过 MaxDepth 而没有抓取的链接数
+	Disallowed int              // 被 robots.txt 禁止的页面数
+	Retries    int              // 重试次数
+	Pool       workerpool.Stats // worker 池的统计
+
+	RobotsFetched int // 获取 robots.txt 的次数（多次 Crawl 累计）
+}
+
+// Crawler 抓取页面；同一个 Crawler 可以多次 Crawl，但同一时间只能运行一次
+type Crawler struct {
+	opts         Options
+	client       *http.Client // 抓取页面，不自动跟随重定向
+	robotsClient *http.Client // 获取 robots.txt，按 Options.Client 的设置跟随重定向
+
+	mu    sync.Mutex
+	stats Stats
+	pool  *workerpool.Pool[target, Result]
+
+	// 主机状态和全局限速在多次 Crawl 和 Fetch 之间共享，robots.txt 只获取一次
+	hostsMu sync.Mutex
+	hosts   map[string]*host
+	global  *bucket
+}
+
+// target 是一个待抓取的页面
+type target struct {
+	url   *url.URL
+	depth int
+	from  string
+}
+
+// New 创建爬虫
+func New(opts Options) *Crawler {
+	if opts.UserAgent == "" {
+		opts.UserAgent = "golang_study-crawler/1.0"
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 1 << 20
+	}
+	if opts.Pool.Workers <= 0 && opts.Pool.MaxWorkers <= 0 {
+		opts.Pool.Workers = 4
+	}
+	if opts.Clock == nil {
+		opts.Clock = workerpool.RealClock()
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 200 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 32 * opts.Backoff
+	}
+	robotsClient := opts.Client
+	if robotsClient == nil {
+		robotsClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	// 自动跟随的重定向会绕过主机过滤，页面的重定向由 get 自己处理
+	client := *robotsClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &Crawler{
+		opts:         opts,
+		client:       &client,
+		robotsClient: robotsClient,
+		hosts:        make(map[string]*host),
+		global:       newBucket(opts.Clock, opts.GlobalQPS, 1),
+	}
+}
+
+// ========== 抓取单个页面 ==========
+
+// Fetch 抓取一个页面并提取链接，同样遵守 robots.txt 和限速
+func (c *Crawler) Fetch(ctx context.Context, raw string) Result {
+	u, err := Normalize(raw)
+	if err != nil {
+		return Result{URL: raw, Err: err, Category: Classify(err)}
+	}
+	return c.fetch(ctx, target{url: u})
+}
+
+// fetch 抓取一个页面，可重试的失败按退避等待后重试
+func (c *Crawler) fetch(ctx context.Context, t target) Result {
+	r := Result{URL: t.url.String(), Depth: t.depth, From: t.from, Worker: workerpool.WorkerID(ctx)}
+	// tries 包括 robots.txt 暂时无法获取、没有发出页面请求的那些尝试
+	for tries := 1; ; tries++ {
+		r.Err = c.attempt(ctx, t.url, &r)
+		r.Category = Classify(r.Err)
+		if r.Err != nil && ctx.Err() != nil {
+			r.Category = Canceled
+		}
+		if !r.Category.Retryable() || tries > c.opts.Retries {
+			return r
+		}
+		// 服务器用 Retry-After 要求的等待时间是下限，超过 MaxBackoff 时不再重试
+		after := retryAfter(r.Err)
+		if after > c.opts.MaxBackoff {
+			return r
+		}
+		c.count(func(s *Stats) { s.Retries++ })
+		if !c.sleep(ctx, max(c.backoff(tries), after)) {
+			return r
+		}
+	}
+}
+
+// attempt 抓取 u 一次，跟随同一主机内的重定向（最多 maxRedirects 次），结果写入 r。
+// 重定向到其他主机时不跟随，目标地址作为唯一的链接返回，由调用方决定是否抓取。
+func (c *Crawler) attempt(ctx context.Context, u *url.URL, r *Result) error {
+	r.Status, r.Links, r.Bytes, r.Redirects = 0, nil, 0, nil
+	for {
+		next, err := c.hop(ctx, u, r)
+		if err != nil || next == nil {
+			return err
+		}
+		if next.Host != u.Host {
+			r.Links = []string{next.String()}
+			return nil
+		}
+		if len(r.Redirects) == maxRedirects {
+			return fmt.Errorf("%w: 超过 %d 次", ErrRedirects, maxRedirects)
+		}
+		r.Redirects = append(r.Redirects, next.String())
+		u = next
+	}
+}
+
+// hop 请求重定向链中的一个地址：和第一个地址一样，先检查 robots.txt，再等待连接名额和令牌
+func (c *Crawler) hop(ctx context.Context, u *url.URL, r *Result) (*url.URL, error) {
+	if err := c.allowed(ctx, u); err != nil {
+		return nil, err
+	}
+	release, err := c.acquire(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if len(r.Redirects) == 0 {
+		r.Attempts++ // 跟随重定向不算新的一次请求
+	}
+	start := time.Now()
+	defer func() { r.Duration += time.Since(start) }()
+	return c.request(ctx, u, r)
+}
+
+// request 发送一次 GET 请求，结果写入 r。响应是重定向时返回规范化的目标地址，不读取响应体；
+// 否则读取响应体，是 HTML 时按 u 解析链接。
+func (c *Crawler) request(ctx context.Context, u *url.URL, r *Result) (*url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadURL, err)
+	}
+	req.Header.Set("User-Agent", c.opts.UserAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	r.Status = resp.StatusCode
+	if isRedirect(resp.StatusCode) {
+		// 没有 Location 的 3xx 按普通响应处理
+		if loc, err := resp.Location(); err == nil {
+			next, err := normalize(loc)
+			if err != nil {
+				return nil, fmt.Errorf("重定向到 %s: %w", loc, err)
+			}
+			return next, nil
+		}
+	}
+	if resp.StatusCode >= 400 {
+		return nil, c.statusError(resp)
+	}
+	if resp.ContentLength > c.opts.MaxBodyBytes {
+		return nil, fmt.Errorf("%w: Content-Length %d 超过 %d 字节", ErrTooLarge, resp.ContentLength, c.opts.MaxBodyBytes)
+	}
+	// 多读 1 个字节，用来发现没有 Content-Length 的超大页面
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.opts.MaxBodyBytes+1))
+	r.Bytes = int64(len(body))
+	if err != nil {
+		return nil, err
+	}
+	if r.Bytes > c.opts.MaxBodyBytes {
+		return nil, fmt.Errorf("%w: 超过 %d 字节", ErrTooLarge, c.opts.MaxBodyBytes)
+	}
+	if isHTML(resp.Header.Get("Content-Type")) {
+		r.Links = ExtractLinks(u, string(body))
+	}
+	return nil, nil
+}
+
+// isRedirect 报告状态码是否表示重定向
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// isHTML 报告 Content-Type 是否为 HTML；没有 Content-Type 时按 HTML 处理
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// ========== 广度优先抓取 ==========
+
+// Crawl 从 seeds 开始按层抓取，每抓完一个页面就输出它的结果（同一层内按完成顺序），全部抓完或 ctx 取消后关闭通道。
+// 无效的种子以带 ErrBadURL 的结果输出。输出通道必须有人持续读取。
+func (c *Crawler) Crawl(ctx context.Context, seeds ...string) <-chan Result {
+	out := make(chan Result)
+	pool := workerpool.New(ctx, func(ctx context.Context, t target) (Result, error) {
+		return c.fetch(ctx, t), nil
+	}, c.opts.Pool)
+	c.mu.Lock()
+	c.stats, c.pool = Stats{RobotsFetched: c.stats.RobotsFetched}, pool
+	c.mu.Unlock()
+
+	go func() {
+		defer close(out)
+		defer func() {
+			// 提前返回时可能还有没读取的结果，丢弃它们以免 Drain 阻塞
+			go func() {
+				for range pool.Results() {
+				}
+			}()
+			pool.Drain()
+		}()
+
+		seen := make(map[string]bool)
+		hosts := make(map[string]bool)
+		var level []target
+		for _, s := range seeds {
+			u, err := Normalize(s)
+			if err != nil {
+				if !send(ctx, out, Result{URL: s, Err: err, Category: Classify(err)}) {
+					return
+				}
+				continue
+			}
+			hosts[u.Host] = true
+			if c.visit(seen, u) {
+				level = append(level, target{url: u})
+			}
+		}
+
+		for len(level) > 0 && ctx.Err() == nil {
+			var next []target
+			ok := c.fetchLevel(ctx, pool, level, func(r Result) bool {
+				for _, link := range r.Links {
+					u, err := Normalize(link)
+					if err != nil {
+						continue
+					}
+					if !hosts[u.Host] {
+						c.count(func(s *Stats) { s.External++ })
+						continue
+					}
+					if !c.visit(seen, u) {
+						continue
+					}
+					if r.Depth+1 > c.opts.MaxDepth {
+						c.count(func(s *Stats) { s.TooDeep++ })
+						continue
+					}
+					next = append(next, target{url: u, depth: r.Depth + 1, from: r.URL})
+				}
+				return send(ctx, out, r)
+			})
+			if !ok {
+				return
+			}
+			level = next
+		}
+	}()
+	return out
+}
+
+// visit 记录 u 已被发现，之前见过时返回 false
+func (c *Crawler) visit(seen map[string]bool, u *url.URL) bool {
+	key := Key(u)
+	if seen[key] {
+		c.count(func(s *Stats) { s.Duplicates++ })
+		return false
+	}
+	seen[key] = true
+	return true
+}
+
+// fetchLevel 把一层页面提交给 worker 池，按完成顺序对每个结果调用 handle，handle 返回 false 时停止。
+// 提交在另一个 goroutine 中进行，这样队列满时也能同时读取结果；ctx 取消后未提交的页面不再抓取。
+func (c *Crawler) fetchLevel(ctx context.Context, pool *workerpool.Pool[target, Result], level []target, handle func(Result) bool) bool {
+	submitted := make(chan int, 1)
+	go func() {
+		n := 0
+		for _, t := range level {
+			if pool.Submit(ctx, t) != nil {
+				break
+			}
+			n++
+		}
+		submitted <- n
+	}()
+
+	for done, total := 0, -1; total < 0 || done < total; {
+		select {
+		case total = <-submitted:
+		case pr := <-pool.Results():
+			done++
+			r := pr.Value
+			if pr.Err != nil {
+				// 没有执行（ctx 取消或被丢弃）或者 panic
+				r = Result{URL: pr.Input.url.String(), Depth: pr.Input.depth, From: pr.Input.from, Err: pr.Err, Category: Canceled}
+				var panicErr *workerpool.PanicError
+				if errors.As(pr.Err, &panicErr) {
+					r.Category = Other
+				}
+			}
+			c.count(func(s *Stats) { s.Pages++ })
+			if !handle(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *Crawler) count(fn func(*Stats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(&c.stats)
+}
+
+// Stats 返回当前（或最近一次）Crawl 的统计
+func (c *Crawler) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	if c.pool != nil {
+		s.Pool = c.pool.Stats()
+	}
+	return s
+}
+
+// send 发送 v，ctx 取消时放弃并返回 false
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,523 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ========== 本地模拟网站 ==========
+
+// mockWeb 在本机的一个 httptest.Server 上模拟任意多个网站，代替真实网络。
+// client() 返回的 http.Client 把所有请求（包括 https）都发到这个服务器，Host 头保持原样，
+// 服务器按 Host 和路径找到登记的页面，没有登记的页面返回 404。
+// 每个主机的请求时间和最大并发数都有记录，用来检查爬虫是否礼貌。
+//
+// latency 和 failRate 需要在 start 之前设置。
+type mockWeb struct {
+	latency  func() time.Duration // 每个请求的处理时间，nil 表示立即返回
+	failRate float64              // 以这个概率返回 500
+
+	mu       sync.Mutex
+	pages    map[string]mockPage // 规范化 URL 的 Key → 页面
+	requests []string
+	agents   map[string]int // User-Agent → 请求数
+	hosts    map[string]*hostActivity
+	server   *httptest.Server
+}
+
+type mockPage struct {
+	status      int
+	contentType string
+	body        string
+	links       []string
+	html        bool         // 按 links 生成 HTML，否则原样返回 body
+	handler     http.Handler // 不为 nil 时由它处理请求
+}
+
+// hostActivity 是一个主机收到的请求
+type hostActivity struct {
+	times         []time.Time // 每个请求的到达时间
+	active        int         // 正在处理的请求数
+	maxConcurrent int         // 同时处理的最大请求数
+}
+
+func newMockWeb() *mockWeb {
+	return &mockWeb{
+		pages:  make(map[string]mockPage),
+		agents: make(map[string]int),
+		hosts:  make(map[string]*hostActivity),
+	}
+}
+
+func (m *mockWeb) add(raw string, p mockPage) {
+	u, err := Normalize(raw)
+	if err != nil {
+		panic(err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pages[Key(u)] = p
+}
+
+// page 登记一个 HTML 页面及其中的链接（原样写入 href，可以是相对地址）
+func (m *mockWeb) page(raw string, links ...string) {
+	m.add(raw, mockPage{status: http.StatusOK, contentType: "text/html; charset=utf-8", links: links, html: true})
+}
+
+// robots 登记网站的 robots.txt，site 形如 "https://golang.org"
+func (m *mockWeb) robots(site, body string) {
+	m.add(site+"/robots.txt", mockPage{status: http.StatusOK, contentType: "text/plain; charset=utf-8", body: body})
+}
+
+// status 让一个地址固定返回 code
+func (m *mockWeb) status(raw string, code int) {
+	m.add(raw, mockPage{status: code, contentType: "text/plain; charset=utf-8", body: http.StatusText(code)})
+}
+
+// handle 让一个地址由 h 处理，用来模拟重定向、时好时坏或超大的页面
+func (m *mockWeb) handle(raw string, h http.HandlerFunc) {
+	m.add(raw, mockPage{handler: h})
+}
+
+// start 启动服务，测试结束时自动关闭
+func (m *mockWeb) start(t *testing.T) {
+	m.server = httptest.NewServer(m)
+	t.Cleanup(m.server.Close)
+}
+
+// client 返回把所有请求发到本地服务的 http.Client
+func (m *mockWeb) client() *http.Client {
+	target, _ := url.Parse(m.server.URL)
+	transport := m.server.Client().Transport
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			local := req.Clone(req.Context())
+			local.Host = req.URL.Host
+			local.URL.Scheme, local.URL.Host = target.Scheme, target.Host
+			resp, err := transport.RoundTrip(local)
+			if err == nil {
+				resp.Request = req // 让调用方看到原始地址，按它解析 Location
+			}
+			return resp, err
+		}),
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// received 返回收到的请求（主机 + 路径 + 查询），按到达顺序
+func (m *mockWeb) received() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.requests...)
+}
+
+// host 返回主机（如 "golang.org"）收到的请求
+func (m *mockWeb) host(host string) hostActivity {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.hosts[host]
+	if !ok {
+		return hostActivity{}
+	}
+	activity := *a
+	activity.times = append([]time.Time(nil), a.times...)
+	return activity
+}
+
+// userAgents 返回每个 User-Agent 的请求数
+func (m *mockWeb) userAgents() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	agents := make(map[string]int, len(m.agents))
+	for k, v := range m.agents {
+		agents[k] = v
+	}
+	return agents
+}
+
+func (m *mockWeb) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u := &url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	m.mu.Lock()
+	m.requests = append(m.requests, r.Host+r.URL.RequestURI())
+	m.agents[r.Header.Get("User-Agent")]++
+	activity, ok := m.hosts[r.Host]
+	if !ok {
+		activity = &hostActivity{}
+		m.hosts[r.Host] = activity
+	}
+	activity.times = append(activity.times, time.Now())
+	activity.active++
+	activity.maxConcurrent = max(activity.maxConcurrent, activity.active)
+	var (
+		page  mockPage
+		found bool
+	)
+	if n, err := normalize(u); err == nil {
+		page, found = m.pages[Key(n)]
+	}
+	fail := m.failRate > 0 && rand.Float64() < m.failRate
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		activity.active--
+		m.mu.Unlock()
+	}()
+
+	if m.latency != nil {
+		select {
+		case <-time.After(m.latency()):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	switch {
+	case fail:
+		http.Error(w, "模拟的服务器错误", http.StatusInternalServerError)
+		return
+	case !found:
+		http.NotFound(w, r)
+		return
+	case page.handler != nil:
+		page.handler.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", page.contentType)
+	w.WriteHeader(page.status)
+	if !page.html {
+		fmt.Fprint(w, page.body)
+		return
+	}
+	fmt.Fprintf(w, "<html><head><title>%s</title></head><body>\n", html.EscapeString(u.String()))
+	for _, l := range page.links {
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(l), html.EscapeString(l))
+	}
+	fmt.Fprint(w, "</body></html>\n")
+}
+
+func drain(results <-chan Result) []Result {
+	var all []Result
+	for r := range results {
+		all = append(all, r)
+	}
+	return all
+}
+
+// ========== 广度优先抓取 ==========
+
+// newSite 搭建测试网站：site.test 的各个页面互相链接，并链接到 other.test
+func newSite(t *testing.T) *mockWeb {
+	web := newMockWeb()
+	web.page("http://site.test/", "/a", "b", "http://site.test/a#top", "https://other.test/", "mailto:me@site.test", "/a/../b")
+	web.page("http://site.test/a", "/c", "/", "/missing")
+	web.page("http://site.test/b", "/c", "/d?y=2&x=1", "HTTP://SITE.TEST:80/a")
+	web.page("http://site.test/c", "/deep")
+	web.page("http://site.test/d?x=1&y=2", "/deep")
+	web.page("http://site.test/deep", "/deeper")
+	web.page("http://other.test/", "/x")
+	web.start(t)
+	return web
+}
+
+func TestCrawlBreadthFirstWithinDepth(t *testing.T) {
+	web := newSite(t)
+	c := New(Options{Client: web.client(), UserAgent: "study-bot/0.1", MaxDepth: 2})
+
+	var (
+		pages []string
+		depth = make(map[string]int)
+		errs  = make(map[string]error)
+		from  = make(map[string]string)
+		cat   = make(map[string]Category)
+		last  int
+	)
+	for r := range c.Crawl(context.Background(), "http://site.test/", "https://SITE.test:443", "not a url", "ftp://x") {
+		if r.Depth < last {
+			t.Errorf("%s 的深度 %d 小于之前输出的 %d，没有按层输出", r.URL, r.Depth, last)
+		}
+		last = r.Depth
+		pages = append(pages, r.URL)
+		depth[r.URL], errs[r.URL], from[r.URL], cat[r.URL] = r.Depth, r.Err, r.From, r.Category
+	}
+	sort.Strings(pages)
+	want := []string{
+		"ftp://x",
+		"http://site.test/",
+		"http://site.test/a",
+		"http://site.test/b",
+		"http://site.test/c",
+		"http://site.test/d?x=1&y=2",
+		"http://site.test/missing",
+		"not a url",
+	}
+	if !reflect.DeepEqual(pages, want) {
+		t.Fatalf("抓取的页面 %v, want %v", pages, want)
+	}
+	if d := depth["http://site.test/c"]; d != 2 {
+		t.Errorf("/c 的深度 %d, want 2", d)
+	}
+	if f := from["http://site.test/c"]; f != "http://site.test/a" && f != "http://site.test/b" {
+		t.Errorf("/c 由 %q 发现, want 第 1 层的 /a 或 /b", f)
+	}
+	for _, seed := range []string{"not a url", "ftp://x"} {
+		if !errors.Is(errs[seed], ErrBadURL) || cat[seed] != Other {
+			t.Errorf("无效种子 %q: err = %v, 分类 %v, want ErrBadURL 和 %v", seed, errs[seed], cat[seed], Other)
+		}
+	}
+	if !errors.Is(errs["http://site.test/missing"], ErrStatus) {
+		t.Errorf("404 err = %v, want ErrStatus", errs["http://site.test/missing"])
+	}
+
+	s := c.Stats()
+	if got := []int{s.Pages, s.Duplicates, s.External, s.TooDeep}; !reflect.DeepEqual(got, []int{6, 5, 1, 1}) {
+		t.Errorf("页面/重复/外部/过深 = %v, want [6 5 1 1]", got)
+	}
+	// 每个页面只请求一次，另有 1 次 robots.txt；/deep 在第 3 层，没有请求
+	requests := web.received()
+	if len(requests) != 7 || slices.Contains(requests, "site.test/deep") {
+		t.Errorf("请求 %v, want 6 个页面和 robots.txt", requests)
+	}
+	for _, r := range requests {
+		if strings.HasPrefix(r, "other.test") {
+			t.Errorf("访问了其他主机: %s", r)
+		}
+	}
+	if agents := web.userAgents(); !reflect.DeepEqual(agents, map[string]int{"study-bot/0.1": 7}) {
+		t.Errorf("User-Agent = %v", agents)
+	}
+}
+
+func TestCrawlMaxDepthZeroFetchesOnlySeeds(t *testing.T) {
+	web := newSite(t)
+	c := New(Options{Client: web.client(), IgnoreRobots: true})
+	results := drain(c.Crawl(context.Background(), "http://site.test/"))
+	if len(results) != 1 || results[0].URL != "http://site.test/" {
+		t.Fatalf("MaxDepth 0 的结果 %v, want 只有种子", results)
+	}
+	// 首页的 /a、/b 超过深度，other.test 是外部链接；/a#top 和 /a/../b 在提取链接时已经去重
+	if s := c.Stats(); s.TooDeep != 2 || s.External != 1 || s.Duplicates != 0 {
+		t.Fatalf("统计 %+v, want TooDeep 2 External 1 Duplicates 0", s)
+	}
+}
+
+func TestCrawlCancel(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 每个页面链接到 10 个新页面，并且很慢：不取消的话永远抓不完
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		for i := range 10 {
+			fmt.Fprintf(w, `<a href="%s/%d">%d</a>`, strings.TrimSuffix(r.URL.Path, "/"), i, i)
+		}
+	}))
+	defer slow.Close()
+
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := New(Options{Client: slow.Client(), MaxDepth: 100})
+	results := c.Crawl(ctx, slow.URL)
+	for range 5 {
+		<-results
+	}
+	cancel()
+	start := time.Now()
+	for range results {
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("取消后 %v 结果通道才关闭", d)
+	}
+
+	slow.Client().CloseIdleConnections()
+	after := runtime.NumGoroutine()
+	for deadline := time.Now().Add(time.Second); after > before && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+	if after > before {
+		t.Fatalf("取消后泄漏 %d 个 goroutine", after-before)
+	}
+}
+
+// ========== 重定向 ==========
+
+func TestRedirectWithinHost(t *testing.T) {
+	web := newMockWeb()
+	web.handle("http://site.test/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/hop", http.StatusMovedPermanently)
+	})
+	web.handle("http://site.test/hop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://site.test/final", http.StatusFound)
+	})
+	web.page("http://site.test/final", "/")
+	// /ping 和 /pong 互相重定向
+	web.handle("http://site.test/ping", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/pong", http.StatusFound)
+	})
+	web.handle("http://site.test/pong", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ping", http.StatusFound)
+	})
+	web.start(t)
+	c := New(Options{Client: web.client(), IgnoreRobots: true})
+
+	r := c.Fetch(context.Background(), "http://site.test/redirect")
+	if r.Err != nil || r.Status != http.StatusOK || r.Attempts != 1 || r.Bytes == 0 {
+		t.Fatalf("重定向后的结果 %+v", r)
+	}
+	if want := []string{"http://site.test/hop", "https://site.test/final"}; !reflect.DeepEqual(r.Redirects, want) {
+		t.Errorf("重定向链 %v, want %v", r.Redirects, want)
+	}
+	// 链接按最终地址解析
+	if want := []string{"https://site.test/"}; !reflect.DeepEqual(r.Links, want) {
+		t.Errorf("链接 %v, want %v", r.Links, want)
+	}
+
+	r = c.Fetch(context.Background(), "http://site.test/ping")
+	if !errors.Is(r.Err, ErrRedirects) || len(r.Redirects) != maxRedirects {
+		t.Fatalf("重定向循环 err = %v，跟随 %d 次, want ErrRedirects 和 %d 次", r.Err, len(r.Redirects), maxRedirects)
+	}
+}
+
+// 重定向到其他主机时不跟随，目标地址和页面中的链接一样按主机过滤
+func TestRedirectToOtherHostIsNotFollowed(t *testing.T) {
+	web := newMockWeb()
+	web.page("http://site.test/", "/jump")
+	web.handle("http://site.test/jump", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://evil.test/secret", http.StatusFound)
+	})
+	web.robots("http://evil.test", "User-agent: *\nDisallow: /\n")
+	web.page("http://evil.test/secret")
+	web.start(t)
+	c := New(Options{Client: web.client(), MaxDepth: 3})
+
+	var jump Result
+	for r := range c.Crawl(context.Background(), "http://site.test/") {
+		if r.URL == "http://site.test/jump" {
+			jump = r
+		}
+	}
+	if jump.Err != nil || jump.Status != http.StatusFound || !reflect.DeepEqual(jump.Links, []string{"http://evil.test/secret"}) {
+		t.Fatalf("/jump 的结果 %+v, want 302 并以目标地址为链接", jump)
+	}
+	if n := c.Stats().External; n != 1 {
+		t.Errorf("External = %d, want 1", n)
+	}
+	if r := c.Fetch(context.Background(), "http://site.test/jump"); r.Err != nil || len(r.Redirects) != 0 {
+		t.Errorf("Fetch /jump = %+v, want 不跟随", r)
+	}
+	for _, r := range web.received() {
+		if strings.HasPrefix(r, "evil.test") {
+			t.Fatalf("跟随重定向访问了其他主机: %s", r)
+		}
+	}
+}
+
+// 重定向的目标是另一个种子主机时，作为下一层的页面抓取
+func TestRedirectToSeedHostIsQueued(t *testing.T) {
+	web := newMockWeb()
+	web.page("http://a.test/", "/moved")
+	web.handle("http://a.test/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://b.test/new", http.StatusMovedPermanently)
+	})
+	web.page("http://b.test/")
+	web.page("http://b.test/new")
+	web.start(t)
+	c := New(Options{Client: web.client(), MaxDepth: 2, IgnoreRobots: true})
+
+	found := make(map[string]Result)
+	for r := range c.Crawl(context.Background(), "http://a.test/", "http://b.test/") {
+		found[r.URL] = r
+	}
+	r, ok := found["http://b.test/new"]
+	if !ok || r.Err != nil || r.Depth != 2 || r.From != "http://a.test/moved" {
+		t.Fatalf("重定向目标的结果 %+v（找到 %v）, want 第 2 层、由 /moved 发现", r, ok)
+	}
+}
+
+// 重定向链中的每一跳都要经过 robots.txt 检查
+func TestRedirectHopsObeyRobots(t *testing.T) {
+	web := newMockWeb()
+	web.robots("http://a.test", "User-agent: *\nDisallow: /private\n")
+	web.page("http://a.test/", "/jump", "/go")
+	web.handle("http://a.test/go", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/private", http.StatusFound)
+	})
+	web.page("http://a.test/private")
+	web.handle("http://a.test/jump", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://evil.test/secret", http.StatusFound)
+	})
+	web.robots("http://evil.test", "User-agent: *\nDisallow: /secret\n")
+	web.page("http://evil.test/")
+	web.page("http://evil.test/secret")
+	web.start(t)
+	c := New(Options{Client: web.client(), MaxDepth: 2})
+
+	found := make(map[string]Result)
+	for r := range c.Crawl(context.Background(), "http://a.test/", "http://evil.test/") {
+		found[r.URL] = r
+	}
+	// 同一主机内重定向到被禁止的路径
+	if r := found["http://a.test/go"]; !errors.Is(r.Err, ErrDisallowed) || !reflect.DeepEqual(r.Redirects, []string{"http://a.test/private"}) {
+		t.Errorf("/go 的结果 %+v, want 重定向到 /private 后被禁止", r)
+	}
+	// 重定向到另一个种子主机，那个主机禁止抓取目标地址
+	if r, ok := found["http://evil.test/secret"]; !ok || !errors.Is(r.Err, ErrDisallowed) || r.From != "http://a.test/jump" {
+		t.Errorf("evil.test/secret 的结果 %+v（找到 %v）, want 由 /jump 发现并被禁止", r, ok)
+	}
+	if r := found["http://evil.test/"]; r.Err != nil {
+		t.Errorf("evil.test/ err = %v, want 允许抓取", r.Err)
+	}
+	for _, req := range web.received() {
+		if req == "a.test/private" || req == "evil.test/secret" {
+			t.Errorf("请求了 robots.txt 禁止的地址 %s", req)
+		}
+	}
+}
+
+// 重定向链中的每一跳都要等待主机和全局的令牌
+func TestRedirectHopsAreRateLimited(t *testing.T) {
+	web := newMockWeb()
+	for i := 1; i < 4; i++ {
+		web.handle(fmt.Sprintf("http://rate.test/r%d", i), func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, fmt.Sprintf("/r%d", i+1), http.StatusFound)
+		})
+	}
+	web.page("http://rate.test/r4")
+	web.start(t)
+
+	for name, opts := range map[string]Options{
+		"HostQPS":   {HostQPS: 10},
+		"GlobalQPS": {GlobalQPS: 10},
+	} {
+		t.Run(name, func(t *testing.T) {
+			before := len(web.host("rate.test").times)
+			opts.Client, opts.IgnoreRobots = web.client(), true
+			r := New(opts).Fetch(context.Background(), "http://rate.test/r1")
+			if r.Err != nil || len(r.Redirects) != 3 {
+				t.Fatalf("Fetch = %+v, want 跟随 3 次重定向", r)
+			}
+			if gap := minGap(web.host("rate.test").times[before:]); gap < 90*time.Millisecond {
+				t.Fatalf("%s 10 时重定向链的最短请求间隔 %v, want ≥ 90ms", name, gap)
+			}
+		})
+	}
+}
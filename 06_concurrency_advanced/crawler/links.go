@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ========== URL 规范化 ==========
+
+// Normalize 解析并规范化一个绝对 URL，用于抓取和去重：
+//
+//   - scheme 和主机名转为小写，去掉默认端口（http 的 80、https 的 443）和主机名末尾的点
+//   - 空路径补为 "/"，解析 "." 和 ".." 路径段
+//   - 去掉片段（#...），查询参数按名字排序
+//
+// 只接受 http 和 https。
+func Normalize(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadURL, err)
+	}
+	return normalize(u)
+}
+
+func normalize(u *url.URL) (*url.URL, error) {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	if n.Scheme != "http" && n.Scheme != "https" {
+		return nil, fmt.Errorf("%w: 不支持的协议 %q", ErrBadURL, u.String())
+	}
+	host := strings.TrimSuffix(strings.ToLower(n.Hostname()), ".")
+	if host == "" {
+		return nil, fmt.Errorf("%w: 缺少主机名 %q", ErrBadURL, u.String())
+	}
+	if port := n.Port(); port != "" && !(n.Scheme == "http" && port == "80") && !(n.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	n.Host = host
+	n.User = nil
+	n.Fragment, n.RawFragment = "", ""
+	n.Opaque = ""
+	// 相对于 "/" 解析一次，顺带去掉 "." 和 ".." 路径段
+	n.Path = (&url.URL{Path: "/"}).ResolveReference(&url.URL{Path: n.Path}).Path
+	n.RawPath = ""
+	if n.RawQuery != "" {
+		n.RawQuery = n.Query().Encode()
+	}
+	n.ForceQuery = false
+	return &n, nil
+}
+
+// Key 返回去重用的键：忽略 scheme，http://golang.org/ 和 https://golang.org 是同一个页面
+func Key(u *url.URL) string {
+	k := *u
+	k.Scheme = ""
+	return k.String()
+}
+
+// ========== 链接提取 ==========
+
+var (
+	// 注释和 script/style 中的内容不是链接
+	skipPattern = regexp.MustCompile(`(?is)<!--.*?-->|<script\b.*?</script\s*>|<style\b.*?</style\s*>`)
+	tagPattern  = regexp.MustCompile(`(?is)<(a|area|base)\b([^>]*)>`)
+	// href 前面必须是空白（或 "/"），data-href、xhref 之类的属性不算
+	hrefPattern = regexp.MustCompile(`(?is)(?:^|[\s/])href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// ExtractLinks 从 HTML 中提取 <a>、<area> 的 href，按 base（和文档中的 <base href>）解析为规范化的绝对 URL。
+// 结果按出现顺序排列并去重（按 Key），非 http/https 链接（mailto:、javascript: 等）被忽略。
+func ExtractLinks(base *url.URL, body string) []string {
+	body = skipPattern.ReplaceAllString(body, "")
+	var (
+		links []string
+		seen  = make(map[string]bool)
+	)
+	for _, tag := range tagPattern.FindAllStringSubmatch(body, -1) {
+		m := hrefPattern.FindStringSubmatch(tag[2])
+		if m == nil {
+			continue
+		}
+		href := html.UnescapeString(m[1] + m[2] + m[3])
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			continue
+		}
+		abs := base.ResolveReference(ref)
+		if strings.EqualFold(tag[1], "base") {
+			base = abs
+			continue
+		}
+		u, err := normalize(abs)
+		if err != nil || seen[Key(u)] {
+			continue
+		}
+		seen[Key(u)] = true
+		links = append(links, u.String())
+	}
+	return links
+}
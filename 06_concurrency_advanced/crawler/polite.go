@@ -0,0 +1,228 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+// ========== 令牌桶 ==========
+
+// bucket 是令牌桶限速器：每秒补充 rate 个令牌，最多存 burst 个。nil 表示不限速。
+type bucket struct {
+	mu     sync.Mutex
+	clock  workerpool.Clock
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(clock workerpool.Clock, rate float64, burst int) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(max(burst, 1))
+	return &bucket{clock: clock, rate: rate, burst: b, tokens: b, last: clock.Now()}
+}
+
+// refillLocked 按经过的时间补充令牌
+func (b *bucket) refillLocked() {
+	now := b.clock.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// wait 取一个令牌，没有时等到补充出来为止。
+// 令牌先预支（可以为负），所以同时等待的调用者按到达顺序依次获得令牌；ctx 取消时归还。
+func (b *bucket) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	b.refillLocked()
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := b.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// slowDown 把速率降到不超过 rate，突发容量降为 1（用于 Crawl-delay）
+func (b *bucket) slowDown(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refillLocked()
+	if rate < b.rate {
+		b.rate = rate
+	}
+	b.burst = 1
+	b.tokens = min(b.tokens, 1)
+}
+
+// ========== 每个主机的状态 ==========
+
+// host 记录一个主机的并发连接、限速和 robots.txt
+type host struct {
+	slots  chan struct{} // 并发连接数上限，nil 表示不限
+	bucket *bucket
+
+	mu      sync.Mutex
+	robots  *Robots
+	loading chan struct{} // 正在获取 robots.txt 时不为 nil，获取完成后关闭
+}
+
+// host 返回 u 所在主机的状态，第一次访问时创建
+func (c *Crawler) host(u *url.URL) *host {
+	c.hostsMu.Lock()
+	defer c.hostsMu.Unlock()
+	h, ok := c.hosts[u.Host]
+	if !ok {
+		h = &host{bucket: newBucket(c.opts.Clock, c.opts.HostQPS, c.opts.HostBurst)}
+		if c.opts.HostConcurrency > 0 {
+			h.slots = make(chan struct{}, c.opts.HostConcurrency)
+		}
+		c.hosts[u.Host] = h
+	}
+	return h
+}
+
+// acquire 等待 u 所在主机的连接名额、主机令牌和全局令牌，返回释放连接名额的函数
+func (c *Crawler) acquire(ctx context.Context, u *url.URL) (func(), error) {
+	h := c.host(u)
+	release := func() {}
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+			release = func() { <-h.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	h.mu.Lock()
+	b := h.bucket
+	h.mu.Unlock()
+	if err := b.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	if err := c.global.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// allowed 按 robots.txt 检查是否可以抓取 u，被禁止时返回 ErrDisallowed
+func (c *Crawler) allowed(ctx context.Context, u *url.URL) error {
+	if c.opts.IgnoreRobots {
+		return nil
+	}
+	robots, err := c.robots(ctx, u)
+	if err != nil {
+		return err
+	}
+	if !robots.Allowed(u.RequestURI()) {
+		c.count(func(s *Stats) { s.Disallowed++ })
+		return fmt.Errorf("%w: %s", ErrDisallowed, u)
+	}
+	return nil
+}
+
+// robots 返回 u 所在主机的 robots.txt 规则。每个主机只获取一次，同时到达的调用者等待同一次获取；
+// 获取失败不缓存，下一个请求会重试。
+func (c *Crawler) robots(ctx context.Context, u *url.URL) (*Robots, error) {
+	h := c.host(u)
+	for {
+		h.mu.Lock()
+		if h.robots != nil {
+			h.mu.Unlock()
+			return h.robots, nil
+		}
+		if wait := h.loading; wait != nil {
+			h.mu.Unlock()
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		h.loading = make(chan struct{})
+		h.mu.Unlock()
+
+		robots, err := c.fetchRobots(ctx, u)
+
+		h.mu.Lock()
+		close(h.loading)
+		h.loading = nil
+		if err == nil {
+			h.robots = robots
+			if robots.CrawlDelay > 0 {
+				rate := float64(time.Second) / float64(robots.CrawlDelay)
+				if h.bucket == nil {
+					h.bucket = newBucket(c.opts.Clock, rate, 1)
+				} else {
+					h.bucket.slowDown(rate)
+				}
+			}
+		}
+		h.mu.Unlock()
+		return robots, err
+	}
+}
+
+// fetchRobots 获取并解析 robots.txt：4xx 表示没有限制，429、5xx 和网络错误返回 ErrRobots
+func (c *Crawler) fetchRobots(ctx context.Context, u *url.URL) (*Robots, error) {
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	release, err := c.acquire(ctx, robotsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	c.count(func(s *Stats) { s.RobotsFetched++ })
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRobots, err)
+	}
+	req.Header.Set("User-Agent", c.opts.UserAgent)
+	resp, err := c.robotsClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRobots, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests:
+		return nil, fmt.Errorf("%w: %w", ErrRobots, c.statusError(resp))
+	case resp.StatusCode >= 400:
+		return allowAll, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 512<<10))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRobots, err)
+	}
+	return ParseRobots(c.opts.UserAgent, string(body)), nil
+}
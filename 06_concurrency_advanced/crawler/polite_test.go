@@ -0,0 +1,122 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+// minGap 返回相邻请求之间最短的间隔
+func minGap(times []time.Time) time.Duration {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	gap := time.Duration(math.MaxInt64)
+	for i := 1; i < len(times); i++ {
+		gap = min(gap, times[i].Sub(times[i-1]))
+	}
+	return gap
+}
+
+// addSite 登记一个网站：首页链接到 /p1 … /p<n-1>
+func addSite(web *mockWeb, site string, n int) {
+	var links []string
+	for i := 1; i < n; i++ {
+		links = append(links, fmt.Sprintf("/p%d", i))
+		web.page(fmt.Sprintf("%s/p%d", site, i))
+	}
+	web.page(site+"/", links...)
+}
+
+func TestRobotsTxt(t *testing.T) {
+	web := newMockWeb()
+	web.latency = func() time.Duration { return 20 * time.Millisecond }
+	web.robots("http://robots.test", "User-agent: study-bot\nDisallow: /p2\n\nUser-agent: *\nDisallow: /\n")
+	addSite(web, "http://robots.test", 4)
+	web.status("http://down.test/robots.txt", http.StatusServiceUnavailable)
+	web.page("http://down.test/")
+	web.page("http://norobots.test/")
+	web.start(t)
+	ctx := context.Background()
+
+	// 按 User-Agent 选组，每个主机只获取一次
+	c := New(Options{Client: web.client(), UserAgent: "study-bot/0.1", MaxDepth: 1, Pool: workerpool.Options{Workers: 4}})
+	var disallowed []string
+	for _, r := range drain(c.Crawl(ctx, "http://robots.test/")) {
+		if errors.Is(r.Err, ErrDisallowed) {
+			disallowed = append(disallowed, r.URL)
+		}
+	}
+	if want := []string{"http://robots.test/p2"}; !reflect.DeepEqual(disallowed, want) {
+		t.Errorf("被禁止的页面 %v, want %v", disallowed, want)
+	}
+	if s := c.Stats(); s.RobotsFetched != 1 || s.Disallowed != 1 {
+		t.Errorf("获取 robots.txt %d 次，禁止 %d 个页面, want 1/1", s.RobotsFetched, s.Disallowed)
+	}
+	if slices.Contains(web.received(), "robots.test/p2") {
+		t.Error("请求了被禁止的页面")
+	}
+
+	if r := c.Fetch(ctx, "http://down.test/"); !errors.Is(r.Err, ErrRobots) || slices.Contains(web.received(), "down.test/") {
+		t.Errorf("robots.txt 返回 503 时 err = %v, want ErrRobots 且不抓取页面", r.Err)
+	}
+	if r := c.Fetch(ctx, "http://norobots.test/"); r.Err != nil {
+		t.Errorf("没有 robots.txt（404）时 err = %v, want 允许抓取", r.Err)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	web := newMockWeb()
+	web.latency = func() time.Duration { return 20 * time.Millisecond }
+	addSite(web, "http://rate.test", 8)
+	web.robots("http://delay.test", "User-agent: *\nCrawl-delay: 0.15\n")
+	addSite(web, "http://delay.test", 5)
+	addSite(web, "http://busy.test", 10)
+	for _, h := range []string{"g1", "g2", "g3", "g4"} {
+		addSite(web, "http://"+h+".test", 5)
+	}
+	web.start(t)
+	ctx := context.Background()
+	pool := workerpool.Options{Workers: 8}
+
+	t.Run("HostQPS", func(t *testing.T) {
+		c := New(Options{Client: web.client(), MaxDepth: 1, HostQPS: 10, Pool: pool})
+		drain(c.Crawl(ctx, "http://rate.test/"))
+		if gap := minGap(web.host("rate.test").times); gap < 90*time.Millisecond {
+			t.Fatalf("HostQPS 10 时最短请求间隔 %v, want ≥ 90ms", gap)
+		}
+	})
+
+	t.Run("Crawl-delay", func(t *testing.T) {
+		c := New(Options{Client: web.client(), MaxDepth: 1, Pool: pool})
+		drain(c.Crawl(ctx, "http://delay.test/"))
+		if gap := minGap(web.host("delay.test").times[1:]); gap < 135*time.Millisecond { // 第一个请求是 robots.txt
+			t.Fatalf("Crawl-delay 0.15 时最短请求间隔 %v, want ≥ 135ms", gap)
+		}
+	})
+
+	t.Run("HostConcurrency", func(t *testing.T) {
+		c := New(Options{Client: web.client(), MaxDepth: 1, HostConcurrency: 2, IgnoreRobots: true, Pool: pool})
+		drain(c.Crawl(ctx, "http://busy.test/"))
+		if n := web.host("busy.test").maxConcurrent; n != 2 {
+			t.Fatalf("HostConcurrency 2 时最大并发连接数 %d, want 2", n)
+		}
+	})
+
+	t.Run("GlobalQPS", func(t *testing.T) {
+		// 4 个主机共 20 个页面，不少于 19 × 50ms
+		c := New(Options{Client: web.client(), MaxDepth: 1, GlobalQPS: 20, IgnoreRobots: true, Pool: pool})
+		start := time.Now()
+		pages := drain(c.Crawl(ctx, "http://g1.test/", "http://g2.test/", "http://g3.test/", "http://g4.test/"))
+		if elapsed := time.Since(start); len(pages) != 20 || elapsed < 855*time.Millisecond {
+			t.Fatalf("GlobalQPS 20 时 %d 个页面耗时 %v, want 20 个且 ≥ 855ms", len(pages), elapsed)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+// ========== 错误分类 ==========
+
+// Category 是抓取结果的分类，决定失败后是否重试
+//
+//go:generate go run golang_study/tools/enumgen -type=Category
+type Category int
+
+const (
+	OK          Category = iota // enum:"成功"
+	DNS                         // enum:"DNS 解析失败"
+	Timeout                     // enum:"超时"
+	Connection                  // enum:"连接失败"
+	ClientError                 // enum:"4xx"
+	ServerError                 // enum:"5xx"
+	Throttled                   // enum:"429 限流"
+	TooLarge                    // enum:"页面过大"
+	Disallowed                  // enum:"robots.txt 禁止"
+	Canceled                    // enum:"已取消"
+	Other                       // enum:"其他错误"
+)
+
+// Retryable 报告这一类失败是否值得重试：超时、连接失败、429 和 5xx 通常是暂时的，
+// DNS 解析失败、其他 4xx、页面过大和 robots.txt 禁止再试也是一样的结果
+func (c Category) Retryable() bool {
+	switch c {
+	case Timeout, Connection, ServerError, Throttled:
+		return true
+	}
+	return false
+}
+
+// StatusError 表示状态码为 4xx 或 5xx 的响应，errors.Is(err, ErrStatus) 为 true
+type StatusError struct {
+	Code       int
+	Status     string
+	RetryAfter time.Duration // 429 和 503 响应的 Retry-After，没有或无效时为 0
+}
+
+func (e *StatusError) Error() string { return ErrStatus.Error() + ": " + e.Status }
+
+func (e *StatusError) Unwrap() error { return ErrStatus }
+
+// Classify 判断 err 属于哪一类，err 为 nil 时返回 OK。
+// robots.txt 暂时无法获取时按获取 robots.txt 的错误分类。
+// context.DeadlineExceeded 归为超时；整个 Crawl 的 ctx 结束导致的失败由爬虫标记为 Canceled。
+func Classify(err error) Category {
+	var (
+		status *StatusError
+		dnsErr *net.DNSError
+		netErr net.Error
+	)
+	switch {
+	case err == nil:
+		return OK
+	case errors.Is(err, context.Canceled), errors.Is(err, workerpool.ErrDropped):
+		return Canceled
+	case errors.Is(err, ErrDisallowed):
+		return Disallowed
+	case errors.Is(err, ErrTooLarge):
+		return TooLarge
+	case errors.As(err, &status):
+		switch {
+		case status.Code == http.StatusTooManyRequests:
+			return Throttled
+		case status.Code >= 500:
+			return ServerError
+		}
+		return ClientError
+	case errors.As(err, &dnsErr):
+		return DNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return Timeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return Connection
+	case errors.As(err, &netErr):
+		return Connection
+	}
+	return Other
+}
+
+// ========== 重试 ==========
+
+// backoff 返回第 attempt 次重试（从 1 开始）前的等待时间：
+// 基准为 Backoff × 2^(attempt-1)，不超过 MaxBackoff，实际等待在基准的 [50%, 100%] 之间随机，
+// 避免大量失败的请求在同一时刻一起重试
+func (c *Crawler) backoff(attempt int) time.Duration {
+	d := c.opts.Backoff
+	for i := 1; i < attempt && d < c.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, c.opts.MaxBackoff)
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// statusError 把状态码为 4xx 或 5xx 的响应转成 StatusError，429 和 503 带上 Retry-After
+func (c *Crawler) statusError(resp *http.Response) *StatusError {
+	err := &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		err.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), c.opts.Clock.Now())
+	}
+	return err
+}
+
+// parseRetryAfter 解析 Retry-After：秒数或 HTTP 日期，返回从 now 起需要等待的时间；无效或已经过去时返回 0
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		if secs > math.MaxInt64/int64(time.Second) {
+			return math.MaxInt64
+		}
+		return time.Duration(secs) * time.Second
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0
+	}
+	return max(t.Sub(now), 0)
+}
+
+// retryAfter 返回 err 中服务器要求的最短等待时间
+func retryAfter(err error) time.Duration {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.RetryAfter
+	}
+	return 0
+}
+
+// sleep 按 Clock 等待 d，ctx 取消时提前返回 false
+func (c *Crawler) sleep(ctx context.Context, d time.Duration) bool {
+	timer := c.opts.Clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// enumgen:begin Category
+// 以下代码由 enumgen 根据 Category 的常量生成，请勿手动修改；修改常量后重新运行 go generate。
+
+// String 返回显示名称（常量注释中的 enum:"..."，没有标注时为常量名）
+func (x Category) String() string {
+	switch x {
+	case OK:
+		return "成功"
+	case DNS:
+		return "DNS 解析失败"
+	case Timeout:
+		return "超时"
+	case Connection:
+		return "连接失败"
+	case ClientError:
+		return "4xx"
+	case ServerError:
+		return "5xx"
+	case Throttled:
+		return "429 限流"
+	case TooLarge:
+		return "页面过大"
+	case Disallowed:
+		return "robots.txt 禁止"
+	case Canceled:
+		return "已取消"
+	case Other:
+		return "其他错误"
+	}
+	return fmt.Sprintf("Category(%d)", int64(x))
+}
+
+// Name 返回常量名，用于文本和 JSON 序列化
+func (x Category) Name() string {
+	switch x {
+	case OK:
+		return "OK"
+	case DNS:
+		return "DNS"
+	case Timeout:
+		return "Timeout"
+	case Connection:
+		return "Connection"
+	case ClientError:
+		return "ClientError"
+	case ServerError:
+		return "ServerError"
+	case Throttled:
+		return "Throttled"
+	case TooLarge:
+		return "TooLarge"
+	case Disallowed:
+		return "Disallowed"
+	case Canceled:
+		return "Canceled"
+	case Other:
+		return "Other"
+	}
+	return fmt.Sprintf("Category(%d)", int64(x))
+}
+
+// IsValid 报告 x 是否是已定义的常量
+func (x Category) IsValid() bool {
+	switch x {
+	case OK, DNS, Timeout, Connection, ClientError, ServerError, Throttled, TooLarge, Disallowed, Canceled, Other:
+		return true
+	}
+	return false
+}
+
+// CategoryValues 按声明顺序返回所有常量
+func CategoryValues() []Category {
+	return []Category{OK, DNS, Timeout, Connection, ClientError, ServerError, Throttled, TooLarge, Disallowed, Canceled, Other}
+}
+
+// ParseCategory 按常量名或显示名称解析
+func ParseCategory(s string) (Category, error) {
+	switch s {
+	case "OK", "成功":
+		return OK, nil
+	case "DNS", "DNS 解析失败":
+		return DNS, nil
+	case "Timeout", "超时":
+		return Timeout, nil
+	case "Connection", "连接失败":
+		return Connection, nil
+	case "ClientError", "4xx":
+		return ClientError, nil
+	case "ServerError", "5xx":
+		return ServerError, nil
+	case "Throttled", "429 限流":
+		return Throttled, nil
+	case "TooLarge", "页面过大":
+		return TooLarge, nil
+	case "Disallowed", "robots.txt 禁止":
+		return Disallowed, nil
+	case "Canceled", "已取消":
+		return Canceled, nil
+	case "Other", "其他错误":
+		return Other, nil
+	}
+	return 0, fmt.Errorf("无效的 Category: %q", s)
+}
+
+// MarshalText 实现 encoding.TextMarshaler，输出常量名
+func (x Category) MarshalText() ([]byte, error) {
+	if !x.IsValid() {
+		return nil, fmt.Errorf("无效的 Category: %d", int64(x))
+	}
+	return []byte(x.Name()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，接受常量名或显示名称
+func (x *Category) UnmarshalText(text []byte) error {
+	v, err := ParseCategory(string(text))
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+// MarshalJSON 输出常量名字符串
+func (x Category) MarshalJSON() ([]byte, error) {
+	text, err := x.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%q", text)), nil
+}
+
+// UnmarshalJSON 接受字符串（常量名或显示名称）或数字；null 保持原值不变，与标准库的约定一致
+func (x *Category) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("无效的 Category: %s", data)
+		}
+		return x.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil || !Category(n).IsValid() {
+		return fmt.Errorf("无效的 Category: %s", data)
+	}
+	*x = Category(n)
+	return nil
+}
+
+// enumgen:end Category
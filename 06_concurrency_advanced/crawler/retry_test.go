@@ -0,0 +1,214 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+func TestClassify(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	cases := []struct {
+		err  error
+		want Category
+	}{
+		{nil, OK},
+		{&net.DNSError{Err: "no such host", Name: "nowhere.test", IsNotFound: true}, DNS},
+		{fmt.Errorf("Get: %w", context.DeadlineExceeded), Timeout},
+		{refused, Connection},
+		{&StatusError{Code: 404, Status: "404 Not Found"}, ClientError},
+		{&StatusError{Code: 429, Status: "429 Too Many Requests"}, Throttled},
+		{&StatusError{Code: 503, Status: "503 Service Unavailable"}, ServerError},
+		{fmt.Errorf("%w: %w", ErrRobots, &StatusError{Code: 503, Status: "503 Service Unavailable"}), ServerError},
+		{ErrTooLarge, TooLarge},
+		{ErrDisallowed, Disallowed},
+		{context.Canceled, Canceled},
+		{errors.New("奇怪的错误"), Other},
+	}
+	for _, c := range cases {
+		if got := Classify(c.err); got != c.want {
+			t.Errorf("Classify(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+	for _, c := range []Category{Timeout, Connection, ServerError, Throttled} {
+		if !c.Retryable() {
+			t.Errorf("%v 应当可以重试", c)
+		}
+	}
+	if ClientError.Retryable() {
+		t.Error("4xx 不应重试")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"120":                           2 * time.Minute,
+		" 5 ":                           5 * time.Second,
+		"Sat, 01 Mar 2025 12:01:30 GMT": 90 * time.Second,
+		"Sat, 01 Mar 2025 11:00:00 GMT": 0, // 已经过去
+		"":                              0,
+		"0":                             0,
+		"-5":                            0,
+		"soon":                          0,
+		"99999999999999999":             math.MaxInt64,
+	}
+	for v, want := range cases {
+		if got := parseRetryAfter(v, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+// Retry-After 是重试等待的下限：退避只有几毫秒时也要等满服务器要求的时间
+func TestRetryAfterIsMinimumBackoff(t *testing.T) {
+	web := newMockWeb()
+	var calls atomic.Int32
+	web.handle("http://busy.test/", func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "30")
+			http.Error(w, "请求太多", http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+	web.handle("http://busy.test/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, "维护中", http.StatusServiceUnavailable)
+	})
+	web.start(t)
+
+	clock := workerpool.NewFakeClock(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+	c := New(Options{
+		Client:       web.client(),
+		Retries:      3,
+		Backoff:      10 * time.Millisecond,
+		MaxBackoff:   time.Minute,
+		IgnoreRobots: true,
+		Clock:        clock,
+	})
+	done := make(chan Result, 1)
+	go func() { done <- c.Fetch(context.Background(), "http://busy.test/") }()
+
+	for deadline := time.Now().Add(2 * time.Second); clock.Timers() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("429 之后没有等待重试")
+		}
+	}
+	clock.Advance(29 * time.Second)
+	select {
+	case r := <-done:
+		t.Fatalf("Retry-After 30 秒，29 秒后就重试了: %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	if r := <-done; r.Category != OK || r.Attempts != 2 {
+		t.Fatalf("等满 Retry-After 后 %v，请求 %d 次, want 成功、2 次", r.Category, r.Attempts)
+	}
+
+	// 要求等待的时间超过 MaxBackoff 时不再重试
+	r := c.Fetch(context.Background(), "http://busy.test/maintenance")
+	if r.Category != ServerError || r.Attempts != 1 {
+		t.Fatalf("Retry-After 1 小时时 %v，请求 %d 次, want 5xx、1 次", r.Category, r.Attempts)
+	}
+	if n := c.Stats().Retries; n != 1 {
+		t.Fatalf("重试次数 %d, want 1", n)
+	}
+}
+
+func TestRetryAndResultDetails(t *testing.T) {
+	web := newMockWeb()
+	var flakyCalls atomic.Int32
+	web.handle("http://retry.test/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if flakyCalls.Add(1) <= 2 {
+			http.Error(w, "忙", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `<a href="/final">ok</a>`)
+	})
+	web.status("http://retry.test/always503", http.StatusServiceUnavailable)
+	web.handle("http://retry.test/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	})
+	web.handle("http://retry.test/big", func(w http.ResponseWriter, r *http.Request) {
+		for range 4 {
+			w.Write([]byte(strings.Repeat("x", 1000)))
+			w.(http.Flusher).Flush() // 分块传输，没有 Content-Length
+		}
+	})
+	web.handle("http://retry.test/big-declared", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5000")
+		w.Write([]byte(strings.Repeat("x", 5000)))
+	})
+	web.start(t)
+
+	client := web.client()
+	client.Timeout = 100 * time.Millisecond
+	c := New(Options{
+		Client:       client,
+		MaxBodyBytes: 1024,
+		Retries:      3,
+		Backoff:      20 * time.Millisecond,
+		IgnoreRobots: true,
+	})
+	ctx := context.Background()
+	summary := func(r Result) string {
+		return fmt.Sprintf("%v 状态 %d 请求 %d 次，读取 %d 字节", r.Category, r.Status, r.Attempts, r.Bytes)
+	}
+
+	cases := []struct {
+		path, want string
+	}{
+		{"/flaky", "成功 状态 200 请求 3 次，读取 23 字节"},         // 两次 503 后成功
+		{"/missing", "4xx 状态 404 请求 1 次，读取 0 字节"},       // 404 不重试
+		{"/always503", "5xx 状态 503 请求 4 次，读取 0 字节"},     // 重试 3 次后放弃
+		{"/slow", "超时 状态 0 请求 4 次，读取 0 字节"},             // 超时也重试
+		{"/big", "页面过大 状态 200 请求 1 次，读取 1025 字节"},       // 分块传输的超大页面
+		{"/big-declared", "页面过大 状态 200 请求 1 次，读取 0 字节"}, // Content-Length 超过上限时不读取
+	}
+	for _, tc := range cases {
+		start := time.Now()
+		r := c.Fetch(ctx, "http://retry.test"+tc.path)
+		if got := summary(r); got != tc.want {
+			t.Errorf("Fetch(%s) = %s, want %s", tc.path, got, tc.want)
+		}
+		// 退避基准 20ms、40ms、80ms，抖动后至少各一半
+		if tc.path == "/always503" && time.Since(start) < 70*time.Millisecond {
+			t.Errorf("重试 3 次只等待了 %v, want ≥ 70ms", time.Since(start))
+		}
+	}
+	if n := c.Stats().Retries; n != 2+3+3 {
+		t.Errorf("重试次数 %d, want 8", n)
+	}
+}
+
+func TestDNSFailureIsNotRetried(t *testing.T) {
+	c := New(Options{
+		Client: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, _, _ := net.SplitHostPort(addr)
+				return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}}
+			},
+		}},
+		Retries:      3,
+		IgnoreRobots: true,
+	})
+	r := c.Fetch(context.Background(), "http://nowhere.test/")
+	if r.Category != DNS || r.Attempts != 1 {
+		t.Fatalf("DNS 解析失败时 %v 请求 %d 次, want DNS 解析失败 1 次", r.Category, r.Attempts)
+	}
+}
@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"bufio"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========== robots.txt ==========
+
+// Robots 是 robots.txt 中适用于某个 User-Agent 的规则（RFC 9309）
+type Robots struct {
+	rules      []robotsRule
+	CrawlDelay time.Duration // 两次请求之间的最小间隔，0 表示没有要求
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int // 规则的长度，匹配的规则中最长的生效
+	pattern *regexp.Regexp
+}
+
+// allowAll 用于 robots.txt 不存在（4xx）的网站
+var allowAll = &Robots{}
+
+// ParseRobots 解析 robots.txt，选出适用于 userAgent 的规则组：
+//
+//   - User-agent 与 userAgent 的产品名（"study-bot/0.1" 中的 "study-bot"）相同的组优先，不区分大小写；
+//     没有时使用 "User-agent: *" 的组；同名的多个组合并
+//   - Allow/Disallow 的路径支持 "*"（任意字符）和结尾的 "$"（匹配到路径末尾）
+//   - 多条规则匹配时最长的生效，一样长时 Allow 优先；空的 Disallow 不限制任何路径
+//   - Crawl-delay 以秒为单位，可以是小数
+func ParseRobots(userAgent, body string) *Robots {
+	type group struct {
+		agents []string
+		lines  [][2]string
+	}
+	var (
+		groups []*group
+		cur    *group
+	)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if key == "user-agent" {
+			// 规则之后的 User-agent 开始一个新组，连续的 User-agent 属于同一组
+			if cur == nil || len(cur.lines) > 0 {
+				cur = &group{}
+				groups = append(groups, cur)
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+			continue
+		}
+		if cur != nil {
+			cur.lines = append(cur.lines, [2]string{key, value})
+		}
+	}
+
+	product := strings.ToLower(userAgent)
+	if i := strings.IndexAny(product, "/ "); i >= 0 {
+		product = product[:i]
+	}
+	var specific, wildcard [][2]string
+	// 同一组可能同时列出 * 和爬虫自己的名字，不论先后都以名字为准
+	for _, g := range groups {
+		switch {
+		case slices.Contains(g.agents, product):
+			specific = append(specific, g.lines...)
+		case slices.Contains(g.agents, "*"):
+			wildcard = append(wildcard, g.lines...)
+		}
+	}
+	lines := specific
+	if lines == nil {
+		lines = wildcard
+	}
+
+	r := &Robots{}
+	for _, kv := range lines {
+		switch kv[0] {
+		case "allow", "disallow":
+			if kv[1] == "" {
+				continue
+			}
+			r.rules = append(r.rules, robotsRule{
+				allow:   kv[0] == "allow",
+				length:  len(kv[1]),
+				pattern: robotsPattern(kv[1]),
+			})
+		case "crawl-delay":
+			if secs, err := strconv.ParseFloat(kv[1], 64); err == nil && secs > 0 {
+				r.CrawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+	return r
+}
+
+// robotsPattern 把规则路径转成从路径开头匹配的正则
+func robotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(path), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// Allowed 报告是否允许抓取 path（包括查询部分，如 "/search?q=go"）
+func (r *Robots) Allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	best, allowed := -1, true
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > best || (rule.length == best && rule.allow) {
+			best, allowed = rule.length, rule.allow
+		}
+	}
+	return allowed
+}
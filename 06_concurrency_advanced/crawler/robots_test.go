@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+)
+
+const robotsBody = `# 示例
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Crawl-delay: 2
+
+User-agent: Study-Bot
+User-agent: other-bot
+Disallow: /tmp/
+Allow: /tmp/ok$
+Disallow: /*.pdf$
+Allow: /same
+Disallow: /same
+Crawl-delay: 0.5
+
+user-agent: study-bot
+disallow: /search?  # 同名的组合并
+`
+
+func TestParseRobots(t *testing.T) {
+	cases := []struct {
+		agent string
+		delay time.Duration
+		paths map[string]bool
+	}{
+		{"study-bot/0.1", 500 * time.Millisecond, map[string]bool{
+			"/tmp/x":       false,
+			"/tmp/ok":      true,
+			"/tmp/ok2":     false,
+			"/a.pdf":       false,
+			"/a.pdf?v=1":   true,
+			"/search?q=go": false,
+			"/same":        true, // 一样长时 Allow 优先
+			"/private":     true, // 有专门的组时不使用 * 组
+			"/robots.txt":  true,
+		}},
+		{"some-bot", 2 * time.Second, map[string]bool{
+			"/private":          false,
+			"/private/public/x": true,
+			"/tmp/x":            true,
+		}},
+	}
+	for _, c := range cases {
+		r := ParseRobots(c.agent, robotsBody)
+		if r.CrawlDelay != c.delay {
+			t.Errorf("%s: CrawlDelay = %v, want %v", c.agent, r.CrawlDelay, c.delay)
+		}
+		for path, want := range c.paths {
+			if got := r.Allowed(path); got != want {
+				t.Errorf("%s: Allowed(%q) = %v, want %v", c.agent, path, got, want)
+			}
+		}
+	}
+	// * 和爬虫名字在同一组、* 写在前面时，仍然算作专门给这个爬虫的组，不再使用其他 * 组
+	shared := ParseRobots("study-bot/0.1", `User-agent: *
+Disallow: /
+
+User-agent: *
+User-agent: study-bot
+Disallow: /admin
+`)
+	if !shared.Allowed("/page") || shared.Allowed("/admin") {
+		t.Errorf("* 在前的共享组: Allowed(/page) = %v、Allowed(/admin) = %v, want true、false",
+			shared.Allowed("/page"), shared.Allowed("/admin"))
+	}
+	if !ParseRobots("study-bot", "").Allowed("/anything") {
+		t.Error("空的 robots.txt 不应限制任何路径")
+	}
+}
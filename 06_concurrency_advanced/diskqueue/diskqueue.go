@@ -0,0 +1,548 @@
+// Package diskqueue 是一个落盘的持久化任务队列，进程退出或崩溃后任务不会丢失。
+//
+// 队列状态保存在一个只追加的日志文件中，每行一条 JSON 记录（入队、租约、确认、否认、死信，
+// 以及 Compact 写入的下一个消息 ID）。打开队列时重放日志恢复状态；崩溃时写了一半的最后一行会被截掉，
+// 写入或 fsync 失败时日志截回写入前的长度。
+//
+// 投递语义是"至少一次"：
+//
+//   - Receive 取出一条消息并加上租约，租约期间（VisibilityTimeout）其他消费者看不到它
+//   - 处理成功调用 Ack 删除；失败调用 Nack 立即重新投递
+//   - 消费者崩溃、没有 Ack 也没有 Nack 时，租约到期后消息被重新投递
+//   - 投递 MaxAttempts 次仍未成功的消息进入死信，不再投递
+//
+// 同一个队列文件同一时间只能被一个进程打开。
+package diskqueue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+var (
+	ErrEmpty     = errors.New("队列中没有可投递的消息")
+	ErrClosed    = errors.New("队列已关闭")
+	ErrNotFound  = errors.New("消息不存在或已确认")
+	ErrLeaseLost = errors.New("租约已失效，消息已被重新投递")
+	ErrCorrupt   = errors.New("队列日志损坏")
+)
+
+// Options 配置队列，零值字段使用默认值
+type Options struct {
+	VisibilityTimeout time.Duration // 租约时长，默认 30 秒
+	MaxAttempts       int           // 最多投递次数，默认 5
+	NoSync            bool          // 不在每次写入后 fsync（更快，但断电可能丢失最近的记录）
+	Clock             workerpool.Clock
+}
+
+// Message 是队列中的一条消息
+type Message struct {
+	ID        uint64
+	Body      []byte
+	Attempts  int // 已投递次数（包括本次）；Ack/Nack 时用它确认租约仍属于自己
+	Enqueued  time.Time
+	LastError string // 最近一次 Nack 或进入死信的原因
+}
+
+// Stats 是队列的状态
+type Stats struct {
+	Ready    int // 可投递（包括租约已过期）的消息数
+	InFlight int // 租约中的消息数
+	Dead     int // 死信数
+	Records  int // 日志记录数，远大于消息数时可以 Compact
+}
+
+// ========== 日志记录 ==========
+
+const (
+	opEnqueue = "enqueue"
+	opLease   = "lease"
+	opAck     = "ack"
+	opNack    = "nack"
+	opDead    = "dead"
+	opMeta    = "meta" // Compact 写在开头，记录下一个消息 ID，避免确认过的 ID 在重启后被重复使用
+)
+
+type record struct {
+	Op      string `json:"op"`
+	ID      uint64 `json:"id"`
+	Body    []byte `json:"body,omitempty"`
+	At      int64  `json:"at,omitempty"`    // 入队时间（UnixNano）
+	Until   int64  `json:"until,omitempty"` // 租约到期时间（UnixNano）
+	Attempt int    `json:"attempt,omitempty"`
+	Err     string `json:"err,omitempty"`
+	Next    uint64 `json:"next,omitempty"` // meta：下一个消息 ID
+}
+
+type entry struct {
+	msg   Message
+	until time.Time // 租约到期时间，零值表示没有租约
+}
+
+func (e *entry) leasedAt(now time.Time) bool {
+	return !e.until.IsZero() && now.Before(e.until)
+}
+
+// ========== Queue ==========
+
+// logFile 是日志文件需要的操作，*os.File 实现了它
+type logFile interface {
+	io.ReadWriteSeeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// Queue 是持久化队列，可以被多个 goroutine 同时使用
+type Queue struct {
+	path  string
+	opts  Options
+	clock workerpool.Clock
+
+	mu      sync.Mutex
+	f       logFile
+	size    int64 // 日志中完整记录的字节数，写入失败时截回这个长度
+	nextID  uint64
+	live    map[uint64]*entry // 未确认的消息
+	order   []uint64          // live 的 ID，升序，先入队的先投递
+	dead    []Message
+	records int
+	notify  chan struct{} // 有新消息可投递时关闭并替换，唤醒等待中的 Receive
+	closed  bool
+}
+
+// Open 打开（不存在时创建）队列文件并重放日志
+func Open(path string, opts Options) (*Queue, error) {
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 30 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Clock == nil {
+		opts.Clock = workerpool.RealClock()
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	q := &Queue{
+		path:   path,
+		opts:   opts,
+		clock:  opts.Clock,
+		f:      f,
+		nextID: 1,
+		live:   make(map[uint64]*entry),
+		notify: make(chan struct{}),
+	}
+	if err := q.replay(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return q, nil
+}
+
+// replay 读取整个日志恢复状态；最后一行不完整（崩溃时写了一半）时截掉
+func (q *Queue) replay() error {
+	r := bufio.NewReader(q.f)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(data)) > 0 {
+				// 没有换行结尾的最后一行是没写完的记录
+				if err := q.f.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var rec record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("%w: 第 %d 行: %v", ErrCorrupt, line, err)
+		}
+		if err := q.apply(rec); err != nil {
+			return fmt.Errorf("%w: 第 %d 行: %v", ErrCorrupt, line, err)
+		}
+		offset += int64(len(data))
+	}
+	q.size = offset
+	_, err := q.f.Seek(offset, io.SeekStart)
+	return err
+}
+
+// apply 把一条记录应用到内存状态
+func (q *Queue) apply(rec record) error {
+	q.records++
+	switch rec.Op {
+	case opMeta:
+		q.nextID = max(q.nextID, rec.Next)
+		return nil
+	case opEnqueue:
+		if _, dup := q.live[rec.ID]; dup {
+			return fmt.Errorf("重复的消息 %d", rec.ID)
+		}
+		q.live[rec.ID] = &entry{msg: Message{
+			ID:        rec.ID,
+			Body:      rec.Body,
+			Attempts:  rec.Attempt,
+			Enqueued:  time.Unix(0, rec.At),
+			LastError: rec.Err,
+		}}
+		q.order = append(q.order, rec.ID)
+		q.nextID = max(q.nextID, rec.ID+1)
+		return nil
+	}
+
+	e, ok := q.live[rec.ID]
+	if !ok {
+		return fmt.Errorf("%s 引用了不存在的消息 %d", rec.Op, rec.ID)
+	}
+	switch rec.Op {
+	case opLease:
+		e.msg.Attempts = rec.Attempt
+		e.until = time.Unix(0, rec.Until)
+	case opNack:
+		e.until = time.Time{}
+		e.msg.LastError = rec.Err
+	case opAck:
+		q.remove(rec.ID)
+	case opDead:
+		e.msg.LastError = rec.Err
+		q.dead = append(q.dead, e.msg)
+		q.remove(rec.ID)
+	default:
+		return fmt.Errorf("未知的操作 %q", rec.Op)
+	}
+	return nil
+}
+
+func (q *Queue) remove(id uint64) {
+	delete(q.live, id)
+	i := sort.Search(len(q.order), func(i int) bool { return q.order[i] >= id })
+	if i < len(q.order) && q.order[i] == id {
+		q.order = append(q.order[:i], q.order[i+1:]...)
+	}
+}
+
+// write 先把记录写入日志（默认 fsync），成功后再修改内存状态
+func (q *Queue) write(rec record) error {
+	if q.closed {
+		return ErrClosed
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := q.append(append(line, '\n')); err != nil {
+		return err
+	}
+	return q.apply(rec)
+}
+
+// append 把一行写到日志末尾。写了一部分或 fsync 失败时截回写入前的长度：
+// 否则内存中没有生效的记录会留在日志里，下次重放时才生效，或者后面的记录接在半行之后把日志写坏。
+// 截断也失败时日志状态未知，关闭队列，不再写入。
+func (q *Queue) append(line []byte) error {
+	_, err := q.f.Write(line)
+	if err == nil && !q.opts.NoSync {
+		err = q.f.Sync()
+	}
+	if err == nil {
+		q.size += int64(len(line))
+		return nil
+	}
+	if rerr := q.rollback(); rerr != nil {
+		q.closed = true
+		q.wakeLocked()
+		q.f.Close()
+		return fmt.Errorf("%w（截断日志失败，队列已关闭: %v）", err, rerr)
+	}
+	return err
+}
+
+// rollback 把日志截回 q.size 并把写入位置移回末尾
+func (q *Queue) rollback() error {
+	if err := q.f.Truncate(q.size); err != nil {
+		return err
+	}
+	_, err := q.f.Seek(q.size, io.SeekStart)
+	return err
+}
+
+func (q *Queue) wakeLocked() {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// ========== 生产和消费 ==========
+
+// Enqueue 追加一条消息，返回消息 ID
+func (q *Queue) Enqueue(body []byte) (uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	id := q.nextID
+	if err := q.write(record{Op: opEnqueue, ID: id, Body: body, At: q.clock.Now().UnixNano()}); err != nil {
+		return 0, err
+	}
+	q.wakeLocked()
+	return id, nil
+}
+
+// TryReceive 取出最早的可投递消息并加上租约；没有时返回 ErrEmpty
+func (q *Queue) TryReceive() (Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	m, _, err := q.receiveLocked()
+	return m, err
+}
+
+// Receive 等待并取出一条消息，直到有消息、ctx 取消或队列关闭
+func (q *Queue) Receive(ctx context.Context) (Message, error) {
+	for {
+		q.mu.Lock()
+		m, wait, err := q.receiveLocked()
+		notify := q.notify
+		q.mu.Unlock()
+		if !errors.Is(err, ErrEmpty) {
+			return m, err
+		}
+
+		// 没有可投递的消息：等新消息、最早的租约到期或 ctx 取消
+		var (
+			timer   workerpool.Timer
+			expired <-chan time.Time
+		)
+		if wait > 0 {
+			timer = q.clock.NewTimer(wait)
+			expired = timer.C()
+		}
+		select {
+		case <-notify:
+		case <-expired:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return Message{}, err
+		}
+	}
+}
+
+// receiveLocked 按 ID 顺序找第一条没有租约（或租约已过期）的消息。
+// 租约过期且已投递 MaxAttempts 次的消息转入死信。没有消息时返回距离最早租约到期的时间。
+func (q *Queue) receiveLocked() (Message, time.Duration, error) {
+	if q.closed {
+		return Message{}, 0, ErrClosed
+	}
+	now := q.clock.Now()
+	var wait time.Duration
+	for i := 0; i < len(q.order); i++ {
+		id := q.order[i]
+		e := q.live[id]
+		if e.leasedAt(now) {
+			if d := e.until.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		if !e.until.IsZero() && e.msg.Attempts >= q.opts.MaxAttempts {
+			reason := fmt.Sprintf("第 %d 次投递处理超时", e.msg.Attempts)
+			if err := q.write(record{Op: opDead, ID: id, Err: reason}); err != nil {
+				return Message{}, 0, err
+			}
+			i-- // remove 删除了 order[i]
+			continue
+		}
+		until := now.Add(q.opts.VisibilityTimeout)
+		if err := q.write(record{Op: opLease, ID: id, Attempt: e.msg.Attempts + 1, Until: until.UnixNano()}); err != nil {
+			return Message{}, 0, err
+		}
+		return e.msg, 0, nil
+	}
+	return Message{}, wait, ErrEmpty
+}
+
+// leasedLocked 检查 m 的租约是否仍然属于调用方
+func (q *Queue) leasedLocked(m Message) (*entry, error) {
+	e, ok := q.live[m.ID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrNotFound, m.ID)
+	}
+	if e.until.IsZero() || e.msg.Attempts != m.Attempts {
+		return nil, fmt.Errorf("%w: 消息 %d 第 %d 次投递", ErrLeaseLost, m.ID, m.Attempts)
+	}
+	return e, nil
+}
+
+// Ack 确认消息处理成功并删除
+func (q *Queue) Ack(m Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.leasedLocked(m); err != nil {
+		return err
+	}
+	return q.write(record{Op: opAck, ID: m.ID})
+}
+
+// Nack 报告处理失败：未达到 MaxAttempts 时立即重新投递，否则转入死信
+func (q *Queue) Nack(m Message, reason error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, err := q.leasedLocked(m)
+	if err != nil {
+		return err
+	}
+	text := "未知错误"
+	if reason != nil {
+		text = reason.Error()
+	}
+	if e.msg.Attempts >= q.opts.MaxAttempts {
+		return q.write(record{Op: opDead, ID: m.ID, Err: text})
+	}
+	if err := q.write(record{Op: opNack, ID: m.ID, Err: text}); err != nil {
+		return err
+	}
+	q.wakeLocked()
+	return nil
+}
+
+// ========== 查询和维护 ==========
+
+// Stats 返回当前状态
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.clock.Now()
+	s := Stats{Dead: len(q.dead), Records: q.records}
+	for _, e := range q.live {
+		if e.leasedAt(now) {
+			s.InFlight++
+		} else {
+			s.Ready++
+		}
+	}
+	return s
+}
+
+// DeadLetters 返回死信，按进入死信的顺序
+func (q *Queue) DeadLetters() []Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Message(nil), q.dead...)
+}
+
+// Compact 用当前状态重写日志，丢弃已确认消息和过时的租约记录。
+// 先写临时文件再改名，中途崩溃时原日志保持不变。
+func (q *Queue) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	recs := []record{{Op: opMeta, Next: q.nextID}}
+	for _, m := range q.dead {
+		recs = append(recs,
+			record{Op: opEnqueue, ID: m.ID, Body: m.Body, At: m.Enqueued.UnixNano(), Attempt: m.Attempts},
+			record{Op: opDead, ID: m.ID, Err: m.LastError})
+	}
+	for _, id := range q.order {
+		e := q.live[id]
+		recs = append(recs, record{Op: opEnqueue, ID: id, Body: e.msg.Body, At: e.msg.Enqueued.UnixNano(), Attempt: e.msg.Attempts, Err: e.msg.LastError})
+		if !e.until.IsZero() {
+			recs = append(recs, record{Op: opLease, ID: id, Attempt: e.msg.Attempts, Until: e.until.UnixNano()})
+		}
+	}
+
+	tmp := q.path + ".tmp"
+	if err := writeRecords(tmp, recs); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(q.path))
+
+	f, err := os.OpenFile(q.path, os.O_RDWR, 0o644)
+	var size int64
+	if err == nil {
+		if size, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		q.closed = true // 原文件句柄指向已被替换的旧日志，不能再写
+		q.wakeLocked()
+		q.f.Close()
+		return err
+	}
+	q.f.Close()
+	q.f = f
+	q.size = size
+	q.records = len(recs)
+	return nil
+}
+
+func writeRecords(path string, recs []record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir 让目录项（改名）落盘；部分平台不支持对目录 fsync，忽略错误
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// Close 关闭队列，等待中的 Receive 返回 ErrClosed
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	q.wakeLocked()
+	return q.f.Close()
+}
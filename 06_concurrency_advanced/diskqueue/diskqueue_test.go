@@ -0,0 +1,322 @@
+package diskqueue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+// workerEnv 非空时测试二进制作为子进程 worker 运行，值为队列文件路径
+const workerEnv = "DISKQUEUE_TEST_WORKER"
+
+const visibility = 300 * time.Millisecond
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(workerEnv); path != "" {
+		runWorker(path)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runWorker 取出一个任务，报告给父进程后假装处理很久，等着被 kill
+func runWorker(path string) {
+	q, err := Open(path, Options{VisibilityTimeout: visibility})
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+	m, err := q.Receive(context.Background())
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("received %d %s %d\n", m.ID, m.Body, m.Attempts)
+	time.Sleep(time.Hour)
+}
+
+func open(t *testing.T, path string, opts Options) *Queue {
+	t.Helper()
+	q, err := Open(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// 处理中途 kill worker 进程，重新打开队列后租约到期时同一个任务被再次投递
+func TestRedeliveryAfterWorkerKilled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kill.queue")
+	q := open(t, path, Options{VisibilityTimeout: visibility})
+	id, err := q.Enqueue([]byte("发送欢迎邮件"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), workerEnv+"="+path)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	line, _ := bufio.NewReader(stdout).ReadString('\n')
+	cmd.Process.Kill()
+	cmd.Wait()
+	if want := fmt.Sprintf("received %d 发送欢迎邮件 1", id); strings.TrimSpace(line) != want {
+		t.Fatalf("子进程输出 %q, want %q", line, want)
+	}
+
+	q = open(t, path, Options{VisibilityTimeout: visibility})
+	defer q.Close()
+	if _, err := q.TryReceive(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("重启后租约未到期时 TryReceive err = %v, want ErrEmpty", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m, err := q.Receive(ctx)
+	if err != nil || m.ID != id || string(m.Body) != "发送欢迎邮件" || m.Attempts != 2 {
+		t.Fatalf("租约到期后 Receive = %+v, %v, want 同一个任务第 2 次投递", m, err)
+	}
+	if err := q.Ack(m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTornLastLineIsTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "torn.queue")
+	q := open(t, path, Options{})
+	q.Enqueue([]byte("任务 A"))
+	q.Close()
+
+	// 模拟崩溃时只写了一半的记录
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"enqueue","id":2,"bo`)
+	f.Close()
+
+	q = open(t, path, Options{})
+	if n := q.Stats().Ready; n != 1 {
+		t.Fatalf("重放后 Ready = %d, want 1", n)
+	}
+	if id, err := q.Enqueue([]byte("任务 B")); err != nil || id != 2 {
+		t.Fatalf("截断后 Enqueue = %d, %v, want 2", id, err)
+	}
+	q.Close()
+
+	q = open(t, path, Options{})
+	defer q.Close()
+	if n := q.Stats().Ready; n != 2 {
+		t.Fatalf("再次打开后 Ready = %d, want 2", n)
+	}
+}
+
+func TestDeadLetterSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.queue")
+	q := open(t, path, Options{MaxAttempts: 3})
+	q.Enqueue([]byte("格式错误的订单"))
+	for range 3 {
+		m, err := q.TryReceive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.Nack(m, fmt.Errorf("解析失败（第 %d 次）", m.Attempts))
+	}
+	if _, err := q.TryReceive(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("失败 3 次后 TryReceive err = %v, want ErrEmpty", err)
+	}
+	q.Close()
+
+	q = open(t, path, Options{MaxAttempts: 3})
+	defer q.Close()
+	dead := q.DeadLetters()
+	if len(dead) != 1 || dead[0].Attempts != 3 || dead[0].LastError != "解析失败（第 3 次）" {
+		t.Fatalf("重启后死信 = %+v", dead)
+	}
+}
+
+func TestLeaseLost(t *testing.T) {
+	clock := workerpool.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	q := open(t, filepath.Join(t.TempDir(), "lease.queue"), Options{
+		VisibilityTimeout: time.Minute,
+		MaxAttempts:       2,
+		Clock:             clock,
+	})
+	defer q.Close()
+	q.Enqueue([]byte("生成报表"))
+
+	slow, _ := q.TryReceive()
+	clock.Advance(61 * time.Second)
+	fast, err := q.TryReceive()
+	if err != nil || fast.ID != slow.ID || fast.Attempts != 2 {
+		t.Fatalf("租约过期后 TryReceive = %+v, %v, want 第 2 次投递", fast, err)
+	}
+	if err := q.Ack(slow); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("慢 worker 的 Ack err = %v, want ErrLeaseLost", err)
+	}
+	clock.Advance(61 * time.Second)
+	if _, err := q.TryReceive(); !errors.Is(err, ErrEmpty) || q.Stats().Dead != 1 {
+		t.Fatalf("第 2 次投递也超时后 err = %v，%+v，want 转入死信", err, q.Stats())
+	}
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compact.queue")
+	q := open(t, path, Options{NoSync: true})
+	for i := 1; i <= 100; i++ {
+		q.Enqueue(fmt.Appendf(nil, "任务 %d", i))
+	}
+	for range 95 {
+		m, _ := q.TryReceive()
+		q.Ack(m)
+	}
+	leased, _ := q.TryReceive() // 第 96 个处于租约中
+	before, _ := os.Stat(path)
+	if err := q.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.Stat(path); after.Size() >= before.Size() {
+		t.Fatalf("压缩后 %d 字节，压缩前 %d 字节", after.Size(), before.Size())
+	}
+	if id, err := q.Enqueue([]byte("任务 101")); err != nil || id != 101 {
+		t.Fatalf("压缩后 Enqueue = %d, %v, want 101", id, err)
+	}
+	if err := q.Ack(leased); err != nil {
+		t.Fatalf("压缩后 Ack 租约中的消息: %v", err)
+	}
+	q.Close()
+
+	q = open(t, path, Options{})
+	defer q.Close()
+	m, _ := q.TryReceive()
+	if s := q.Stats(); s.Ready != 4 || string(m.Body) != "任务 97" {
+		t.Fatalf("重新打开后 %+v，下一条 %s", s, m.Body)
+	}
+}
+
+// 全部确认后压缩，日志里不剩任何消息，重启后也不能重复使用已分配过的 ID
+func TestCompactKeepsNextID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.queue")
+	q := open(t, path, Options{NoSync: true})
+	for range 3 {
+		q.Enqueue([]byte("任务"))
+	}
+	for range 3 {
+		m, _ := q.TryReceive()
+		q.Ack(m)
+	}
+	if err := q.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	q = open(t, path, Options{})
+	defer q.Close()
+	if id, err := q.Enqueue([]byte("新任务")); err != nil || id != 4 {
+		t.Fatalf("压缩并重启后 Enqueue = %d, %v, want 4", id, err)
+	}
+}
+
+// faultyFile 包装日志文件，按设置让写入只写一半或 fsync 失败
+type faultyFile struct {
+	*os.File
+	partial   bool
+	failSync  bool
+	failTrunc bool
+}
+
+var errInjected = errors.New("注入的 I/O 错误")
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.partial {
+		n, _ := f.File.Write(p[:len(p)/2])
+		return n, errInjected
+	}
+	return f.File.Write(p)
+}
+
+func (f *faultyFile) Sync() error {
+	if f.failSync {
+		return errInjected
+	}
+	return f.File.Sync()
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	if f.failTrunc {
+		return errInjected
+	}
+	return f.File.Truncate(size)
+}
+
+// 写入失败时日志截回写入前的长度：失败的记录不会在重启后生效，后续写入也不会接在半行之后
+func TestFailedWriteIsRolledBack(t *testing.T) {
+	for _, fault := range []string{"写了一半", "fsync 失败"} {
+		t.Run(fault, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fault.queue")
+			q := open(t, path, Options{})
+			defer q.Close()
+			q.Enqueue([]byte("任务 A"))
+			before, _ := os.Stat(path)
+
+			f := &faultyFile{File: q.f.(*os.File), partial: fault == "写了一半", failSync: fault == "fsync 失败"}
+			q.f = f
+			if _, err := q.Enqueue([]byte("任务 B")); !errors.Is(err, errInjected) {
+				t.Fatalf("Enqueue err = %v, want 注入的错误", err)
+			}
+			if after, _ := os.Stat(path); after.Size() != before.Size() {
+				t.Fatalf("失败后日志 %d 字节，want 截回 %d 字节", after.Size(), before.Size())
+			}
+
+			f.partial, f.failSync = false, false
+			if id, err := q.Enqueue([]byte("任务 C")); err != nil || id != 2 {
+				t.Fatalf("恢复后 Enqueue = %d, %v, want 2", id, err)
+			}
+			q.Close()
+
+			q = open(t, path, Options{})
+			var bodies []string
+			for {
+				m, err := q.TryReceive()
+				if err != nil {
+					break
+				}
+				bodies = append(bodies, string(m.Body))
+			}
+			if got := strings.Join(bodies, ","); got != "任务 A,任务 C" {
+				t.Fatalf("重启后的消息 %s, want 任务 A,任务 C", got)
+			}
+		})
+	}
+}
+
+// 截断也失败时日志状态未知，队列关闭
+func TestFailedRollbackClosesQueue(t *testing.T) {
+	q := open(t, filepath.Join(t.TempDir(), "fault.queue"), Options{})
+	defer q.Close()
+	q.f = &faultyFile{File: q.f.(*os.File), failSync: true, failTrunc: true}
+	if _, err := q.Enqueue([]byte("任务")); !errors.Is(err, errInjected) {
+		t.Fatalf("Enqueue err = %v, want 注入的错误", err)
+	}
+	if _, err := q.Enqueue([]byte("任务")); !errors.Is(err, ErrClosed) {
+		t.Fatalf("截断失败后 Enqueue err = %v, want ErrClosed", err)
+	}
+}
@@ -2,15 +2,23 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"html"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
+
+	"golang_study/06_concurrency_advanced/crawler"
+	"golang_study/06_concurrency_advanced/pipeline"
+	"golang_study/06_concurrency_advanced/workerpool"
 )
 
-// URL 列表（模拟要爬取的网页）
+// 种子 URL 列表（默认由 newWeb 在本机模拟这些网站）
 var urls = []string{
 	"https://golang.org",
 	"https://github.com",
@@ -29,56 +37,159 @@ var urls = []string{
 	"https://example3.com",
 }
 
-type CrawlResult struct {
-	URL      string
-	Duration time.Duration
-	Success  bool
+// CrawlResult 是一个页面的抓取结果：状态码、错误分类（Category）、字节数、重定向链和请求次数
+type CrawlResult = crawler.Result
+
+// spider 抓取真实的 HTTP 页面；默认指向本机模拟的网站，加 -live 参数时访问真实网络
+var spider *crawler.Crawler
+
+// fakeWeb 在本机的 httptest.Server 上模拟多个网站：按 Host 和路径找到登记的处理函数，没有登记的返回 404。
+// 每个请求耗时 200-800ms，10% 的请求返回 500。
+type fakeWeb struct {
+	handlers map[string]http.HandlerFunc // "主机/路径" → 处理函数
+	server   *httptest.Server
 }
 
-func crawl(url string) CrawlResult {
-	start := time.Now()
+func (f *fakeWeb) handle(raw string, h http.HandlerFunc) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	f.handlers[u.Host+"/"+strings.TrimPrefix(u.Path, "/")] = h
+}
 
-	// 模拟网络延迟（200-800ms）
-	delay := time.Duration(rand.Intn(600)+200) * time.Millisecond
-	time.Sleep(delay)
+// page 登记一个 HTML 页面，内容是指向 links 的链接
+func (f *fakeWeb) page(raw string, links ...string) {
+	f.handle(raw, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "<html><body>")
+		for _, l := range links {
+			fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(l), html.EscapeString(l))
+		}
+		fmt.Fprintln(w, "</body></html>")
+	})
+}
 
-	success := rand.Intn(10) > 0 // 90% 成功率
+func (f *fakeWeb) robots(site, body string) {
+	f.handle(site+"/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, body)
+	})
+}
 
-	return CrawlResult{
-		URL:      url,
-		Duration: time.Since(start),
-		Success:  success,
+func (f *fakeWeb) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-time.After(time.Duration(rand.Intn(600)+200) * time.Millisecond):
+	case <-r.Context().Done():
+		return
+	}
+	if rand.Float64() < 0.1 {
+		http.Error(w, "模拟的服务器错误", http.StatusInternalServerError)
+		return
+	}
+	h, ok := f.handlers[r.Host+r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
 	}
+	h(w, r)
 }
 
-func worker(id int, jobs <-chan string, results chan<- CrawlResult, wg *sync.WaitGroup, ctx context.Context) {
-	defer wg.Done()
+// client 返回把所有请求（包括 https）都发到本地服务的 http.Client，Host 头保持原样
+func (f *fakeWeb) client() *http.Client {
+	local, _ := url.Parse(f.server.URL)
+	transport := f.server.Client().Transport
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			out := req.Clone(req.Context())
+			out.Host = req.URL.Host
+			out.URL.Scheme, out.URL.Host = local.Scheme, local.Host
+			resp, err := transport.RoundTrip(out)
+			if err == nil {
+				resp.Request = req
+			}
+			return resp, err
+		}),
+	}
+}
 
-	for url := range jobs {
-		select {
-		case <-ctx.Done():
-			fmt.Printf("Worker-%d: 收到取消信号\n", id)
-			return
-		default:
-			fmt.Printf("Worker-%d: 爬取 %s\n", id, url)
-			result := crawl(url)
-			results <- result
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// newWeb 为 urls 中的每个网站模拟几个页面：首页链接到本站的 /about 和 /news（/news 再链接两篇文章），
+// 以及下一个网站的首页。
+// golang.org 的 robots.txt 禁止抓取 /news/2，github.com 要求两次请求间隔 0.5 秒，
+// zhihu.com 的 /about 重定向到 /about-us，jd.com 的 /news/2 已删除（404），taobao.com 的 /news/1 超过 1 MiB。
+func newWeb() *fakeWeb {
+	web := &fakeWeb{handlers: make(map[string]http.HandlerFunc)}
+	web.robots("https://golang.org", "User-agent: *\nDisallow: /news/2\n")
+	web.robots("https://github.com", "User-agent: *\nCrawl-delay: 0.5\n")
+	for i, url := range urls {
+		next := urls[(i+1)%len(urls)]
+		web.page(url, "/about", "/news", next)
+		web.page(url+"/about", "/")
+		web.page(url+"/news", "/news/1", "/news/2", "/")
+		web.page(url+"/news/1", "/news")
+		web.page(url+"/news/2", "/news")
+	}
+	web.handle("https://zhihu.com/about", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/about-us", http.StatusMovedPermanently)
+	})
+	web.page("https://zhihu.com/about-us", "/")
+	web.handle("https://jd.com/news/2", http.NotFound)
+	web.handle("https://taobao.com/news/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("<p>双十一</p>", 200_000)))
+	})
+	web.server = httptest.NewServer(web)
+	return web
+}
+
+func crawl(url string) CrawlResult {
+	return spider.Fetch(context.Background(), url)
+}
+
+// firstSeen 返回去重用的过滤函数，只保留第一次出现的 URL（按规范化后的地址比较）
+func firstSeen() func(string) bool {
+	visited := make(map[string]bool)
+	return func(raw string) bool {
+		key := raw
+		if u, err := crawler.Normalize(raw); err == nil {
+			key = crawler.Key(u)
+		}
+		if visited[key] {
+			return false
 		}
+		visited[key] = true
+		return true
 	}
 }
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
+	live := flag.Bool("live", false, "访问真实网络，而不是本机模拟的网站")
+	depth := flag.Int("depth", 1, "并发爬虫的最大深度（种子为第 0 层）")
+	flag.Parse()
+
+	var client *http.Client
+	if !*live {
+		web := newWeb()
+		defer web.server.Close()
+		client = web.client()
+	}
+	// 超时、连接失败和 5xx 最多重试 2 次，从 200ms 起指数退避
+	spider = crawler.New(crawler.Options{Client: client, Retries: 2})
+
 	fmt.Println("===== 串行爬虫（Step 1）=====")
 	start := time.Now()
 
 	for _, url := range urls {
 		result := crawl(url)
-		if result.Success {
-			fmt.Printf("成功爬取: %s (耗时: %v)\n", result.URL, result.Duration)
+		if result.Category == crawler.OK {
+			fmt.Printf("成功爬取: %s (状态 %d，%d 字节，耗时: %v)\n", result.URL, result.Status, result.Bytes, result.Duration)
 		} else {
-			fmt.Printf("爬取失败: %s (耗时: %v)\n", result.URL, result.Duration)
+			fmt.Printf("爬取失败: %s [%v，请求 %d 次] (耗时: %v)\n", result.URL, result.Category, result.Attempts, result.Duration)
 		}
 	}
 
@@ -94,62 +205,87 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	jobs := make(chan string, 10)
-	results := make(chan CrawlResult, 15)
-	var wg sync.WaitGroup
-
-	// Step 5: 使用 atomic 统计成功/失败/跳过数量
-	var successCount, failCount, skippedCount int64
+	// Step 5: 使用 atomic 统计成功/失败/取消数量，重复的 URL 由爬虫规范化后跳过
+	var successCount, failCount, cancelledCount int64
+	failures := make(map[crawler.Category]int) // 按分类统计失败，只在收集结果的循环中使用
 
-	// 启动 5 个 worker
-	for i := 1; i <= 5; i++ {
-		wg.Add(1)
-		go worker(i, jobs, results, &wg, ctx)
-	}
-
-	// 发送任务（带去重）
-	go func() {
-		visited := make(map[string]bool)
-
-		for i, url := range urls {
-			if visited[url] {
-				fmt.Printf("⏭️  [%d] 跳过重复: %s\n", i+1, url)
-				atomic.AddInt64(&skippedCount, 1)
-				continue
-			}
-			visited[url] = true
-			jobs <- url
-		}
-		close(jobs)
-	}()
+	// 从 urls 出发按层抓取同一网站的页面；worker 数在 2~8 之间按队列长度自动伸缩，队列容量 10。
+	// 每个网站每秒最多 4 个请求、同时最多 2 个连接，所有网站合计每秒最多 30 个请求
+	bfs := crawler.New(crawler.Options{
+		Client:          client,
+		MaxDepth:        *depth,
+		HostQPS:         4,
+		HostConcurrency: 2,
+		GlobalQPS:       30,
+		Retries:         2,
+		Pool: workerpool.Options{
+			MinWorkers:  2,
+			MaxWorkers:  8,
+			QueueSize:   10,
+			IdleTimeout: 500 * time.Millisecond,
+		},
+	})
 
 	// 收集结果
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	for result := range results {
-		if result.Success {
-			fmt.Printf("成功爬取: %s (耗时: %v)\n", result.URL, result.Duration)
+	for r := range bfs.Crawl(ctx, urls...) {
+		retried := ""
+		if r.Attempts > 1 {
+			retried = fmt.Sprintf("，请求 %d 次", r.Attempts)
+		}
+		switch r.Category {
+		case crawler.Canceled:
+			fmt.Printf("已取消: %s (%v)\n", r.URL, r.Err)
+			atomic.AddInt64(&cancelledCount, 1)
+		case crawler.Disallowed:
+			fmt.Printf("robots.txt 禁止: %s\n", r.URL)
+		case crawler.OK:
+			fmt.Printf("Worker-%d: 成功爬取 [深度 %d] %s (状态 %d，%d 字节，链接 %d 个，耗时: %v%s)\n",
+				r.Worker, r.Depth, r.URL, r.Status, r.Bytes, len(r.Links), r.Duration.Round(time.Millisecond), retried)
+			if len(r.Redirects) > 0 {
+				fmt.Printf("    重定向: %s\n", strings.Join(r.Redirects, " → "))
+			}
 			atomic.AddInt64(&successCount, 1)
-		} else {
-			fmt.Printf("爬取失败: %s (耗时: %v)\n", result.URL, result.Duration)
+		default:
+			fmt.Printf("Worker-%d: 爬取失败 [深度 %d] %s [%v%s] (%v)\n", r.Worker, r.Depth, r.URL, r.Category, retried, r.Err)
 			atomic.AddInt64(&failCount, 1)
+			failures[r.Category]++
 		}
 	}
 
-	// Step 5: 打印详细统计信息
 	concurrentDuration := time.Since(start)
+
+	// 有序并发：ParallelMap 同样最多 5 个并发，但按 urls 的顺序输出结果
+	fmt.Println("\n===== 有序并发爬虫（ParallelMap）=====")
+	start = time.Now()
+	unique := pipeline.Filter(ctx, pipeline.Generate(ctx, urls...), firstSeen())
+	index := 0
+	for result := range pipeline.ParallelMap(ctx, unique, 5, crawl) {
+		index++
+		fmt.Printf("[%2d] %s %s (耗时: %v)\n", index, result.Category, result.URL, result.Duration.Round(time.Millisecond))
+	}
+	orderedDuration := time.Since(start)
+
+	// Step 5: 打印详细统计信息
 	fmt.Println("\n" + strings.Repeat("=", 50))
 	fmt.Println("📊 并发爬虫统计报告")
 	fmt.Println(strings.Repeat("=", 50))
 	fmt.Printf("✅ 成功: %d 个\n", atomic.LoadInt64(&successCount))
 	fmt.Printf("❌ 失败: %d 个\n", atomic.LoadInt64(&failCount))
-	fmt.Printf("⏭️  跳过: %d 个\n", atomic.LoadInt64(&skippedCount))
-	fmt.Printf("📈 总计: %d 个URL\n", len(urls))
+	for _, category := range crawler.CategoryValues() {
+		if n := failures[category]; n > 0 {
+			fmt.Printf("   - %v: %d 个\n", category, n)
+		}
+	}
+	fmt.Printf("⏹️  取消: %d 个\n", atomic.LoadInt64(&cancelledCount))
+	stats := bfs.Stats()
+	fmt.Printf("🔁 重试: %d 次\n", stats.Retries)
+	fmt.Printf("⏭️  跳过: 重复 %d 个，其他网站 %d 个，超过深度 %d 个，robots.txt 禁止 %d 个\n",
+		stats.Duplicates, stats.External, stats.TooDeep, stats.Disallowed)
+	fmt.Printf("📈 总计: %d 个种子 URL，抓取 %d 个页面\n", len(urls), stats.Pages)
+	fmt.Printf("👷 worker: 峰值 %d 个，扩容 %d 次，平均耗时 %v\n", stats.Pool.PeakWorkers, stats.Pool.ScaleUps, stats.Pool.AvgLatency.Round(time.Millisecond))
 	fmt.Printf("⏱️  串行耗时: %v\n", serialDuration)
 	fmt.Printf("⚡ 并发耗时: %v\n", concurrentDuration)
+	fmt.Printf("🔢 有序并发耗时: %v\n", orderedDuration)
 	fmt.Printf("🚀 提速倍数: %.2fx\n", float64(serialDuration)/float64(concurrentDuration))
 	fmt.Println(strings.Repeat("=", 50))
 }
@@ -0,0 +1,315 @@
+// Package pipeline 提供泛型、可取消的管道阶段，替代第05节手写的 generate/square/filterEven。
+//
+// 每个阶段启动自己的 goroutine，读取输入通道、写入输出通道，并在以下情况退出且关闭输出：
+//
+//   - 输入通道关闭（正常结束）
+//   - ctx 取消（下游提前停止消费时，取消 ctx 就能让整条管道的 goroutine 全部退出）
+//
+// 所有阶段的发送和接收都同时等待 ctx.Done()，因此不会因为下游不再读取而永久阻塞。
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// send 发送 v，ctx 取消时放弃并返回 false
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// each 依次读取 in 并调用 fn，直到 in 关闭、ctx 取消或 fn 返回 false
+func each[T any](ctx context.Context, in <-chan T, fn func(T) bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case v, ok := <-in:
+			if !ok || !fn(v) {
+				return
+			}
+		}
+	}
+}
+
+// ========== 数据源和终点 ==========
+
+// Generate 依次输出 values
+func Generate[T any](ctx context.Context, values ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range values {
+			if !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Collect 读取 in 直到关闭或 ctx 取消，返回已读取的值和 ctx 的错误
+func Collect[T any](ctx context.Context, in <-chan T) ([]T, error) {
+	var all []T
+	each(ctx, in, func(v T) bool {
+		all = append(all, v)
+		return true
+	})
+	return all, ctx.Err()
+}
+
+// ========== 转换 ==========
+
+// Map 对每个值调用 fn
+func Map[In, Out any](ctx context.Context, in <-chan In, fn func(In) Out) <-chan Out {
+	out := make(chan Out)
+	go func() {
+		defer close(out)
+		each(ctx, in, func(v In) bool { return send(ctx, out, fn(v)) })
+	}()
+	return out
+}
+
+// Filter 只保留 keep 返回 true 的值
+func Filter[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		each(ctx, in, func(v T) bool { return !keep(v) || send(ctx, out, v) })
+	}()
+	return out
+}
+
+// FlatMap 把每个值展开为零个或多个值
+func FlatMap[In, Out any](ctx context.Context, in <-chan In, fn func(In) []Out) <-chan Out {
+	out := make(chan Out)
+	go func() {
+		defer close(out)
+		each(ctx, in, func(v In) bool {
+			for _, w := range fn(v) {
+				if !send(ctx, out, w) {
+					return false
+				}
+			}
+			return true
+		})
+	}()
+	return out
+}
+
+// Take 只输出前 n 个值。Take 之后不再读取上游，上游会阻塞在发送上，
+// 所以用完后应取消 ctx 让上游退出。
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		if n <= 0 {
+			return
+		}
+		count := 0
+		each(ctx, in, func(v T) bool {
+			count++
+			return send(ctx, out, v) && count < n
+		})
+	}()
+	return out
+}
+
+// ========== 分组 ==========
+
+// Batch 把值按 size 个一组输出；maxWait 大于 0 时，一组中第一个值等待超过 maxWait 也会输出不满的一组。
+// 输入关闭时输出剩余的值；ctx 取消时丢弃未满的一组。
+func Batch[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	out := make(chan []T)
+	size = max(size, 1)
+	go func() {
+		defer close(out)
+		var (
+			batch []T
+			timer *time.Timer
+			flush <-chan time.Time
+		)
+		emit := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, flush = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			b := batch
+			batch = nil
+			return send(ctx, out, b)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-flush:
+				timer, flush = nil, nil
+				if !emit() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					emit()
+					return
+				}
+				batch = append(batch, v)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = time.NewTimer(maxWait)
+					flush = timer.C
+				}
+				if len(batch) == size && !emit() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Window 输出长度为 size 的滑动窗口，每次向前移动 step 个值（step == size 时为不重叠的分组）。
+// 输入关闭时不足 size 的尾部不输出。每个窗口都是新的切片，可以安全保留。
+func Window[T any](ctx context.Context, in <-chan T, size, step int) <-chan []T {
+	out := make(chan []T)
+	size, step = max(size, 1), max(step, 1)
+	go func() {
+		defer close(out)
+		var buf []T
+		skip := 0 // step > size 时，窗口之间需要跳过的值
+		each(ctx, in, func(v T) bool {
+			if skip > 0 {
+				skip--
+				return true
+			}
+			buf = append(buf, v)
+			if len(buf) < size {
+				return true
+			}
+			window := append([]T(nil), buf...)
+			if step >= size {
+				buf, skip = buf[:0], step-size
+			} else {
+				buf = append(buf[:0], buf[step:]...)
+			}
+			return send(ctx, out, window)
+		})
+	}()
+	return out
+}
+
+// ========== 扇出和扇入 ==========
+
+// FanOut 启动 n 个输出，多个输出竞争读取同一个输入，每个值只会出现在其中一个输出中。
+// 通常每个输出后面接一个相同的处理阶段，再用 Merge 合并。
+func FanOut[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	outs := make([]<-chan T, max(n, 1))
+	for i := range outs {
+		out := make(chan T)
+		outs[i] = out
+		go func() {
+			defer close(out)
+			each(ctx, in, func(v T) bool { return send(ctx, out, v) })
+		}()
+	}
+	return outs
+}
+
+// Merge 把多个输入合并为一个输出，顺序不确定；所有输入都关闭后关闭输出
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			each(ctx, in, func(v T) bool { return send(ctx, out, v) })
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// FanIn 是 Merge 的别名，与 FanOut 成对使用
+func FanIn[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	return Merge(ctx, ins...)
+}
+
+// Tee 把每个值同时发给两个输出。两个输出都收到之后才读取下一个值，
+// 所以两个输出都必须有人消费（或取消 ctx）。
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		each(ctx, in, func(v T) bool {
+			// 发送成功的一方置为 nil，select 不会再选中它
+			a, b := out1, out2
+			for a != nil || b != nil {
+				select {
+				case a <- v:
+					a = nil
+				case b <- v:
+					b = nil
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		})
+	}()
+	return out1, out2
+}
+
+// ========== 并行 ==========
+
+// ParallelMap 最多用 n 个 goroutine 并行调用 fn，但按输入顺序输出结果。
+//
+// 每读取一个值就把它的结果占位（容量为 1 的通道）放进长度为 n 的有序队列，输出端按队列顺序等待结果。
+// 队列满时停止读取输入，所以已读取但未输出的值最多 n+2 个（输出端正在等待的 1 个、队列中 n 个、等待入队的 1 个）：
+// 即使前面的值很慢，后面已完成的结果也只会在这个有界的重排缓冲中等待，内存不会无限增长。
+func ParallelMap[In, Out any](ctx context.Context, in <-chan In, n int, fn func(In) Out) <-chan Out {
+	n = max(n, 1)
+	out := make(chan Out)
+	pending := make(chan chan Out, n) // 按输入顺序排列的结果占位
+	sem := make(chan struct{}, n)     // 同时运行的 fn 不超过 n 个
+
+	go func() {
+		defer close(pending)
+		each(ctx, in, func(v In) bool {
+			result := make(chan Out, 1)
+			if !send(ctx, pending, result) || !send(ctx, sem, struct{}{}) {
+				return false
+			}
+			go func() {
+				// fn 返回后结果写入缓冲通道，不会阻塞，goroutine 随即退出
+				result <- fn(v)
+				<-sem
+			}()
+			return true
+		})
+	}()
+
+	go func() {
+		defer close(out)
+		each(ctx, pending, func(result chan Out) bool {
+			select {
+			case v := <-result:
+				return send(ctx, out, v)
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return out
+}
@@ -0,0 +1,227 @@
+package pipeline
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// naturals 是无限数据源 1, 2, 3, …，ctx 取消时退出
+func naturals(ctx context.Context) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for n := 1; ; n++ {
+			if !send(ctx, out, n) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func collect[T any](in <-chan T) []T {
+	all, _ := Collect(context.Background(), in)
+	return all
+}
+
+func TestStages(t *testing.T) {
+	ctx := context.Background()
+	nums := func() <-chan int { return Generate(ctx, 1, 2, 3, 4, 5, 6, 7) }
+
+	cases := []struct {
+		name      string
+		got, want any
+	}{
+		{"Map", collect(Map(ctx, nums(), func(n int) string { return strings.Repeat("*", n%4) })),
+			[]string{"*", "**", "***", "", "*", "**", "***"}},
+		{"Filter", collect(Filter(ctx, nums(), func(n int) bool { return n%3 == 0 })), []int{3, 6}},
+		{"FlatMap", collect(FlatMap(ctx, Generate(ctx, "a b", "", "c"), strings.Fields)), []string{"a", "b", "c"}},
+		{"Take", collect(Take(ctx, nums(), 3)), []int{1, 2, 3}},
+		{"Batch(3)", collect(Batch(ctx, nums(), 3, 0)), [][]int{{1, 2, 3}, {4, 5, 6}, {7}}},
+		{"Window(3, 1)", collect(Window(ctx, Generate(ctx, 1, 2, 3, 4, 5), 3, 1)), [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}}},
+		{"Window(2, 3)", collect(Window(ctx, nums(), 2, 3)), [][]int{{1, 2}, {4, 5}}},
+	}
+	for _, c := range cases {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+// 慢速数据源每 100ms 一个值，150ms 内凑不满 3 个就先输出
+func TestBatchMaxWait(t *testing.T) {
+	slow := make(chan int)
+	go func() {
+		defer close(slow)
+		for i := 1; i <= 4; i++ {
+			slow <- i
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+	got := collect(Batch(context.Background(), slow, 3, 150*time.Millisecond))
+	if want := [][]int{{1, 2}, {3, 4}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Batch(3, 150ms) = %v, want %v", got, want)
+	}
+}
+
+func TestFanOutFanInAndTee(t *testing.T) {
+	ctx := context.Background()
+	// FanOut 到 3 个平方阶段，再 FanIn：顺序不确定，总和确定
+	var squares []<-chan int
+	for _, part := range FanOut(ctx, Generate(ctx, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 3) {
+		squares = append(squares, Map(ctx, part, func(n int) int { return n * n }))
+	}
+	sum := 0
+	for v := range FanIn(ctx, squares...) {
+		sum += v
+	}
+	if sum != 385 {
+		t.Errorf("FanOut + FanIn 平方和 = %d, want 385", sum)
+	}
+
+	a, b := Tee(ctx, Generate(ctx, 1, 2, 3))
+	left := make(chan []int)
+	go func() { left <- collect(a) }()
+	right := collect(b)
+	if got, want := [][]int{<-left, right}, [][]int{{1, 2, 3}, {1, 2, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tee = %v, want %v", got, want)
+	}
+}
+
+func TestParallelMapKeepsOrderAndBoundsConcurrency(t *testing.T) {
+	ctx := context.Background()
+	nums := make([]int, 50)
+	for i := range nums {
+		nums[i] = i + 1
+	}
+
+	// 每个值随机耗时，并记录同时运行的 fn 数量
+	var running, peak atomic.Int32
+	slowSquare := func(n int) int {
+		cur := running.Add(1)
+		for old := peak.Load(); cur > old && !peak.CompareAndSwap(old, cur); old = peak.Load() {
+		}
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+		running.Add(-1)
+		return n * n
+	}
+	got := collect(ParallelMap(ctx, Generate(ctx, nums...), 8, slowSquare))
+	want := collect(Map(ctx, Generate(ctx, nums...), func(n int) int { return n * n }))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParallelMap 没有按输入顺序输出: %v", got)
+	}
+	if p := peak.Load(); p > 8 {
+		t.Fatalf("同时运行的 fn 峰值 %d，超过 n = 8", p)
+	}
+}
+
+// 重排缓冲有界：第一个值阻塞时，读取的输入不会无限增长
+func TestParallelMapBoundsReorderBuffer(t *testing.T) {
+	ctx := context.Background()
+	nums := make([]int, 50)
+	for i := range nums {
+		nums[i] = i + 1
+	}
+	var read atomic.Int32
+	counted := Map(ctx, Generate(ctx, nums...), func(n int) int {
+		read.Add(1)
+		return n
+	})
+	release := make(chan struct{})
+	out := ParallelMap(ctx, counted, 4, func(n int) int {
+		if n == 1 {
+			<-release
+		}
+		return n
+	})
+	time.Sleep(20 * time.Millisecond)
+	blocked := read.Load()
+	close(release)
+	rest := collect(out)
+
+	// ParallelMap 自己最多持有 n+2 个（输出端 1 个、队列 n 个、等待入队 1 个），计数的 Map 阶段再多算 1 个
+	if blocked > 4+3 {
+		t.Fatalf("第一个值阻塞时读取了 %d 个输入，want 不超过 n+3 = 7", blocked)
+	}
+	if len(rest) != len(nums) {
+		t.Fatalf("放行后输出 %d 个值，want %d", len(rest), len(nums))
+	}
+}
+
+// 每个阶段接上无限数据源，只读取 3 个值就取消 ctx，goroutine 数量必须回到开始时的水平
+func TestStagesExitOnCancel(t *testing.T) {
+	square := func(n int) int { return n * n }
+	builds := map[string]func(ctx context.Context, src <-chan int) <-chan any{
+		"Map": func(ctx context.Context, src <-chan int) <-chan any {
+			return asAny(ctx, Map(ctx, src, square))
+		},
+		"Filter": func(ctx context.Context, src <-chan int) <-chan any {
+			return asAny(ctx, Filter(ctx, src, func(n int) bool { return n%2 == 0 }))
+		},
+		"FlatMap": func(ctx context.Context, src <-chan int) <-chan any {
+			return asAny(ctx, FlatMap(ctx, src, func(n int) []int { return []int{n, -n} }))
+		},
+		"Batch": func(ctx context.Context, src <-chan int) <-chan any {
+			return asAny(ctx, Batch(ctx, src, 4, time.Second))
+		},
+		"Window": func(ctx context.Context, src <-chan int) <-chan any {
+			return asAny(ctx, Window(ctx, src, 3, 1))
+		},
+		"Take": func(ctx context.Context, src <-chan int) <-chan any {
+			return asAny(ctx, Take(ctx, src, 5))
+		},
+		"FanOut+FanIn": func(ctx context.Context, src <-chan int) <-chan any {
+			return asAny(ctx, FanIn(ctx, FanOut(ctx, src, 4)...))
+		},
+		// Tee 的第二个输出由另一个 goroutine 读取；取消后 Tee 和它都要退出
+		"Tee": func(ctx context.Context, src <-chan int) <-chan any {
+			a, b := Tee(ctx, src)
+			go func() {
+				for range b {
+				}
+			}()
+			return asAny(ctx, a)
+		},
+		"ParallelMap": func(ctx context.Context, src <-chan int) <-chan any {
+			return asAny(ctx, ParallelMap(ctx, src, 4, square))
+		},
+		"Merge": func(ctx context.Context, src <-chan int) <-chan any {
+			return asAny(ctx, Merge(ctx, src, naturals(ctx)))
+		},
+	}
+	for name, build := range builds {
+		t.Run(name, func(t *testing.T) { leakCheck(t, build) })
+	}
+}
+
+// leakCheck 构建管道，只读取几个值就取消，确认 goroutine 全部退出
+func leakCheck(t *testing.T, build func(ctx context.Context, src <-chan int) <-chan any) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	out := build(ctx, naturals(ctx))
+	for range 3 {
+		<-out
+	}
+	cancel()
+
+	after := runtime.NumGoroutine()
+	for deadline := time.Now().Add(time.Second); after > before && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+	if after > before {
+		t.Fatalf("取消后泄漏 %d 个 goroutine", after-before)
+	}
+}
+
+// asAny 把各种输出统一成 <-chan any，方便 leakCheck 读取；它本身也是一个 Map 阶段
+func asAny[T any](ctx context.Context, in <-chan T) <-chan any {
+	return Map(ctx, in, func(v T) any { return v })
+}
@@ -0,0 +1,575 @@
+// Package scheduler 为第05节练习里的 Task 提供按优先级、截止时间和重试次数调度的 worker：
+//
+//   - 优先级高的任务先执行；等待越久的任务有效优先级越高（老化），低优先级任务不会被饿死
+//   - 超过截止时间的任务按 DeadlinePolicy 丢弃，或照常执行并标记为迟到
+//   - 失败的任务按指数退避重新排队，直到用完重试次数
+//
+// 时间来自 workerpool.Clock，可以用 workerpool.FakeClock 驱动。
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+var (
+	ErrClosed   = errors.New("调度器已关闭")
+	ErrDeadline = errors.New("任务已过截止时间")
+)
+
+// ========== 任务 ==========
+
+// Priority 是任务优先级，数值越大越优先
+//
+//go:generate go run golang_study/tools/enumgen -type=Priority,Outcome
+type Priority int
+
+const (
+	Low    Priority = iota // enum:"低"
+	Normal                 // enum:"中"
+	High                   // enum:"高"
+	Urgent                 // enum:"紧急"
+)
+
+// Outcome 是任务的最终结果
+type Outcome int
+
+const (
+	Done    Outcome = iota + 1 // enum:"完成"
+	Failed                     // enum:"失败"
+	Expired                    // enum:"过期"
+)
+
+// Task 是一个待调度的任务；Deadline 为零值表示没有截止时间
+type Task struct {
+	ID         int
+	Priority   Priority
+	Deadline   time.Time
+	MaxRetries int // 失败后最多重试的次数
+}
+
+// Handler 执行一次任务，attempt 从 1 开始
+type Handler func(ctx context.Context, t Task, attempt int) error
+
+// DeadlinePolicy 决定如何处理超过截止时间的任务
+type DeadlinePolicy int
+
+const (
+	DropExpired DeadlinePolicy = iota // 开始执行前或重试前已过期的任务不再执行，结果为 Expired
+	FlagLate                          // 照常执行，完成时超过截止时间则 Report.Late 为 true
+)
+
+// Options 配置调度器，零值字段使用默认值
+type Options struct {
+	Workers    int            // 默认 runtime.NumCPU()
+	Aging      time.Duration  // 每多等待一个 Aging，有效优先级提高一级，默认 1 秒
+	Backoff    time.Duration  // 第一次重试前的等待时间，之后每次翻倍，默认 100ms
+	MaxBackoff time.Duration  // 退避上限，默认 Backoff 的 32 倍
+	Deadline   DeadlinePolicy // 默认 DropExpired
+	Clock      workerpool.Clock
+}
+
+// Report 是一个任务的最终报告
+type Report struct {
+	Task      Task
+	Outcome   Outcome
+	Err       error // 最后一次失败的错误；Expired 时包装 ErrDeadline
+	Attempts  int   // 实际执行次数
+	Late      bool  // FlagLate 模式下完成时已超过截止时间
+	Worker    int   // 最后一次执行所在的 worker，未执行时为 0
+	Submitted time.Time
+	Finished  time.Time
+}
+
+// Latency 是从提交到最终完成（包括排队、重试和退避）的时间
+func (r Report) Latency() time.Duration {
+	return r.Finished.Sub(r.Submitted)
+}
+
+// ========== 优先队列 ==========
+
+type item struct {
+	task      Task
+	attempts  int
+	submitted time.Time
+	key       time.Time // 入队时间减去 Priority × Aging，越早越优先
+	seq       uint64    // key 相同时先入队的先执行
+	lastErr   error
+}
+
+// queue 按 key 排序。有效优先级 = Priority + 等待时间 / Aging，
+// 所有任务的等待时间以同样的速度增长，所以两个任务的先后顺序不随时间变化，可以直接用堆。
+type queue []*item
+
+func (q queue) Len() int { return len(q) }
+func (q queue) Less(i, j int) bool {
+	if !q[i].key.Equal(q[j].key) {
+		return q[i].key.Before(q[j].key)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x any)   { *q = append(*q, x.(*item)) }
+func (q *queue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+// ========== Scheduler ==========
+
+// Scheduler 用固定数量的 worker 从优先队列中取任务执行
+type Scheduler struct {
+	handler Handler
+	opts    Options
+	clock   workerpool.Clock
+	ctx     context.Context
+	cancel  context.CancelFunc
+	reports chan Report
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ready   queue
+	seq     uint64
+	backoff int // 正在退避等待重试的任务数
+	running int
+	workers int
+	closed  bool
+}
+
+// New 创建并启动调度器。ctx 取消后，正在执行的任务收到取消信号，其余任务不再执行，以 Failed 报告，
+// 之后不需要 Close，Reports 也会关闭。
+func New(ctx context.Context, handler Handler, opts Options) *Scheduler {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.Aging <= 0 {
+		opts.Aging = time.Second
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 32 * opts.Backoff
+	}
+	if opts.Clock == nil {
+		opts.Clock = workerpool.RealClock()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Scheduler{
+		handler: handler,
+		opts:    opts,
+		clock:   opts.Clock,
+		ctx:     ctx,
+		cancel:  cancel,
+		reports: make(chan Report, opts.Workers),
+		workers: opts.Workers,
+	}
+	s.cond = sync.NewCond(&s.mu)
+	for id := 1; id <= opts.Workers; id++ {
+		go s.worker(id)
+	}
+	// 唤醒在 cond.Wait 中等待任务的 worker；最后一个 worker 退出时会 cancel，这个 goroutine 总会结束
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}()
+	return s
+}
+
+// Submit 提交任务，不会阻塞
+func (s *Scheduler) Submit(t Task) error {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 在锁内检查 ctx：worker 要拿到锁才能退出，检查通过后放入的任务一定会被取出
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if s.closed {
+		return ErrClosed
+	}
+	s.pushLocked(&item{task: t, submitted: now}, now)
+	return nil
+}
+
+// Reports 返回报告通道，Close 或 ctx 取消之后所有任务（包括重试）结束时关闭
+func (s *Scheduler) Reports() <-chan Report {
+	return s.reports
+}
+
+// Close 不再接收新任务；已提交的任务和等待中的重试仍会执行完
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// Len 返回排队中（不含退避等待中）的任务数
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.ready)
+}
+
+func (s *Scheduler) pushLocked(it *item, now time.Time) {
+	it.key = now.Add(-time.Duration(it.task.Priority) * s.opts.Aging)
+	it.seq = s.seq
+	s.seq++
+	heap.Push(&s.ready, it)
+	s.cond.Signal()
+}
+
+// ========== worker ==========
+
+func (s *Scheduler) worker(id int) {
+	ctx := workerpool.WithWorkerID(s.ctx, id)
+	for {
+		it, ok := s.next()
+		if !ok {
+			break
+		}
+		s.run(ctx, id, it)
+	}
+
+	s.mu.Lock()
+	s.workers--
+	last := s.workers == 0
+	s.mu.Unlock()
+	if last {
+		close(s.reports)
+		s.cancel()
+	}
+}
+
+// next 取出有效优先级最高的任务；关闭或 ctx 取消后，队列为空且没有执行中或退避中的任务时返回 false
+func (s *Scheduler) next() (*item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.ready) == 0 {
+		if (s.closed || s.ctx.Err() != nil) && s.backoff == 0 && s.running == 0 {
+			return nil, false
+		}
+		s.cond.Wait()
+	}
+	s.running++
+	return heap.Pop(&s.ready).(*item), true
+}
+
+func (s *Scheduler) run(ctx context.Context, id int, it *item) {
+	t := it.task
+	r := Report{Task: t, Attempts: it.attempts, Err: it.lastErr, Submitted: it.submitted}
+	expired := func(at time.Time) bool { return !t.Deadline.IsZero() && at.After(t.Deadline) }
+
+	switch {
+	case s.ctx.Err() != nil:
+		r.Outcome, r.Err = Failed, s.ctx.Err()
+	case s.opts.Deadline == DropExpired && expired(s.clock.Now()):
+		r.Outcome, r.Err = Expired, wrapDeadline(it.lastErr)
+	default:
+		it.attempts++
+		r.Attempts, r.Worker = it.attempts, id
+		err := s.call(ctx, t, it.attempts)
+		if err == nil {
+			r.Outcome, r.Err = Done, nil
+			break
+		}
+		it.lastErr = err
+		r.Outcome, r.Err = Failed, err
+		if it.attempts > t.MaxRetries || s.ctx.Err() != nil {
+			break
+		}
+		delay := s.delay(it.attempts)
+		if s.opts.Deadline == DropExpired && expired(s.clock.Now().Add(delay)) {
+			r.Outcome, r.Err = Expired, wrapDeadline(err)
+			break
+		}
+		s.retry(it, delay)
+		return
+	}
+
+	r.Finished = s.clock.Now()
+	r.Late = r.Outcome == Done && expired(r.Finished)
+	s.reports <- r
+	s.mu.Lock()
+	s.running--
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// call 执行 Handler，panic 转换为 *workerpool.PanicError
+func (s *Scheduler) call(ctx context.Context, t Task, attempt int) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &workerpool.PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return s.handler(ctx, t, attempt)
+}
+
+// delay 返回第 attempt 次失败后的退避时间：Backoff × 2^(attempt-1)，不超过 MaxBackoff
+func (s *Scheduler) delay(attempt int) time.Duration {
+	d := s.opts.Backoff
+	for i := 1; i < attempt && d < s.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, s.opts.MaxBackoff)
+}
+
+// retry 在退避结束（或 ctx 取消）后把任务重新放回队列，按重新入队的时间老化
+func (s *Scheduler) retry(it *item, delay time.Duration) {
+	s.mu.Lock()
+	s.running--
+	s.backoff++
+	s.mu.Unlock()
+
+	timer := s.clock.NewTimer(delay)
+	go func() {
+		select {
+		case <-timer.C():
+		case <-s.ctx.Done():
+			timer.Stop()
+		}
+		now := s.clock.Now()
+		s.mu.Lock()
+		s.backoff--
+		s.pushLocked(it, now)
+		s.mu.Unlock()
+	}()
+}
+
+func wrapDeadline(err error) error {
+	if err == nil {
+		return ErrDeadline
+	}
+	return fmt.Errorf("%w（最后一次错误: %w）", ErrDeadline, err)
+}
+
+// enumgen:begin Priority
+// 以下代码由 enumgen 根据 Priority 的常量生成，请勿手动修改；修改常量后重新运行 go generate。
+
+// String 返回显示名称（常量注释中的 enum:"..."，没有标注时为常量名）
+func (x Priority) String() string {
+	switch x {
+	case Low:
+		return "低"
+	case Normal:
+		return "中"
+	case High:
+		return "高"
+	case Urgent:
+		return "紧急"
+	}
+	return fmt.Sprintf("Priority(%d)", int64(x))
+}
+
+// Name 返回常量名，用于文本和 JSON 序列化
+func (x Priority) Name() string {
+	switch x {
+	case Low:
+		return "Low"
+	case Normal:
+		return "Normal"
+	case High:
+		return "High"
+	case Urgent:
+		return "Urgent"
+	}
+	return fmt.Sprintf("Priority(%d)", int64(x))
+}
+
+// IsValid 报告 x 是否是已定义的常量
+func (x Priority) IsValid() bool {
+	switch x {
+	case Low, Normal, High, Urgent:
+		return true
+	}
+	return false
+}
+
+// PriorityValues 按声明顺序返回所有常量
+func PriorityValues() []Priority {
+	return []Priority{Low, Normal, High, Urgent}
+}
+
+// ParsePriority 按常量名或显示名称解析
+func ParsePriority(s string) (Priority, error) {
+	switch s {
+	case "Low", "低":
+		return Low, nil
+	case "Normal", "中":
+		return Normal, nil
+	case "High", "高":
+		return High, nil
+	case "Urgent", "紧急":
+		return Urgent, nil
+	}
+	return 0, fmt.Errorf("无效的 Priority: %q", s)
+}
+
+// MarshalText 实现 encoding.TextMarshaler，输出常量名
+func (x Priority) MarshalText() ([]byte, error) {
+	if !x.IsValid() {
+		return nil, fmt.Errorf("无效的 Priority: %d", int64(x))
+	}
+	return []byte(x.Name()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，接受常量名或显示名称
+func (x *Priority) UnmarshalText(text []byte) error {
+	v, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+// MarshalJSON 输出常量名字符串
+func (x Priority) MarshalJSON() ([]byte, error) {
+	text, err := x.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%q", text)), nil
+}
+
+// UnmarshalJSON 接受字符串（常量名或显示名称）或数字；null 保持原值不变，与标准库的约定一致
+func (x *Priority) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("无效的 Priority: %s", data)
+		}
+		return x.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil || !Priority(n).IsValid() {
+		return fmt.Errorf("无效的 Priority: %s", data)
+	}
+	*x = Priority(n)
+	return nil
+}
+
+// enumgen:end Priority
+
+// enumgen:begin Outcome
+// 以下代码由 enumgen 根据 Outcome 的常量生成，请勿手动修改；修改常量后重新运行 go generate。
+
+// String 返回显示名称（常量注释中的 enum:"..."，没有标注时为常量名）
+func (x Outcome) String() string {
+	switch x {
+	case Done:
+		return "完成"
+	case Failed:
+		return "失败"
+	case Expired:
+		return "过期"
+	}
+	return fmt.Sprintf("Outcome(%d)", int64(x))
+}
+
+// Name 返回常量名，用于文本和 JSON 序列化
+func (x Outcome) Name() string {
+	switch x {
+	case Done:
+		return "Done"
+	case Failed:
+		return "Failed"
+	case Expired:
+		return "Expired"
+	}
+	return fmt.Sprintf("Outcome(%d)", int64(x))
+}
+
+// IsValid 报告 x 是否是已定义的常量
+func (x Outcome) IsValid() bool {
+	switch x {
+	case Done, Failed, Expired:
+		return true
+	}
+	return false
+}
+
+// OutcomeValues 按声明顺序返回所有常量
+func OutcomeValues() []Outcome {
+	return []Outcome{Done, Failed, Expired}
+}
+
+// ParseOutcome 按常量名或显示名称解析
+func ParseOutcome(s string) (Outcome, error) {
+	switch s {
+	case "Done", "完成":
+		return Done, nil
+	case "Failed", "失败":
+		return Failed, nil
+	case "Expired", "过期":
+		return Expired, nil
+	}
+	return 0, fmt.Errorf("无效的 Outcome: %q", s)
+}
+
+// MarshalText 实现 encoding.TextMarshaler，输出常量名
+func (x Outcome) MarshalText() ([]byte, error) {
+	if !x.IsValid() {
+		return nil, fmt.Errorf("无效的 Outcome: %d", int64(x))
+	}
+	return []byte(x.Name()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，接受常量名或显示名称
+func (x *Outcome) UnmarshalText(text []byte) error {
+	v, err := ParseOutcome(string(text))
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+// MarshalJSON 输出常量名字符串
+func (x Outcome) MarshalJSON() ([]byte, error) {
+	text, err := x.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%q", text)), nil
+}
+
+// UnmarshalJSON 接受字符串（常量名或显示名称）或数字；null 保持原值不变，与标准库的约定一致
+func (x *Outcome) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("无效的 Outcome: %s", data)
+		}
+		return x.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil || !Outcome(n).IsValid() {
+		return fmt.Errorf("无效的 Outcome: %s", data)
+	}
+	*x = Outcome(n)
+	return nil
+}
+
+// enumgen:end Outcome
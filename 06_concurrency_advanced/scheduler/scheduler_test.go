@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+// 用 FakeClock 驱动调度器：老化、截止时间和退避都只看假时钟，每一步的状态都是确定的。
+
+var (
+	start   = time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	errBoom = errors.New("boom")
+)
+
+// waitFor 轮询直到 cond 成立；worker 和退避定时器在后台 goroutine 中运行，需要给它们一点真实时间
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !cond() {
+		t.Fatalf("等待超时: %s", what)
+	}
+}
+
+// collect 在后台读取报告，返回的函数等待 Reports 关闭并按完成顺序返回所有报告
+func collect(t *testing.T, s *Scheduler) func() []Report {
+	var reports []Report
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range s.Reports() {
+			reports = append(reports, r)
+		}
+	}()
+	return func() []Report {
+		t.Helper()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Reports 没有关闭")
+		}
+		return reports
+	}
+}
+
+// blocker 让 ID 为 0 的任务占住唯一的 worker，直到 release 被调用，其他任务交给 handler
+type blocker struct {
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func newBlocker() *blocker {
+	return &blocker{started: make(chan struct{}), gate: make(chan struct{})}
+}
+
+func (b *blocker) wrap(handler Handler) Handler {
+	return func(ctx context.Context, t Task, attempt int) error {
+		if t.ID == 0 {
+			close(b.started)
+			<-b.gate
+			return nil
+		}
+		return handler(ctx, t, attempt)
+	}
+}
+
+// hold 提交占位任务并等它开始执行，之后提交的任务都会排队
+func (b *blocker) hold(t *testing.T, s *Scheduler) {
+	t.Helper()
+	if err := s.Submit(Task{ID: 0, Priority: Urgent}); err != nil {
+		t.Fatal(err)
+	}
+	<-b.started
+}
+
+func (b *blocker) release() { close(b.gate) }
+
+func TestAgingPreventsStarvation(t *testing.T) {
+	clock := workerpool.NewFakeClock(start)
+	b := newBlocker()
+	var mu sync.Mutex
+	var order []int
+	s := New(context.Background(), b.wrap(func(ctx context.Context, t Task, attempt int) error {
+		mu.Lock()
+		order = append(order, t.ID)
+		mu.Unlock()
+		return nil
+	}), Options{Workers: 1, Aging: time.Second, Clock: clock})
+	wait := collect(t, s)
+	b.hold(t, s)
+
+	// 1 号低优先级任务先等了 3 秒，有效优先级升到 Low+3，超过刚提交的高优先级任务
+	s.Submit(Task{ID: 1, Priority: Low})
+	clock.Advance(3 * time.Second)
+	s.Submit(Task{ID: 4, Priority: Low})
+	s.Submit(Task{ID: 3, Priority: Normal})
+	s.Submit(Task{ID: 2, Priority: High})
+	if n := s.Len(); n != 4 {
+		t.Fatalf("排队 %d 个任务, want 4", n)
+	}
+	b.release()
+	s.Close()
+	wait()
+
+	if want := []int{1, 2, 3, 4}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("执行顺序 %v, want %v", order, want)
+	}
+}
+
+func TestDeadlinePolicy(t *testing.T) {
+	cases := []struct {
+		policy  DeadlinePolicy
+		outcome Outcome
+		tries   int
+		late    bool
+	}{
+		{DropExpired, Expired, 0, false}, // 开始前已过期，不再执行
+		{FlagLate, Done, 1, true},        // 照常执行，标记为迟到
+	}
+	for _, c := range cases {
+		clock := workerpool.NewFakeClock(start)
+		b := newBlocker()
+		s := New(context.Background(), b.wrap(func(ctx context.Context, t Task, attempt int) error {
+			return nil
+		}), Options{Workers: 1, Deadline: c.policy, Clock: clock})
+		wait := collect(t, s)
+		b.hold(t, s)
+
+		s.Submit(Task{ID: 1, Deadline: start.Add(5 * time.Second)})
+		s.Submit(Task{ID: 2, Deadline: start.Add(time.Hour)})
+		clock.Advance(10 * time.Second)
+		b.release()
+		s.Close()
+
+		reports := map[int]Report{}
+		for _, r := range wait() {
+			reports[r.Task.ID] = r
+		}
+		r := reports[1]
+		if r.Outcome != c.outcome || r.Attempts != c.tries || r.Late != c.late {
+			t.Errorf("策略 %d: 过期任务 %v、执行 %d 次、Late=%v, want %v、%d 次、Late=%v",
+				c.policy, r.Outcome, r.Attempts, r.Late, c.outcome, c.tries, c.late)
+		}
+		if c.outcome == Expired && (!errors.Is(r.Err, ErrDeadline) || r.Worker != 0) {
+			t.Errorf("策略 %d: Err = %v、Worker = %d, want ErrDeadline、0", c.policy, r.Err, r.Worker)
+		}
+		if r := reports[2]; r.Outcome != Done || r.Late {
+			t.Errorf("策略 %d: 未过期任务 %v、Late=%v, want 完成、Late=false", c.policy, r.Outcome, r.Late)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	clock := workerpool.NewFakeClock(start)
+	var calls atomic.Int32
+	s := New(context.Background(), func(ctx context.Context, t Task, attempt int) error {
+		calls.Add(1)
+		if t.ID == 1 && attempt == 4 {
+			return nil
+		}
+		return errBoom
+	}, Options{Workers: 1, Backoff: 100 * time.Millisecond, MaxBackoff: 150 * time.Millisecond, Clock: clock})
+	wait := collect(t, s)
+
+	// step 等待退避定时器，推进到差 1ms 时不应重试，推进满 d 后恰好再执行一次
+	step := func(d time.Duration) {
+		t.Helper()
+		waitFor(t, "进入退避", func() bool { return clock.Timers() == 1 })
+		n := calls.Load()
+		clock.Advance(d - time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		if got := calls.Load(); got != n {
+			t.Fatalf("退避 %v 未满就重试了", d)
+		}
+		clock.Advance(time.Millisecond)
+		waitFor(t, "退避结束后重试", func() bool { return calls.Load() == n+1 })
+	}
+
+	// 100ms、200ms 截到上限 150ms、150ms
+	s.Submit(Task{ID: 1, MaxRetries: 5})
+	step(100 * time.Millisecond)
+	step(150 * time.Millisecond)
+	step(150 * time.Millisecond)
+
+	// 第二次失败后要退避 150ms，会越过截止时间，DropExpired 直接报告过期
+	calls.Store(0)
+	s.Submit(Task{ID: 2, MaxRetries: 5, Deadline: clock.Now().Add(120 * time.Millisecond)})
+	step(100 * time.Millisecond)
+
+	// 用完重试次数后报告最后一次错误
+	s.Submit(Task{ID: 3, MaxRetries: 0})
+	s.Close()
+
+	reports := map[int]Report{}
+	for _, r := range wait() {
+		reports[r.Task.ID] = r
+	}
+	if r := reports[1]; r.Outcome != Done || r.Attempts != 4 || r.Latency() != 400*time.Millisecond {
+		t.Errorf("任务 1: %v、执行 %d 次、耗时 %v, want 完成、4 次、400ms", r.Outcome, r.Attempts, r.Latency())
+	}
+	if r := reports[2]; r.Outcome != Expired || r.Attempts != 2 || !errors.Is(r.Err, ErrDeadline) || !errors.Is(r.Err, errBoom) {
+		t.Errorf("任务 2: %v、执行 %d 次、%v, want 过期、2 次、同时包装 ErrDeadline 和最后一次错误", r.Outcome, r.Attempts, r.Err)
+	}
+	if r := reports[3]; r.Outcome != Failed || r.Attempts != 1 || !errors.Is(r.Err, errBoom) {
+		t.Errorf("任务 3: %v、执行 %d 次、%v, want 失败、1 次、errBoom", r.Outcome, r.Attempts, r.Err)
+	}
+}
+
+// 没有调用 Close 时，取消 ctx 也要唤醒空闲等待的 worker 并关闭 Reports
+func TestCancelWakesIdleWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New(ctx, func(ctx context.Context, t Task, attempt int) error {
+		return nil
+	}, Options{Workers: 3, Clock: workerpool.NewFakeClock(start)})
+	wait := collect(t, s)
+
+	if err := s.Submit(Task{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "任务完成", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.seq == 1 && len(s.ready) == 0 && s.running == 0
+	})
+	cancel()
+	if reports := wait(); len(reports) != 1 || reports[0].Outcome != Done {
+		t.Fatalf("报告 %+v, want 1 个完成", reports)
+	}
+	if err := s.Submit(Task{ID: 2}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后 Submit 返回 %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"golang_study/06_concurrency_advanced/diskqueue"
+	"golang_study/06_concurrency_advanced/scheduler"
+	"golang_study/06_concurrency_advanced/workerpool"
+)
+
+// 用法：go run taskqueue.go
+// 第05节练习（05_concurrency_basics/exercise.go）的任务处理系统，再加上 diskqueue：
+// 任务按优先级调度、失败重试，并持久化到磁盘，中断后下次运行继续处理。
+
+// Task 带优先级、截止时间和重试次数，由 scheduler 按优先级（带老化）调度
+type Task = scheduler.Task
+
+var errFlaky = errors.New("模拟的临时故障")
+
+// queueFile 保存未完成的任务：超时或进程被中断时没处理完的任务，下次运行会继续处理
+const queueFile = "tasks.queue"
+
+// taskSpec 是写入队列的任务内容；任务 ID 使用队列的消息 ID，截止时间在取出时才计算
+type taskSpec struct {
+	Priority   scheduler.Priority `json:"priority"`
+	MaxRetries int                `json:"max_retries"`
+	Urgent     bool               `json:"urgent"` // 取出后必须在 1.5 秒内完成
+}
+
+// leases 记录已提交给调度器的任务对应的队列消息，任务结束后据此 Ack 或 Nack
+type leases struct {
+	mu   sync.Mutex
+	msgs map[int]diskqueue.Message
+}
+
+func (l *leases) put(id int, m diskqueue.Message) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs[id] = m
+}
+
+func (l *leases) take(id int) (diskqueue.Message, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m, ok := l.msgs[id]
+	delete(l.msgs, id)
+	return m, ok
+}
+
+func worker(ctx context.Context, task Task, attempt int) error {
+	id := workerpool.WorkerID(ctx)
+	fmt.Printf("Worker-%d: 开始处理任务 %d（优先级 %v，第 %d 次）\n", id, task.ID, task.Priority, attempt)
+
+	// 模拟处理时间
+	start := time.Now()
+	processTime := time.Duration(rand.Intn(400)+100) * time.Millisecond
+	time.Sleep(processTime)
+	duration := time.Since(start)
+
+	// 20% 的概率失败，由调度器按指数退避重试
+	if rand.Intn(5) == 0 {
+		fmt.Printf("Worker-%d: 任务 %d 失败，耗时 %v\n", id, task.ID, duration)
+		return errFlaky
+	}
+	fmt.Printf("Worker-%d: 完成任务 %d，耗时 %v\n", id, task.ID, duration)
+	return nil
+}
+
+func collector(reports <-chan scheduler.Report, settle func(scheduler.Report), done chan<- struct{}) {
+	count := 0
+	outcomes := make(map[scheduler.Outcome]int)
+	latencies := make(map[scheduler.Priority][]time.Duration)
+	for r := range reports {
+		settle(r)
+		outcomes[r.Outcome]++
+		switch r.Outcome {
+		case scheduler.Done:
+			count++
+			latencies[r.Task.Priority] = append(latencies[r.Task.Priority], r.Latency())
+			fmt.Printf("Collector: 任务 %d（%v）由 Worker-%d 完成，执行 %d 次，总耗时 %v\n",
+				r.Task.ID, r.Task.Priority, r.Worker, r.Attempts, r.Latency().Round(time.Millisecond))
+		default:
+			fmt.Printf("Collector: 任务 %d（%v）%v，执行 %d 次: %v\n", r.Task.ID, r.Task.Priority, r.Outcome, r.Attempts, r.Err)
+		}
+	}
+
+	// 打印最终统计
+	fmt.Println("\n===== 统计信息 =====")
+	fmt.Println("总完成任务数:", count)
+	fmt.Printf("失败: %d，过期丢弃: %d\n", outcomes[scheduler.Failed], outcomes[scheduler.Expired])
+
+	// 按优先级统计从提交到完成的时间（包括排队和重试）
+	fmt.Println("\n数量  平均        P90         最大        优先级")
+	for _, p := range highestFirst(scheduler.PriorityValues()) {
+		ds := latencies[p]
+		if len(ds) == 0 {
+			continue
+		}
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		var total time.Duration
+		for _, d := range ds {
+			total += d
+		}
+		p90 := ds[(len(ds)*9-1)/10]
+		avg := total / time.Duration(len(ds))
+		fmt.Printf("%4d  %-10v  %-10v  %-10v  %v\n", len(ds),
+			avg.Round(time.Millisecond), p90.Round(time.Millisecond), ds[len(ds)-1].Round(time.Millisecond), p)
+	}
+	done <- struct{}{}
+}
+
+// highestFirst 按优先级从高到低排列
+func highestFirst(ps []scheduler.Priority) []scheduler.Priority {
+	sort.Slice(ps, func(i, j int) bool { return ps[i] > ps[j] })
+	return ps
+}
+
+// enqueueTasks 向空队列写入 20 个新任务
+func enqueueTasks(q *diskqueue.Queue) error {
+	for i := 1; i <= 20; i++ {
+		body, err := json.Marshal(taskSpec{
+			Priority:   scheduler.Priority(rand.Intn(4)),
+			MaxRetries: 2,
+			Urgent:     i%4 == 0, // 每 4 个任务中有一个必须在 1.5 秒内完成
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := q.Enqueue(body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	// 设置随机种子，确保每次运行的随机数不同
+	rand.Seed(time.Now().UnixNano())
+
+	// 打开任务队列；上次运行被中断时，租约中的任务在 10 秒后重新可见
+	q, err := diskqueue.Open(queueFile, diskqueue.Options{
+		VisibilityTimeout: 10 * time.Second,
+		MaxAttempts:       3,
+	})
+	if err != nil {
+		fmt.Println("打开任务队列失败:", err)
+		return
+	}
+	defer q.Close()
+	if err := q.Compact(); err != nil {
+		fmt.Println("压缩任务队列失败:", err)
+	}
+
+	if s := q.Stats(); s.Ready+s.InFlight == 0 {
+		if err := enqueueTasks(q); err != nil {
+			fmt.Println("写入任务失败:", err)
+			return
+		}
+		fmt.Println("任务队列为空，已生成 20 个新任务")
+	} else {
+		fmt.Printf("继续上次未完成的任务: 待处理 %d，租约中 %d，死信 %d\n", s.Ready, s.InFlight, s.Dead)
+	}
+
+	done := make(chan struct{})
+
+	// 3 个 worker；每多等 1 秒有效优先级提高一级，失败后 200ms 起指数退避
+	sched := scheduler.New(context.Background(), worker, scheduler.Options{
+		Workers: 3,
+		Aging:   time.Second,
+		Backoff: 200 * time.Millisecond,
+	})
+
+	// 启动 collector：任务完成后从队列删除，失败或过期则交还队列，下次运行重试
+	inflight := &leases{msgs: make(map[int]diskqueue.Message)}
+	go collector(sched.Reports(), func(r scheduler.Report) {
+		m, ok := inflight.take(r.Task.ID)
+		if !ok {
+			return
+		}
+		var err error
+		if r.Outcome == scheduler.Done {
+			err = q.Ack(m)
+		} else {
+			err = q.Nack(m, r.Err)
+		}
+		if err != nil {
+			fmt.Printf("Collector: 确认任务 %d 失败: %v\n", r.Task.ID, err)
+		}
+	}, done)
+
+	// 从队列取任务提交给调度器（5 秒超时）
+	fmt.Println("===== 启动任务处理系统 (限时 5 秒) =====")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		defer sched.Close() // 不再提交任务，等待已提交的任务（包括重试）处理完后关闭报告通道
+		for {
+			m, err := q.Receive(ctx)
+			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) {
+					fmt.Println("任务生成超时，停止取出新任务，剩余任务留到下次运行。")
+				}
+				return
+			}
+			var spec taskSpec
+			if err := json.Unmarshal(m.Body, &spec); err != nil {
+				q.Nack(m, err)
+				continue
+			}
+			task := Task{
+				ID:         int(m.ID),
+				Priority:   spec.Priority,
+				MaxRetries: spec.MaxRetries,
+			}
+			if spec.Urgent {
+				task.Deadline = time.Now().Add(1500 * time.Millisecond)
+			}
+			inflight.put(task.ID, m)
+			sched.Submit(task)
+			fmt.Printf("主程序: 取出任务 %d（优先级 %v，第 %d 次投递）\n", task.ID, task.Priority, m.Attempts)
+			time.Sleep(100 * time.Millisecond) // 模拟任务生成间隔
+		}
+	}()
+
+	// 等待 collector 完成
+	<-done
+	s := q.Stats()
+	fmt.Printf("任务队列: 待处理 %d，死信 %d\n", s.Ready+s.InFlight, s.Dead)
+	fmt.Println("系统安全退出")
+}
@@ -0,0 +1,439 @@
+// Package workerpool 把第05、06节反复手写的 jobs/results/WaitGroup 模式整理成一个泛型 worker 池：
+//
+//   - worker 从有界队列取任务，队列满时 Submit 阻塞（背压）
+//   - worker 数量可以固定，也可以在 MinWorkers 和 MaxWorkers 之间按队列长度和任务耗时自动伸缩
+//   - 池的 context 取消后，还没开始的任务不再执行，直接以 ctx.Err() 作为结果返回
+//   - 每个任务的错误和 panic 都被捕获到 Result.Err 中，不会让整个程序崩溃
+//   - Ordered 模式按提交顺序输出结果，否则按完成顺序输出；
+//     Ordered 时领先最早未输出任务超过 ReorderWindow 的任务不再提交，缓存的结果数因此有上限
+//
+// 典型用法：一个 goroutine 提交任务后调用 Shutdown，另一边 range Results() 直到通道关闭。
+// Results() 必须有人持续读取，否则 worker 会阻塞在发送结果上。
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrClosed  = errors.New("worker 池已关闭")
+	ErrDropped = errors.New("任务未执行即被丢弃")
+	ErrFull    = errors.New("任务队列已满")
+)
+
+// Func 处理一个任务。ctx 在池被取消或 Shutdown 超时时取消，可以用 WorkerID(ctx) 取得 worker 编号。
+type Func[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Options 配置 worker 池，零值字段使用默认值
+type Options struct {
+	Workers   int  // 固定 worker 数量，默认 runtime.NumCPU()；自动伸缩时为初始数量
+	QueueSize int  // 队列容量，默认等于 Workers（自动伸缩时为 MaxWorkers）
+	Ordered   bool // 按提交顺序输出结果
+	// ReorderWindow 限制 Ordered 时已提交但还没输出的任务数（包括排队、执行中和等待重排的），
+	// 某个任务很慢时，后面的任务最多领先它这么多，Submit 随后阻塞、TrySubmit 返回 ErrFull。
+	// 默认是 QueueSize 加最大 worker 数的两倍。
+	ReorderWindow int
+
+	// MaxWorkers 大于 0 时启用自动伸缩：队列中的任务多于空闲 worker 时扩容，
+	// 空闲超过 IdleTimeout 的 worker 退出，但不少于 MinWorkers
+	MinWorkers  int
+	MaxWorkers  int
+	IdleTimeout time.Duration // 默认 1 秒
+	// TargetLatency 大于 0 时，只有预计排队时间（队列长度 × 平均耗时 / worker 数）超过它才扩容
+	TargetLatency time.Duration
+
+	Clock Clock // 默认使用真实时间
+}
+
+// Result 是一个任务的处理结果
+type Result[In, Out any] struct {
+	Seq      int // 提交序号，从 0 开始
+	Input    In
+	Value    Out
+	Err      error // 任务返回的错误、*PanicError、ErrDropped 或 context 错误
+	Worker   int   // 处理该任务的 worker 编号（从 1 开始），未执行时为 0
+	Duration time.Duration
+}
+
+// PanicError 记录任务中发生的 panic
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("任务 panic: %v", e.Value)
+}
+
+type workerKey struct{}
+
+// WorkerID 返回当前任务所在的 worker 编号，不在 worker 中时返回 0
+func WorkerID(ctx context.Context) int {
+	id, _ := ctx.Value(workerKey{}).(int)
+	return id
+}
+
+// WithWorkerID 返回带 worker 编号的 context，供基于本包构建的其他调度器使用
+func WithWorkerID(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, workerKey{}, id)
+}
+
+type job[In any] struct {
+	seq int
+	in  In
+}
+
+// ========== Pool ==========
+
+// Pool 是泛型 worker 池，In 为任务输入类型，Out 为结果类型
+type Pool[In, Out any] struct {
+	fn     Func[In, Out]
+	opts   Options
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	jobs    chan job[In]
+	slots   chan struct{}        // 队列空位：Submit 先占位再加锁发送，发送因此不会阻塞
+	window  chan struct{}        // Ordered 时的重排窗口，结果按序输出后归还；非 Ordered 时为 nil
+	raw     chan Result[In, Out] // worker 输出，Ordered 时经过重排再进入 results
+	results chan Result[In, Out]
+	done    chan struct{} // results 关闭后关闭
+
+	mu        sync.Mutex // 保护 seq 和 closed，保证序号连续且不会向已关闭的 jobs 发送；持有期间不会阻塞
+	seq       int
+	closed    bool
+	quit      chan struct{} // 停止接收新任务，唤醒阻塞中的 Submit
+	closeOnce sync.Once
+
+	dropping atomic.Bool // 为 true 时队列中剩余的任务不再执行
+	clock    Clock
+
+	wmu         sync.Mutex // 保护下面的字段和 opts 中的 worker 上下限；加锁顺序 mu → wmu
+	autoscale   bool
+	workers     int
+	active      int
+	nextID      int
+	excess      int           // Resize 缩容后还需要退出的 worker 数
+	wake        chan struct{} // Resize 缩容时关闭，唤醒空闲 worker
+	inputClosed bool
+	finishOnce  sync.Once
+	stats       Stats
+}
+
+// New 创建并启动 worker 池。ctx 取消时，正在执行的任务收到取消信号，排队中的任务不再执行。
+func New[In, Out any](ctx context.Context, fn Func[In, Out], opts Options) *Pool[In, Out] {
+	autoscale := opts.MaxWorkers > 0
+	if autoscale {
+		opts.MinWorkers = min(max(opts.MinWorkers, 0), opts.MaxWorkers)
+		opts.Workers = min(max(opts.Workers, opts.MinWorkers), opts.MaxWorkers)
+		if opts.IdleTimeout <= 0 {
+			opts.IdleTimeout = time.Second
+		}
+		if opts.QueueSize <= 0 {
+			opts.QueueSize = opts.MaxWorkers
+		}
+	} else {
+		if opts.Workers <= 0 {
+			opts.Workers = runtime.NumCPU()
+		}
+		opts.MinWorkers, opts.MaxWorkers, opts.IdleTimeout = opts.Workers, opts.Workers, 0
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = opts.Workers
+	}
+	if opts.Clock == nil {
+		opts.Clock = RealClock()
+	}
+	if opts.Ordered && opts.ReorderWindow <= 0 {
+		opts.ReorderWindow = 2 * (opts.QueueSize + opts.MaxWorkers)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p := &Pool[In, Out]{
+		fn:        fn,
+		opts:      opts,
+		ctx:       ctx,
+		cancel:    cancel,
+		jobs:      make(chan job[In], opts.QueueSize),
+		slots:     make(chan struct{}, opts.QueueSize),
+		results:   make(chan Result[In, Out], opts.QueueSize),
+		done:      make(chan struct{}),
+		quit:      make(chan struct{}),
+		clock:     opts.Clock,
+		autoscale: autoscale,
+		wake:      make(chan struct{}),
+	}
+	p.raw = p.results
+	if opts.Ordered {
+		p.raw = make(chan Result[In, Out], opts.QueueSize)
+		p.window = make(chan struct{}, opts.ReorderWindow)
+		go p.reorder()
+	}
+
+	p.wmu.Lock()
+	for range opts.Workers {
+		p.spawnLocked()
+	}
+	p.wmu.Unlock()
+	return p
+}
+
+// Submit 提交任务，队列满（Ordered 时还包括重排窗口满）时阻塞直到有空位、ctx 取消或池关闭
+func (p *Pool[In, Out]) Submit(ctx context.Context, in In) error {
+	// 先在不持有锁的情况下占好窗口和队列空位，阻塞的 Submit 不会挡住 TrySubmit 和 Shutdown
+	if p.window != nil {
+		if err := p.wait(ctx, p.window); err != nil {
+			return err
+		}
+	}
+	if err := p.wait(ctx, p.slots); err != nil {
+		p.releaseWindow()
+		return err
+	}
+	return p.enqueue(in)
+}
+
+// TrySubmit 提交任务，队列满（Ordered 时还包括重排窗口满）时立即返回 ErrFull
+func (p *Pool[In, Out]) TrySubmit(in In) error {
+	select {
+	case <-p.quit:
+		return ErrClosed
+	default:
+	}
+	if p.window != nil {
+		select {
+		case p.window <- struct{}{}:
+		default:
+			return ErrFull
+		}
+	}
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		p.releaseWindow()
+		return ErrFull
+	}
+	return p.enqueue(in)
+}
+
+// wait 占用 sem 中的一个位置，直到成功、ctx 取消或池关闭
+func (p *Pool[In, Out]) wait(ctx context.Context, sem chan struct{}) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	case <-p.quit:
+		return ErrClosed
+	}
+}
+
+// releaseWindow 归还重排窗口中的一个位置，非 Ordered 时什么也不做
+func (p *Pool[In, Out]) releaseWindow() {
+	if p.window != nil {
+		<-p.window
+	}
+}
+
+// enqueue 分配序号并发送任务；调用方已占好空位，所以持有 mu 时发送不会阻塞
+func (p *Pool[In, Out]) enqueue(in In) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		<-p.slots
+		p.releaseWindow()
+		return ErrClosed
+	}
+	p.jobs <- job[In]{seq: p.seq, in: in}
+	p.seq++
+	p.grow()
+	return nil
+}
+
+// Results 返回结果通道，所有任务处理完（或被丢弃）后关闭
+func (p *Pool[In, Out]) Results() <-chan Result[In, Out] {
+	return p.results
+}
+
+// Done 在结果通道关闭后关闭
+func (p *Pool[In, Out]) Done() <-chan struct{} {
+	return p.done
+}
+
+// closeInput 停止接收新任务；阻塞中的 Submit 返回 ErrClosed
+func (p *Pool[In, Out]) closeInput() {
+	p.closeOnce.Do(func() {
+		close(p.quit)
+		p.mu.Lock()
+		p.closed = true
+		close(p.jobs)
+		p.mu.Unlock()
+
+		p.wmu.Lock()
+		p.inputClosed = true
+		idle := p.workers == 0
+		p.wmu.Unlock()
+		if idle {
+			p.finish()
+		}
+	})
+}
+
+// Shutdown 优雅关闭：不再接收新任务，等待队列中的任务全部执行完。
+// ctx 先到期时，剩余排队任务以 ErrDropped 返回、正在执行的任务被取消，Shutdown 返回 ctx.Err()。
+// 等待期间必须有人读取 Results()。
+func (p *Pool[In, Out]) Shutdown(ctx context.Context) error {
+	p.closeInput()
+	select {
+	case <-p.done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.dropping.Store(true)
+		p.cancel()
+		<-p.done
+		return ctx.Err()
+	}
+}
+
+// Drain 立即关闭：不再接收新任务，队列中还没开始的任务以 ErrDropped 返回，
+// 只等待正在执行的任务完成。等待期间必须有人读取 Results()。
+func (p *Pool[In, Out]) Drain() {
+	p.dropping.Store(true)
+	p.closeInput()
+	<-p.done
+	p.cancel()
+}
+
+// ========== worker ==========
+
+// worker 循环取任务，next 返回 false 或 Resize 要求缩容时退出
+func (p *Pool[In, Out]) worker(id int) {
+	ctx := WithWorkerID(p.ctx, id)
+	for {
+		j, ok := p.next()
+		if !ok {
+			return
+		}
+		p.process(ctx, id, j)
+		if p.shed() {
+			return
+		}
+	}
+}
+
+// next 等待下一个任务。队列关闭、Resize 缩容或自动伸缩时空闲超过 IdleTimeout，
+// worker 需要退出时返回 false（计数已在退出前更新）。
+func (p *Pool[In, Out]) next() (job[In], bool) {
+	for {
+		// 检查多余 worker 和读取 wake 在同一次加锁中完成，不会错过 Resize 的唤醒
+		p.wmu.Lock()
+		if p.excess > 0 {
+			p.shedLocked()
+			return job[In]{}, false
+		}
+		wake := p.wake
+		p.wmu.Unlock()
+
+		var (
+			timer Timer
+			idle  <-chan time.Time
+		)
+		if p.opts.IdleTimeout > 0 {
+			timer = p.clock.NewTimer(p.opts.IdleTimeout)
+			idle = timer.C()
+		}
+		stop := func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+
+		select {
+		case j, ok := <-p.jobs:
+			stop()
+			if !ok {
+				p.exit()
+				return j, false
+			}
+			<-p.slots
+			return j, true
+		case <-wake:
+			stop()
+		case <-idle:
+			if p.retireIdle() {
+				return job[In]{}, false
+			}
+		}
+	}
+}
+
+func (p *Pool[In, Out]) process(ctx context.Context, id int, j job[In]) {
+	r := Result[In, Out]{Seq: j.seq, Input: j.in}
+	switch {
+	case p.dropping.Load():
+		r.Err = ErrDropped
+	case p.ctx.Err() != nil:
+		r.Err = p.ctx.Err()
+	default:
+		p.setActive(1)
+		r.Worker = id
+		start := p.clock.Now()
+		r.Value, r.Err = p.run(ctx, j.in)
+		r.Duration = p.clock.Now().Sub(start)
+		p.setActive(-1)
+	}
+	p.record(r.Err, r.Worker != 0, r.Duration)
+	p.raw <- r
+	p.grow()
+}
+
+// run 执行任务并把 panic 转换为 *PanicError
+func (p *Pool[In, Out]) run(ctx context.Context, in In) (out Out, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return p.fn(ctx, in)
+}
+
+// finish 在所有 worker 退出且不再接收任务后关闭 raw
+func (p *Pool[In, Out]) finish() {
+	p.finishOnce.Do(func() {
+		close(p.raw)
+		if !p.opts.Ordered {
+			close(p.done)
+		}
+	})
+}
+
+// reorder 缓存提前完成的结果，按序号依次输出；每输出一个结果归还一个窗口位置，
+// 所以 pending 中的结果不会超过 ReorderWindow 个
+func (p *Pool[In, Out]) reorder() {
+	defer close(p.done)
+	defer close(p.results)
+	pending := make(map[int]Result[In, Out])
+	next := 0
+	for r := range p.raw {
+		pending[r.Seq] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			p.results <- r
+			p.releaseWindow()
+			next++
+		}
+	}
+}
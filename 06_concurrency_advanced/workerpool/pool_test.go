@@ -0,0 +1,210 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrderedResultsCaptureErrorsAndPanics(t *testing.T) {
+	pool := New(context.Background(), func(ctx context.Context, n int) (int, error) {
+		time.Sleep(time.Duration(10-n) * time.Millisecond) // 后提交的先完成
+		switch n {
+		case 4:
+			return 0, errors.New("数据校验失败")
+		case 7:
+			var m map[int]int
+			m[n]++
+		}
+		return n * n, nil
+	}, Options{Workers: 3, QueueSize: 10, Ordered: true})
+
+	go func() {
+		for i := range 10 {
+			pool.Submit(context.Background(), i)
+		}
+		pool.Shutdown(context.Background())
+	}()
+
+	seq := 0
+	for r := range pool.Results() {
+		if r.Seq != seq || r.Input != seq {
+			t.Fatalf("第 %d 个结果是任务 %d（序号 %d）", seq, r.Input, r.Seq)
+		}
+		var panicErr *PanicError
+		switch r.Input {
+		case 4:
+			if r.Err == nil || errors.As(r.Err, &panicErr) {
+				t.Errorf("任务 4 err = %v, want 普通错误", r.Err)
+			}
+		case 7:
+			if !errors.As(r.Err, &panicErr) {
+				t.Errorf("任务 7 err = %v, want *PanicError", r.Err)
+			}
+		default:
+			if r.Err != nil || r.Value != r.Input*r.Input || r.Worker == 0 {
+				t.Errorf("任务 %d = %+v", r.Input, r)
+			}
+		}
+		seq++
+	}
+	if seq != 10 {
+		t.Fatalf("收到 %d 个结果，want 10", seq)
+	}
+}
+
+// 队头任务卡住时，领先它的任务数不超过 ReorderWindow，之后的提交被拒绝
+func TestReorderWindowBoundsPending(t *testing.T) {
+	const window = 6
+	gate := make(chan struct{})
+	pool := New(context.Background(), func(ctx context.Context, n int) (int, error) {
+		if n == 0 {
+			<-gate
+		}
+		return n, nil
+	}, Options{Workers: 4, QueueSize: 4, Ordered: true, ReorderWindow: window})
+
+	accepted := 0
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		err := pool.TrySubmit(accepted)
+		if err == nil {
+			accepted++
+			continue
+		}
+		if !errors.Is(err, ErrFull) {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond) // 给 worker 时间处理，确认窗口确实不再打开
+	}
+	if accepted != window {
+		t.Fatalf("队头卡住时接受了 %d 个任务，want %d", accepted, window)
+	}
+
+	close(gate)
+	go pool.Shutdown(context.Background())
+	got := 0
+	for r := range pool.Results() {
+		if r.Seq != got {
+			t.Fatalf("结果顺序错误: 第 %d 个是 %d", got, r.Seq)
+		}
+		got++
+	}
+	if got != window {
+		t.Fatalf("收到 %d 个结果，want %d", got, window)
+	}
+}
+
+// Submit 阻塞等待队列空位时不持有锁，TrySubmit 和 Shutdown 不会被挡住
+func TestBlockedSubmitDoesNotBlockOthers(t *testing.T) {
+	gate := make(chan struct{})
+	pool := New(context.Background(), func(ctx context.Context, n int) (int, error) {
+		<-gate
+		return n, nil
+	}, Options{Workers: 1, QueueSize: 1})
+
+	// 1 个在执行，1 个在队列中
+	if err := pool.Submit(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	for pool.Stats().Active == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := pool.Submit(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	blocked := make(chan error)
+	go func() { blocked <- pool.Submit(context.Background(), 3) }()
+	time.Sleep(10 * time.Millisecond)
+
+	tried := make(chan error)
+	go func() { tried <- pool.TrySubmit(4) }()
+	select {
+	case err := <-tried:
+		if !errors.Is(err, ErrFull) {
+			t.Fatalf("TrySubmit err = %v, want ErrFull", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Submit 阻塞时 TrySubmit 也被阻塞")
+	}
+
+	go func() {
+		for range pool.Results() {
+		}
+	}()
+	go pool.Drain()
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("关闭后阻塞的 Submit err = %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("关闭后阻塞的 Submit 没有返回")
+	}
+	close(gate)
+	<-pool.Done()
+}
+
+func TestSubmitRespectsContext(t *testing.T) {
+	gate := make(chan struct{})
+	defer close(gate)
+	pool := New(context.Background(), func(ctx context.Context, n int) (int, error) {
+		<-gate
+		return n, nil
+	}, Options{Workers: 1, QueueSize: 1, Ordered: true, ReorderWindow: 1})
+
+	if err := pool.Submit(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pool.Submit(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("窗口已满时 Submit err = %v, want DeadlineExceeded", err)
+	}
+	if err := pool.TrySubmit(1); !errors.Is(err, ErrFull) {
+		t.Fatalf("窗口已满时 TrySubmit err = %v, want ErrFull", err)
+	}
+}
+
+// 并发提交时序号连续，每个任务恰好输出一次
+func TestConcurrentSubmit(t *testing.T) {
+	var ran atomic.Int64
+	pool := New(context.Background(), func(ctx context.Context, n int) (string, error) {
+		ran.Add(1)
+		return fmt.Sprint(n), nil
+	}, Options{Workers: 4, QueueSize: 2, Ordered: true, ReorderWindow: 3})
+
+	const submitters, each = 8, 50
+	go func() {
+		done := make(chan struct{})
+		for range submitters {
+			go func() {
+				for i := range each {
+					if err := pool.Submit(context.Background(), i); err != nil {
+						t.Error(err)
+					}
+				}
+				done <- struct{}{}
+			}()
+		}
+		for range submitters {
+			<-done
+		}
+		pool.Shutdown(context.Background())
+	}()
+
+	seq := 0
+	for r := range pool.Results() {
+		if r.Seq != seq {
+			t.Fatalf("第 %d 个结果的序号是 %d", seq, r.Seq)
+		}
+		seq++
+	}
+	if seq != submitters*each || ran.Load() != submitters*each {
+		t.Fatalf("输出 %d 个结果，执行 %d 次，want %d", seq, ran.Load(), submitters*each)
+	}
+}
@@ -0,0 +1,167 @@
+package workerpool
+
+import "time"
+
+// Stats 是 worker 池的运行状态快照
+type Stats struct {
+	Workers int // 当前 worker 数
+	Active  int // 正在执行任务的 worker 数
+	Idle    int // 空闲 worker 数
+	Queued  int // 排队中的任务数
+
+	Completed int64 // 已执行的任务数（包括返回错误和 panic 的）
+	Failed    int64 // 执行后返回错误或 panic 的任务数
+	Dropped   int64 // 未执行的任务数（ErrDropped 或 context 取消）
+
+	AvgLatency  time.Duration // 任务耗时的指数移动平均
+	PeakWorkers int
+	ScaleUps    int64 // 自动扩容次数
+	ScaleDowns  int64 // 空闲退出和 Resize 缩容的次数
+}
+
+// latencyWeight 是新样本在耗时移动平均中的权重
+const latencyWeight = 0.2
+
+// Stats 返回当前状态
+func (p *Pool[In, Out]) Stats() Stats {
+	p.wmu.Lock()
+	defer p.wmu.Unlock()
+	s := p.stats
+	s.Workers = p.workers
+	s.Active = p.active
+	s.Idle = p.workers - p.active
+	s.Queued = len(p.jobs)
+	return s
+}
+
+// Resize 立即把 worker 数调整为 n，返回实际的目标数量。
+// 自动伸缩时 n 被限制在 [MinWorkers, MaxWorkers] 内，之后仍按负载伸缩；
+// 固定数量的池会把 n 作为新的固定数量。缩容时正在执行任务的 worker 完成当前任务后退出。
+func (p *Pool[In, Out]) Resize(n int) int {
+	p.wmu.Lock()
+	defer p.wmu.Unlock()
+	if p.autoscale {
+		n = min(max(n, p.opts.MinWorkers, 1), p.opts.MaxWorkers)
+	} else {
+		n = max(n, 1)
+		p.opts.MinWorkers, p.opts.MaxWorkers = n, n
+	}
+	if p.inputClosed {
+		return p.workers
+	}
+
+	p.excess = 0
+	for p.workers < n {
+		p.spawnLocked()
+	}
+	if p.workers > n {
+		p.excess = p.workers - n
+		close(p.wake)
+		p.wake = make(chan struct{})
+	}
+	return n
+}
+
+// ========== worker 计数（调用方持有 wmu 的函数以 Locked 结尾）==========
+
+func (p *Pool[In, Out]) spawnLocked() {
+	p.nextID++
+	p.workers++
+	p.stats.PeakWorkers = max(p.stats.PeakWorkers, p.workers)
+	go p.worker(p.nextID)
+}
+
+// grow 在排队任务多于空闲 worker 时扩容，直到 MaxWorkers
+func (p *Pool[In, Out]) grow() {
+	p.wmu.Lock()
+	defer p.wmu.Unlock()
+	if p.excess > 0 || p.workers == 0 && p.inputClosed {
+		return
+	}
+	queued := len(p.jobs)
+	for queued > p.workers-p.active && p.workers < p.opts.MaxWorkers && p.wantMoreLocked(queued) {
+		p.spawnLocked()
+		p.stats.ScaleUps++
+	}
+}
+
+// wantMoreLocked 判断预计排队时间是否超过 TargetLatency
+func (p *Pool[In, Out]) wantMoreLocked(queued int) bool {
+	if p.workers == 0 || p.opts.TargetLatency <= 0 || p.stats.AvgLatency == 0 {
+		return true
+	}
+	wait := time.Duration(queued) * p.stats.AvgLatency / time.Duration(p.workers)
+	return wait > p.opts.TargetLatency
+}
+
+// retireIdle 在空闲超时后调用，worker 数多于 MinWorkers 且没有排队任务时退出
+func (p *Pool[In, Out]) retireIdle() bool {
+	p.wmu.Lock()
+	if !p.autoscale || p.workers <= p.opts.MinWorkers || len(p.jobs) > 0 {
+		p.wmu.Unlock()
+		return false
+	}
+	p.stats.ScaleDowns++
+	p.leaveLocked()
+	return true
+}
+
+// shed 在 Resize 缩容后调用，还有多余的 worker 时当前 worker 退出
+func (p *Pool[In, Out]) shed() bool {
+	p.wmu.Lock()
+	if p.excess == 0 {
+		p.wmu.Unlock()
+		return false
+	}
+	p.shedLocked()
+	return true
+}
+
+// shedLocked 让当前 worker 作为多余的 worker 退出，并释放 wmu
+func (p *Pool[In, Out]) shedLocked() {
+	p.excess--
+	p.stats.ScaleDowns++
+	p.leaveLocked()
+}
+
+// exit 在任务队列关闭后调用
+func (p *Pool[In, Out]) exit() {
+	p.wmu.Lock()
+	p.leaveLocked()
+}
+
+// leaveLocked 减少 worker 计数并释放 wmu；最后一个 worker 在队列关闭后退出时结束结果输出
+func (p *Pool[In, Out]) leaveLocked() {
+	p.workers--
+	p.excess = min(p.excess, p.workers)
+	last := p.workers == 0 && p.inputClosed
+	p.wmu.Unlock()
+	if last {
+		p.finish()
+	}
+}
+
+func (p *Pool[In, Out]) setActive(delta int) {
+	p.wmu.Lock()
+	p.active += delta
+	p.wmu.Unlock()
+}
+
+// record 更新完成计数和耗时移动平均
+func (p *Pool[In, Out]) record(err error, executed bool, d time.Duration) {
+	p.wmu.Lock()
+	defer p.wmu.Unlock()
+	if !executed {
+		p.stats.Dropped++
+		return
+	}
+	p.stats.Completed++
+	if err != nil {
+		p.stats.Failed++
+	}
+	if p.stats.AvgLatency == 0 {
+		p.stats.AvgLatency = d
+	} else {
+		p.stats.AvgLatency += time.Duration(latencyWeight * float64(d-p.stats.AvgLatency))
+	}
+}
@@ -0,0 +1,146 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 用 FakeClock 驱动自动伸缩的 worker 池。任务在 gate 上阻塞，由测试逐个放行，因此每一步的状态都是确定的。
+
+// waitFor 轮询直到 cond 成立；worker 在后台 goroutine 中调整计数，需要给它们一点真实时间
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !cond() {
+		t.Fatalf("等待超时: %s", what)
+	}
+}
+
+// newGatedPool 创建任务阻塞在 gate 上的池，并在后台读取结果
+func newGatedPool(clock *FakeClock, gate chan struct{}, opts Options) *Pool[int, int] {
+	opts.Clock = clock
+	pool := New(context.Background(), func(ctx context.Context, n int) (int, error) {
+		select {
+		case <-gate:
+			return n, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}, opts)
+	go func() {
+		for range pool.Results() {
+		}
+	}()
+	return pool
+}
+
+func TestScaleUpAndIdleScaleDown(t *testing.T) {
+	clock := NewFakeClock(time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC))
+	gate := make(chan struct{})
+	pool := newGatedPool(clock, gate, Options{
+		MinWorkers:  1,
+		MaxWorkers:  4,
+		QueueSize:   20,
+		IdleTimeout: 10 * time.Second,
+	})
+	defer pool.Shutdown(context.Background())
+
+	if s := pool.Stats(); s.Workers != 1 {
+		t.Fatalf("启动时 %d 个 worker，want MinWorkers = 1", s.Workers)
+	}
+
+	for i := 1; i <= 8; i++ {
+		pool.Submit(context.Background(), i)
+	}
+	waitFor(t, "4 个 worker 都在执行", func() bool { return pool.Stats().Active == 4 })
+	if s := pool.Stats(); s.Workers != 4 || s.ScaleUps != 3 || s.Queued != 4 {
+		t.Fatalf("排队任务多于空闲 worker 时: workers=%d 扩容 %d 次 排队 %d，want 4/3/4", s.Workers, s.ScaleUps, s.Queued)
+	}
+
+	close(gate) // 放行所有任务
+	waitFor(t, "任务全部完成且 4 个 worker 都在等待", func() bool {
+		return pool.Stats().Completed == 8 && clock.Timers() == 4
+	})
+	if s := pool.Stats(); s.Idle != 4 {
+		t.Fatalf("任务完成后空闲 worker %d 个，want 4", s.Idle)
+	}
+
+	clock.Advance(5 * time.Second)
+	time.Sleep(10 * time.Millisecond)
+	if n := pool.Stats().Workers; n != 4 {
+		t.Fatalf("空闲 5 秒（未到 IdleTimeout）后 %d 个 worker，want 4", n)
+	}
+
+	clock.Advance(5 * time.Second)
+	waitFor(t, "缩容到 MinWorkers", func() bool { return pool.Stats().Workers == 1 && clock.Timers() == 1 })
+	if s := pool.Stats(); s.ScaleDowns != 3 {
+		t.Fatalf("缩容 %d 次，want 3", s.ScaleDowns)
+	}
+}
+
+func TestResize(t *testing.T) {
+	clock := NewFakeClock(time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC))
+	pool := newGatedPool(clock, make(chan struct{}), Options{
+		MinWorkers:  1,
+		MaxWorkers:  4,
+		IdleTimeout: time.Minute,
+	})
+	defer pool.Shutdown(context.Background())
+
+	if n := pool.Resize(3); n != 3 || pool.Stats().Workers != 3 {
+		t.Fatalf("Resize(3) = %d，workers=%d", n, pool.Stats().Workers)
+	}
+	if n := pool.Resize(9); n != 4 {
+		t.Fatalf("Resize(9) = %d，want 限制在 MaxWorkers = 4", n)
+	}
+	if n := pool.Resize(0); n != 1 {
+		t.Fatalf("Resize(0) = %d，want 限制在 MinWorkers = 1", n)
+	}
+	waitFor(t, "多余的 worker 退出", func() bool { return pool.Stats().Workers == 1 })
+}
+
+func TestLatencyScaling(t *testing.T) {
+	clock := NewFakeClock(time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC))
+	gate := make(chan struct{})
+	pool := newGatedPool(clock, gate, Options{
+		MinWorkers:    1,
+		MaxWorkers:    4,
+		QueueSize:     20,
+		IdleTimeout:   time.Minute,
+		TargetLatency: 5 * time.Second,
+	})
+	defer pool.Shutdown(context.Background())
+	defer close(gate)
+
+	// 第一个任务耗时 2 秒（假时钟），作为平均耗时
+	pool.Submit(context.Background(), 1)
+	waitFor(t, "任务 1 开始执行", func() bool { return pool.Stats().Active == 1 })
+	clock.Advance(2 * time.Second)
+	gate <- struct{}{}
+	waitFor(t, "任务 1 完成", func() bool { return pool.Stats().Completed == 1 })
+	if avg := pool.Stats().AvgLatency; avg != 2*time.Second {
+		t.Fatalf("平均耗时 %v，want 2s", avg)
+	}
+
+	// worker 忙于任务 2，再排队 2 个：预计等待 2 × 2s / 1 = 4s，不扩容
+	pool.Submit(context.Background(), 2)
+	waitFor(t, "任务 2 开始执行", func() bool { return pool.Stats().Active == 1 })
+	pool.Submit(context.Background(), 3)
+	pool.Submit(context.Background(), 4)
+	if s := pool.Stats(); s.Workers != 1 || s.Queued != 2 {
+		t.Fatalf("预计等待 4s < 5s 时 workers=%d queued=%d，want 1/2", s.Workers, s.Queued)
+	}
+
+	// 第 3 个排队任务：预计等待 3 × 2s / 1 = 6s，扩容一个后降到 3s
+	pool.Submit(context.Background(), 5)
+	waitFor(t, "扩容到 2 个 worker", func() bool { return pool.Stats().Active == 2 })
+	if n := pool.Stats().Workers; n != 2 {
+		t.Fatalf("预计等待 6s > 5s 时 %d 个 worker，want 2", n)
+	}
+}
@@ -0,0 +1,371 @@
+// enumgen 为基于 iota 的枚举类型生成 String、Parse、JSON/文本序列化、IsValid 和 Values。
+//
+// 用法（写在枚举所在的源文件里，然后运行 go generate）：
+//
+//	//go:generate go run golang_study/tools/enumgen -type=OrderStatus
+//
+// 显示名称来自常量行尾注释里的 enum:"..." 标注，没有标注时使用常量名：
+//
+//	Pending OrderStatus = iota + 1 // 1 enum:"待支付"
+//
+// 本仓库的课程文件都是用 go run xxx.go 单独运行的，生成的代码因此写回同一个源文件，
+// 放在 "// enumgen:begin 类型名" 和 "// enumgen:end 类型名" 之间，重新生成时整段替换。
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var (
+	typeNames = flag.String("type", "", "枚举类型名，多个用逗号分隔（必填）")
+	fileName  = flag.String("file", "", "源文件（默认使用 go generate 设置的 $GOFILE）")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("enumgen: ")
+	flag.Parse()
+
+	path := *fileName
+	if path == "" {
+		path = os.Getenv("GOFILE")
+	}
+	if *typeNames == "" || path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range strings.Split(*typeNames, ",") {
+		src, err = generate(path, src, strings.TrimSpace(name))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate 解析源文件，找到类型的常量，替换（或追加）生成区域，并补上生成代码需要的导入
+func generate(path string, src []byte, typeName string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	values, err := collect(file, typeName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var code bytes.Buffer
+	if err := tmpl.Execute(&code, struct {
+		Type   string
+		Values []value
+	}{typeName, values}); err != nil {
+		return nil, err
+	}
+
+	out := replaceRegion(src, typeName, code.Bytes())
+	out, err = ensureImports(path, out, "encoding/json", "fmt")
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(out)
+}
+
+// ========== 收集常量 ==========
+
+type value struct {
+	Name    string
+	Display string
+	Value   int64
+}
+
+var displayTag = regexp.MustCompile(`enum:"((?:[^"\\]|\\.)*)"`)
+
+// collect 找出类型为 typeName 的所有常量，按声明顺序返回。
+// 支持 const 块里省略表达式的隐式重复，以及由 iota、整数、+ - * / << >> 组成的表达式。
+func collect(file *ast.File, typeName string) ([]value, error) {
+	var values []value
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		var (
+			curType string
+			curExpr ast.Expr
+		)
+		for index, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if vs.Type != nil || len(vs.Values) > 0 {
+				curType, curExpr = "", nil
+				if ident, ok := vs.Type.(*ast.Ident); ok {
+					curType = ident.Name
+				}
+				if len(vs.Values) > 0 {
+					curExpr = vs.Values[0]
+				}
+			}
+			if curType != typeName || curExpr == nil {
+				continue
+			}
+			if len(vs.Names) != 1 {
+				return nil, fmt.Errorf("%s 的常量每行只能声明一个", typeName)
+			}
+			name := vs.Names[0].Name
+			if name == "_" {
+				continue
+			}
+			v, err := eval(curExpr, int64(index))
+			if err != nil {
+				return nil, fmt.Errorf("常量 %s: %w", name, err)
+			}
+			display := name
+			if vs.Comment != nil {
+				if m := displayTag.FindStringSubmatch(vs.Comment.Text()); m != nil {
+					display, err = strconv.Unquote(`"` + m[1] + `"`)
+					if err != nil {
+						return nil, fmt.Errorf("常量 %s 的显示名称: %w", name, err)
+					}
+				}
+			}
+			values = append(values, value{Name: name, Display: display, Value: v})
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("没有找到类型 %s 的常量", typeName)
+	}
+	seen := make(map[int64]string)
+	for _, v := range values {
+		if other, dup := seen[v.Value]; dup {
+			return nil, fmt.Errorf("常量 %s 和 %s 的值相同（%d）", other, v.Name, v.Value)
+		}
+		seen[v.Value] = v.Name
+	}
+	return values, nil
+}
+
+func eval(expr ast.Expr, iota int64) (int64, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if e.Name == "iota" {
+			return iota, nil
+		}
+	case *ast.BasicLit:
+		if e.Kind == token.INT {
+			return strconv.ParseInt(e.Value, 0, 64)
+		}
+	case *ast.ParenExpr:
+		return eval(e.X, iota)
+	case *ast.UnaryExpr:
+		if e.Op == token.SUB {
+			x, err := eval(e.X, iota)
+			return -x, err
+		}
+	case *ast.BinaryExpr:
+		x, err := eval(e.X, iota)
+		if err != nil {
+			return 0, err
+		}
+		y, err := eval(e.Y, iota)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return x + y, nil
+		case token.SUB:
+			return x - y, nil
+		case token.MUL:
+			return x * y, nil
+		case token.QUO:
+			if y != 0 {
+				return x / y, nil
+			}
+		case token.SHL:
+			return x << y, nil
+		case token.SHR:
+			return x >> y, nil
+		}
+	}
+	return 0, fmt.Errorf("不支持的常量表达式 %T", expr)
+}
+
+// ========== 写回源文件 ==========
+
+func markers(typeName string) (begin, end string) {
+	return "// enumgen:begin " + typeName + "\n", "// enumgen:end " + typeName + "\n"
+}
+
+// replaceRegion 替换已有的生成区域；没有时追加到文件末尾
+func replaceRegion(src []byte, typeName string, code []byte) []byte {
+	begin, end := markers(typeName)
+	region := append([]byte(begin), code...)
+	region = append(region, end...)
+
+	start := bytes.Index(src, []byte(begin))
+	if start >= 0 {
+		if stop := bytes.Index(src[start:], []byte(end)); stop >= 0 {
+			stop += start + len(end)
+			return append(append(append([]byte(nil), src[:start]...), region...), src[stop:]...)
+		}
+	}
+	out := append(bytes.TrimRight(src, "\n"), "\n\n"...)
+	return append(out, region...)
+}
+
+// ensureImports 补上生成代码用到、源文件里还没有的导入：
+// 有带括号的 import 块时插入块中（format.Source 会重新排序），否则在 package 语句之后加一条 import
+func ensureImports(path string, src []byte, paths ...string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	have := make(map[string]bool)
+	for _, imp := range file.Imports {
+		if imp.Name == nil {
+			have[imp.Path.Value] = true
+		}
+	}
+	var missing []byte
+	for _, p := range paths {
+		if quoted := strconv.Quote(p); !have[quoted] {
+			missing = append(missing, "\n\t"+quoted...)
+		}
+	}
+	if len(missing) == 0 {
+		return src, nil
+	}
+
+	at := fset.Position(file.Name.End()).Offset
+	insert := append([]byte("\n\nimport ("), missing...)
+	insert = append(insert, "\n)"...)
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && gen.Lparen.IsValid() {
+			at = fset.Position(gen.Lparen).Offset + 1
+			insert = missing
+			break
+		}
+	}
+	out := append([]byte(nil), src[:at]...)
+	out = append(out, insert...)
+	return append(out, src[at:]...), nil
+}
+
+// ========== 模板 ==========
+
+var tmpl = template.Must(template.New("enum").Parse(`// 以下代码由 enumgen 根据 {{.Type}} 的常量生成，请勿手动修改；修改常量后重新运行 go generate。
+
+// String 返回显示名称（常量注释中的 enum:"..."，没有标注时为常量名）
+func (x {{.Type}}) String() string {
+	switch x {
+{{- range .Values}}
+	case {{.Name}}:
+		return {{printf "%q" .Display}}
+{{- end}}
+	}
+	return fmt.Sprintf("{{.Type}}(%d)", int64(x))
+}
+
+// Name 返回常量名，用于文本和 JSON 序列化
+func (x {{.Type}}) Name() string {
+	switch x {
+{{- range .Values}}
+	case {{.Name}}:
+		return {{printf "%q" .Name}}
+{{- end}}
+	}
+	return fmt.Sprintf("{{.Type}}(%d)", int64(x))
+}
+
+// IsValid 报告 x 是否是已定义的常量
+func (x {{.Type}}) IsValid() bool {
+	switch x {
+	case {{range $i, $v := .Values}}{{if $i}}, {{end}}{{$v.Name}}{{end}}:
+		return true
+	}
+	return false
+}
+
+// {{.Type}}Values 按声明顺序返回所有常量
+func {{.Type}}Values() []{{.Type}} {
+	return []{{.Type}}{ {{- range $i, $v := .Values}}{{if $i}}, {{end}}{{$v.Name}}{{end -}} }
+}
+
+// Parse{{.Type}} 按常量名或显示名称解析
+func Parse{{.Type}}(s string) ({{.Type}}, error) {
+	switch s {
+{{- range .Values}}
+	case {{printf "%q" .Name}}{{if ne .Name .Display}}, {{printf "%q" .Display}}{{end}}:
+		return {{.Name}}, nil
+{{- end}}
+	}
+	return 0, fmt.Errorf("无效的 {{.Type}}: %q", s)
+}
+
+// MarshalText 实现 encoding.TextMarshaler，输出常量名
+func (x {{.Type}}) MarshalText() ([]byte, error) {
+	if !x.IsValid() {
+		return nil, fmt.Errorf("无效的 {{.Type}}: %d", int64(x))
+	}
+	return []byte(x.Name()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，接受常量名或显示名称
+func (x *{{.Type}}) UnmarshalText(text []byte) error {
+	v, err := Parse{{.Type}}(string(text))
+	if err != nil {
+		return err
+	}
+	*x = v
+	return nil
+}
+
+// MarshalJSON 输出常量名字符串
+func (x {{.Type}}) MarshalJSON() ([]byte, error) {
+	text, err := x.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%q", text)), nil
+}
+
+// UnmarshalJSON 接受字符串（常量名或显示名称）或数字；null 保持原值不变，与标准库的约定一致
+func (x *{{.Type}}) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("无效的 {{.Type}}: %s", data)
+		}
+		return x.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil || !{{.Type}}(n).IsValid() {
+		return fmt.Errorf("无效的 {{.Type}}: %s", data)
+	}
+	*x = {{.Type}}(n)
+	return nil
+}
+`))
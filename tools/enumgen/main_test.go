@@ -0,0 +1,61 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const fixture = `package demo
+
+%s
+
+type Color int
+
+const (
+	Red   Color = iota + 1 // enum:"红"
+	Green                  // enum:"绿"
+)
+`
+
+func TestGenerateAddsImports(t *testing.T) {
+	for _, imports := range []string{
+		"",
+		`import "fmt"`,
+		"import (\n\t\"errors\"\n\t\"fmt\"\n)",
+	} {
+		src := strings.Replace(fixture, "%s", imports, 1)
+		out, err := generate("demo.go", []byte(src), "Color")
+		if err != nil {
+			t.Fatalf("imports %q: %v", imports, err)
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), "demo.go", out, parser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := make(map[string]int)
+		for _, imp := range file.Imports {
+			count[imp.Path.Value]++
+		}
+		if count[`"encoding/json"`] != 1 || count[`"fmt"`] != 1 {
+			t.Errorf("imports %q 生成后的导入: %v", imports, count)
+		}
+
+		// 再生成一次结果不变
+		again, err := generate("demo.go", out, "Color")
+		if err != nil || string(again) != string(out) {
+			t.Errorf("imports %q 重复生成结果不同: %v", imports, err)
+		}
+	}
+}
+
+func TestGeneratedUnmarshalJSONUsesJSONDecoding(t *testing.T) {
+	out, err := generate("demo.go", []byte(strings.Replace(fixture, "%s", "", 1)), "Color")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "Sscanf") || !strings.Contains(string(out), `string(data) == "null"`) {
+		t.Fatalf("UnmarshalJSON 仍使用 Sscanf 或没有处理 null:\n%s", out)
+	}
+}